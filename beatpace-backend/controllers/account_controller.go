@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type AccountController struct {
+	accountService services.AccountService
+}
+
+func NewAccountController(accountService services.AccountService) *AccountController {
+	return &AccountController{
+		accountService: accountService,
+	}
+}
+
+// DeleteAccount removes the current user and everything stored about them
+func (ac *AccountController) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var query types.DeleteAccountQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletion, err := ac.accountService.DeleteAccount(c.Request.Context(), userID, query.UnfollowPlaylists)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deletion)
+}
+
+// ExportAccount downloads everything stored about the current user as a JSON archive
+func (ac *AccountController) ExportAccount(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	archive, err := ac.accountService.ExportAccount(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("beatpace-export-%s.json", archive.ExportedAt.UTC().Format("20060102T150405Z"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, archive)
+}
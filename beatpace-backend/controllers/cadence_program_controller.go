@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type CadenceProgramController struct {
+	programService services.CadenceProgramService
+}
+
+func NewCadenceProgramController(programService services.CadenceProgramService) *CadenceProgramController {
+	return &CadenceProgramController{
+		programService: programService,
+	}
+}
+
+// GetProgram returns the current user's cadence program and its history
+func (pc *CadenceProgramController) GetProgram(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	program, err := pc.programService.GetCadenceProgram(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// SaveProgram starts a cadence program for the current user, replacing any existing one
+func (pc *CadenceProgramController) SaveProgram(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.CadenceProgramRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	program, err := pc.programService.SaveCadenceProgram(c.Request.Context(), userID, req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// Advance moves the current user's cadence program to its next stage
+func (pc *CadenceProgramController) Advance(c *gin.Context) {
+	pc.stageAction(c, pc.programService.AdvanceStage)
+}
+
+// Hold keeps the current user's cadence program at its stage for another week
+func (pc *CadenceProgramController) Hold(c *gin.Context) {
+	pc.stageAction(c, pc.programService.HoldStage)
+}
+
+// DeleteProgram stops the current user's cadence program
+func (pc *CadenceProgramController) DeleteProgram(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := pc.programService.DeleteCadenceProgram(c.Request.Context(), userID); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// stageAction binds the optional note and applies a stage action
+func (pc *CadenceProgramController) stageAction(c *gin.Context, action func(ctx context.Context, userID string, note string) (*services.CadenceProgramResponse, error)) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.CadenceProgramActionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	program, err := action(c.Request.Context(), userID, req.Note)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// CalculatorController serves the public running calculators. They only do arithmetic,
+// so they need no services or login.
+type CalculatorController struct{}
+
+func NewCalculatorController() *CalculatorController {
+	return &CalculatorController{}
+}
+
+// Pace converts between pace, speed and finish time
+func (cc *CalculatorController) Pace(c *gin.Context) {
+	var req types.PaceCalcRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calculation, err := services.CalculatePace(req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calculation)
+}
+
+// Predict predicts race times from a race result
+func (cc *CalculatorController) Predict(c *gin.Context) {
+	var req types.RacePredictionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prediction, err := services.PredictRaceTimes(req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prediction)
+}
+
+// Cadence converts a pace to cadence and music BPM
+func (cc *CalculatorController) Cadence(c *gin.Context) {
+	var req types.CadenceCalcRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calculation, err := services.CalculateCadence(req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calculation)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/yimango/beatpace-backend/middleware"
 	"github.com/yimango/beatpace-backend/model"
 	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
 )
 
 // Controller handles HTTP requests
@@ -176,7 +177,11 @@ func (c *Controller) GeneratePlaylist(ctx *gin.Context) {
 		return
 	}
 
-	playlist, err := c.spotifyService.GeneratePlaylistForPace(ctx, userID, req.PaceSeconds, req.Gender, req.Height)
+	playlist, err := c.spotifyService.GeneratePlaylistForPace(ctx, userID, types.GeneratePlaylistRequest{
+		PaceInSeconds: req.PaceSeconds,
+		Gender:        req.Gender,
+		Height:        float64(req.Height),
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+)
+
+// respondWithServiceError maps service errors to HTTP status codes
+func respondWithServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
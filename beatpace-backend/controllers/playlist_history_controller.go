@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type PlaylistHistoryController struct {
+	historyService services.PlaylistHistoryService
+}
+
+func NewPlaylistHistoryController(historyService services.PlaylistHistoryService) *PlaylistHistoryController {
+	return &PlaylistHistoryController{
+		historyService: historyService,
+	}
+}
+
+// ListPlaylists returns a page of the current user's generated playlists, newest first
+func (hc *PlaylistHistoryController) ListPlaylists(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var query types.PlaylistListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := hc.historyService.ListPlaylists(c.Request.Context(), userID, query)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetPlaylist returns one generated playlist with its inputs and tracks
+func (hc *PlaylistHistoryController) GetPlaylist(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	playlist, err := hc.historyService.GetPlaylist(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
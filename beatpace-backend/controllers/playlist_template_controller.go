@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type PlaylistTemplateController struct {
+	templateService services.PlaylistTemplateService
+}
+
+func NewPlaylistTemplateController(templateService services.PlaylistTemplateService) *PlaylistTemplateController {
+	return &PlaylistTemplateController{
+		templateService: templateService,
+	}
+}
+
+// GetTemplates returns the current user's playlist naming templates
+func (tc *PlaylistTemplateController) GetTemplates(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	templates, err := tc.templateService.GetPlaylistTemplates(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":        templates.NameTemplate,
+		"description": templates.DescriptionTemplate,
+	})
+}
+
+// UpdateTemplates validates and stores the current user's playlist naming templates
+func (tc *PlaylistTemplateController) UpdateTemplates(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.PlaylistTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidatePlaylistTemplates(req.Name, req.Description); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	templates, err := tc.templateService.SavePlaylistTemplates(c.Request.Context(), userID, req.Name, req.Description)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":        templates.NameTemplate,
+		"description": templates.DescriptionTemplate,
+	})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type PreferencesController struct {
+	prefsService services.PreferencesService
+}
+
+func NewPreferencesController(prefsService services.PreferencesService) *PreferencesController {
+	return &PreferencesController{
+		prefsService: prefsService,
+	}
+}
+
+// GetPreferences returns the current user's profile and generation defaults
+func (pc *PreferencesController) GetPreferences(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, err := pc.prefsService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences replaces the current user's profile and generation defaults
+func (pc *PreferencesController) UpdatePreferences(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := pc.prefsService.SavePreferences(c.Request.Context(), userID, req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
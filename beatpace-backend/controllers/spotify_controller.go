@@ -44,16 +44,10 @@ func (sc *SpotifyController) GeneratePlaylist(c *gin.Context) {
 	fmt.Printf("Generating playlist for user %s\n", userID)
 
 	// Generate the playlist using our service
-	playlist, err := sc.spotifyService.GeneratePlaylistForPace(
-		c.Request.Context(),
-		userID,
-		req.PaceInSeconds,
-		req.Gender,
-		int(req.Height),
-	)
+	playlist, err := sc.spotifyService.GeneratePlaylistForPace(c.Request.Context(), userID, req)
 	if err != nil {
 		fmt.Printf("Failed to generate playlist: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWithServiceError(c, err)
 		return
 	}
 
@@ -61,6 +55,234 @@ func (sc *SpotifyController) GeneratePlaylist(c *gin.Context) {
 	c.JSON(http.StatusOK, playlist)
 }
 
+// GeneratePaceLadder handles the batch request for one playlist per pace step
+func (sc *SpotifyController) GeneratePaceLadder(c *gin.Context) {
+	var req types.PaceLadderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ladder, err := sc.spotifyService.GeneratePaceLadder(c.Request.Context(), userID, req)
+	if err != nil {
+		fmt.Printf("Failed to generate pace ladder: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ladder)
+}
+
+// GenerateVariants handles the request for several unpublished playlist variants
+func (sc *SpotifyController) GenerateVariants(c *gin.Context) {
+	var req types.PlaylistVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	variants, err := sc.spotifyService.GeneratePlaylistVariants(c.Request.Context(), userID, req)
+	if err != nil {
+		fmt.Printf("Failed to generate variants: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// PublishVariants publishes the chosen variants of a variant set to Spotify
+func (sc *SpotifyController) PublishVariants(c *gin.Context) {
+	var req types.PublishVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	variants, err := sc.spotifyService.PublishPlaylistVariants(c.Request.Context(), userID, c.Param("id"), req)
+	if err != nil {
+		fmt.Printf("Failed to publish variants: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// GenerateWorkoutPlaylist handles the request for one playlist covering an interval workout
+func (sc *SpotifyController) GenerateWorkoutPlaylist(c *gin.Context) {
+	var req types.WorkoutPlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workout, err := sc.spotifyService.GenerateWorkoutPlaylist(c.Request.Context(), userID, req)
+	if err != nil {
+		fmt.Printf("Failed to generate workout playlist: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workout)
+}
+
+// GenerateProgressionRun handles the request for a playlist following a pace ramp
+func (sc *SpotifyController) GenerateProgressionRun(c *gin.Context) {
+	var req types.ProgressionRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	run, err := sc.spotifyService.GenerateProgressionRun(c.Request.Context(), userID, req)
+	if err != nil {
+		fmt.Printf("Failed to generate progression run: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// GenerateRacePlan handles the request for a race pace plan and playlist
+func (sc *SpotifyController) GenerateRacePlan(c *gin.Context) {
+	var req types.RacePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	plan, err := sc.spotifyService.GenerateRacePlan(c.Request.Context(), userID, req)
+	if err != nil {
+		fmt.Printf("Failed to generate race plan: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// ImportWorkout handles an uploaded TCX or FIT workout file and generates its playlist
+func (sc *SpotifyController) ImportWorkout(c *gin.Context) {
+	var form types.ImportWorkoutForm
+	if err := c.ShouldBind(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	data, err := readUploadedFile(c, "file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	imported, err := sc.spotifyService.ImportWorkout(c.Request.Context(), userID, data, form)
+	if err != nil {
+		fmt.Printf("Failed to import workout: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, imported)
+}
+
+// ReplayRun handles an uploaded GPX or TCX run and generates a playlist that follows it
+func (sc *SpotifyController) ReplayRun(c *gin.Context) {
+	var form types.ReplayRunForm
+	if err := c.ShouldBind(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	data, err := readUploadedFile(c, "file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	replay, err := sc.spotifyService.ReplayRun(c.Request.Context(), userID, data, form)
+	if err != nil {
+		fmt.Printf("Failed to replay run: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, replay)
+}
+
+// GenerateTreadmillPlaylist handles the request for a playlist following a treadmill program
+func (sc *SpotifyController) GenerateTreadmillPlaylist(c *gin.Context) {
+	var req types.TreadmillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: %v", err)})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	treadmill, err := sc.spotifyService.GenerateTreadmillPlaylist(c.Request.Context(), userID, req)
+	if err != nil {
+		fmt.Printf("Failed to generate treadmill playlist: %v\n", err)
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, treadmill)
+}
+
 // GetAuthURL returns the Spotify authorization URL
 func (c *SpotifyController) GetAuthURL(ctx *gin.Context) {
 	authURL := c.spotifyService.GetAuthURL()
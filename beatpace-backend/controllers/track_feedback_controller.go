@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type TrackFeedbackController struct {
+	feedbackService services.TrackFeedbackService
+}
+
+func NewTrackFeedbackController(feedbackService services.TrackFeedbackService) *TrackFeedbackController {
+	return &TrackFeedbackController{
+		feedbackService: feedbackService,
+	}
+}
+
+// RateTrack records the current user's feedback on a track of a generated playlist
+func (fc *TrackFeedbackController) RateTrack(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.TrackFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	feedback, err := fc.feedbackService.RateTrack(c.Request.Context(), userID, c.Param("id"), req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
+
+// Impact shows how the current user's feedback shaped their recent playlists
+func (fc *TrackFeedbackController) Impact(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var query types.FeedbackImpactQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	impact, err := fc.feedbackService.GetFeedbackImpact(c.Request.Context(), userID, query)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, impact)
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type TrainingPlanController struct {
+	planService services.TrainingPlanService
+}
+
+func NewTrainingPlanController(planService services.TrainingPlanService) *TrainingPlanController {
+	return &TrainingPlanController{
+		planService: planService,
+	}
+}
+
+// CreatePlan attaches a training plan to the current user
+func (pc *TrainingPlanController) CreatePlan(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.TrainingPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := pc.planService.CreatePlan(c.Request.Context(), userID, req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// ListPlans returns the current user's training plans
+func (pc *TrainingPlanController) ListPlans(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	plans, err := pc.planService.ListPlans(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+// GetPlan returns a training plan with its sessions
+func (pc *TrainingPlanController) GetPlan(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	plan, err := pc.planService.GetPlan(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// DeletePlan removes a training plan and its sessions
+func (pc *TrainingPlanController) DeletePlan(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := pc.planService.DeletePlan(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCalendarFeed returns the URL of the current user's iCalendar feed
+func (pc *TrainingPlanController) GetCalendarFeed(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	feed, err := pc.planService.GetCalendarFeed(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarFeedResponse(c, feed))
+}
+
+// RotateCalendarFeed replaces the current user's feed URL, revoking the old one
+func (pc *TrainingPlanController) RotateCalendarFeed(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	feed, err := pc.planService.RotateCalendarFeed(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarFeedResponse(c, feed))
+}
+
+// CalendarFeed serves a user's sessions as an iCalendar document. It is public so
+// calendar apps can subscribe; the token in the URL is the credential.
+func (pc *TrainingPlanController) CalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	calendar, err := pc.planService.RenderCalendarFeed(c.Request.Context(), token)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar)
+}
+
+// calendarFeedResponse builds the feed URL from the host the request came in on
+func calendarFeedResponse(c *gin.Context, feed *model.CalendarFeed) gin.H {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	url := scheme + "://" + c.Request.Host + "/api/calendar/" + feed.Token + ".ics"
+	return gin.H{
+		"url":       url,
+		"webcalUrl": "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"),
+		"createdAt": feed.CreatedAt,
+	}
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type TrainingZoneController struct {
+	zoneService services.TrainingZoneService
+}
+
+func NewTrainingZoneController(zoneService services.TrainingZoneService) *TrainingZoneController {
+	return &TrainingZoneController{
+		zoneService: zoneService,
+	}
+}
+
+// GetZones returns the current user's training zones
+func (zc *TrainingZoneController) GetZones(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	zones, err := zc.zoneService.GetTrainingZones(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, zones)
+}
+
+// UpdateZones derives the current user's training zones from a race result and stores them
+func (zc *TrainingZoneController) UpdateZones(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.TrainingZonesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zones, err := zc.zoneService.SaveTrainingZones(c.Request.Context(), userID, req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, zones)
+}
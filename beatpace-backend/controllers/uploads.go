@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadBytes caps uploaded workout and activity files
+const maxUploadBytes = 10 << 20
+
+// readUploadedFile reads a multipart file field into memory
+func readUploadedFile(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		return nil, fmt.Errorf("missing %q file upload: %v", field, err)
+	}
+	if header.Size > maxUploadBytes {
+		return nil, fmt.Errorf("file is too large, the maximum is %d MB", maxUploadBytes>>20)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening upload: %v", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload: %v", err)
+	}
+	return data, nil
+}
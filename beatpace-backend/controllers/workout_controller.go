@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yimango/beatpace-backend/services"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+type WorkoutController struct {
+	workoutService services.WorkoutService
+}
+
+func NewWorkoutController(workoutService services.WorkoutService) *WorkoutController {
+	return &WorkoutController{
+		workoutService: workoutService,
+	}
+}
+
+// ListWorkouts returns the current user's saved workouts
+func (wc *WorkoutController) ListWorkouts(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workouts, err := wc.workoutService.ListWorkouts(c.Request.Context(), userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workouts": workouts})
+}
+
+// GetWorkout returns a single saved workout
+func (wc *WorkoutController) GetWorkout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workout, err := wc.workoutService.GetWorkout(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workout)
+}
+
+// CreateWorkout saves a new workout template
+func (wc *WorkoutController) CreateWorkout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.WorkoutTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workout, err := wc.workoutService.CreateWorkout(c.Request.Context(), userID, req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workout)
+}
+
+// UpdateWorkout replaces a saved workout template
+func (wc *WorkoutController) UpdateWorkout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.WorkoutTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workout, err := wc.workoutService.UpdateWorkout(c.Request.Context(), userID, c.Param("id"), req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workout)
+}
+
+// DeleteWorkout removes a saved workout template
+func (wc *WorkoutController) DeleteWorkout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := wc.workoutService.DeleteWorkout(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DuplicateWorkout copies a saved workout template
+func (wc *WorkoutController) DuplicateWorkout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workout, err := wc.workoutService.DuplicateWorkout(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workout)
+}
+
+// GenerateFromWorkout generates a playlist for a saved workout template
+func (wc *WorkoutController) GenerateFromWorkout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.GenerateFromWorkoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response, err := wc.workoutService.GenerateFromWorkout(c.Request.Context(), userID, c.Param("id"), req)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
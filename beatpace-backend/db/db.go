@@ -3,26 +3,146 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
+// Supported values of DB_DRIVER
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+const (
+	// defaultSQLitePath is the database file used when DB_DRIVER is sqlite and
+	// DB_PATH is not set
+	defaultSQLitePath = "beatpace.db"
+	// defaultPostgresPort and defaultPostgresSSLMode apply when DB_PORT and
+	// DB_SSLMODE are not set
+	defaultPostgresPort    = "5432"
+	defaultPostgresSSLMode = "prefer"
+)
+
+// DriverFromEnv returns the database driver selected by DB_DRIVER, MySQL by default
+func DriverFromEnv() (string, error) {
+	driver := os.Getenv("DB_DRIVER")
+	switch driver {
+	case "":
+		return DriverMySQL, nil
+	case DriverMySQL, DriverSQLite, DriverPostgres:
+		return driver, nil
+	default:
+		return "", fmt.Errorf("unsupported DB_DRIVER %q", driver)
+	}
+}
+
+// InitFromEnv opens the database selected by DB_DRIVER. MySQL and PostgreSQL are
+// configured with DB_USER, DB_PASS, DB_HOST, DB_PORT and DB_NAME, plus DB_SSLMODE
+// for PostgreSQL; SQLite with DB_PATH.
 func InitFromEnv() (*sql.DB, error) {
-    dsn := fmt.Sprintf(
-        "%s:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true",
-        os.Getenv("DB_USER"),
-        os.Getenv("DB_PASS"),
-        os.Getenv("DB_HOST"),
-        os.Getenv("DB_PORT"),
-        os.Getenv("DB_NAME"),
-    )
-    db, err := sql.Open("mysql", dsn)
-    if err != nil {
-        return nil, err
-    }
-    if err := db.Ping(); err != nil {
-        return nil, err
-    }
-    return db, nil
+	driver, err := DriverFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var db *sql.DB
+	switch driver {
+	case DriverSQLite:
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = defaultSQLitePath
+		}
+		db, err = OpenSQLite(path)
+	case DriverPostgres:
+		db, err = sql.Open("pgx", postgresDSNFromEnv())
+	default:
+		db, err = sql.Open("mysql", mysqlDSNFromEnv())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenSQLite opens a SQLite database file, creating it if needed. Foreign keys are
+// enforced and writers wait for each other instead of failing with SQLITE_BUSY.
+func OpenSQLite(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	return sql.Open("sqlite", dsn)
+}
+
+// mysqlDSNFromEnv builds the go-sql-driver DSN. The driver escapes the settings, so
+// passwords may contain any character.
+func mysqlDSNFromEnv() string {
+	config := mysql.NewConfig()
+	config.User = os.Getenv("DB_USER")
+	config.Passwd = os.Getenv("DB_PASS")
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(os.Getenv("DB_HOST"), os.Getenv("DB_PORT"))
+	config.DBName = os.Getenv("DB_NAME")
+	config.ParseTime = true
+	return config.FormatDSN()
+}
+
+// postgresDSNFromEnv builds a postgres:// connection URL
+func postgresDSNFromEnv() string {
+	port := os.Getenv("DB_PORT")
+	if port == "" {
+		port = defaultPostgresPort
+	}
+	sslMode := os.Getenv("DB_SSLMODE")
+	if sslMode == "" {
+		sslMode = defaultPostgresSSLMode
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(os.Getenv("DB_USER"), os.Getenv("DB_PASS")),
+		Host:     net.JoinHostPort(os.Getenv("DB_HOST"), port),
+		Path:     "/" + os.Getenv("DB_NAME"),
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// Rebind rewrites the ? placeholders of a query into the form the driver expects.
+// Queries are written with ?, which MySQL and SQLite take as is; PostgreSQL numbers
+// its parameters as $1, $2 and so on. Question marks inside quoted strings are kept.
+func Rebind(driver string, query string) string {
+	if driver != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var rebound strings.Builder
+	rebound.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, ch := range query {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			rebound.WriteByte('$')
+			rebound.WriteString(strconv.Itoa(n))
+			continue
+		}
+		rebound.WriteRune(ch)
+	}
+	return rebound.String()
 }
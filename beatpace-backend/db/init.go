@@ -1,31 +1,39 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"path/filepath"
+	"log"
 )
 
-// InitDatabase initializes the database and runs migrations
-func InitDatabase() (*sql.DB, error) {
+// InitDatabase connects to the database selected by DB_DRIVER, applies any pending
+// migrations and returns the connection with its driver
+func InitDatabase() (*sql.DB, string, error) {
+	driver, err := DriverFromEnv()
+	if err != nil {
+		return nil, "", err
+	}
+
 	// First connect to the database
 	db, err := InitFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
+		return nil, "", fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	// Read and execute migrations
-	migrationSQL, err := os.ReadFile(filepath.Join("db", "migrations.sql"))
+	migrator, err := NewMigrator(db, driver)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read migrations file: %v", err)
+		db.Close()
+		return nil, "", err
 	}
-
-	// Execute migrations
-	_, err = db.Exec(string(migrationSQL))
+	applied, err := migrator.Up(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute migrations: %v", err)
+		db.Close()
+		return nil, "", fmt.Errorf("failed to execute migrations: %v", err)
+	}
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
 	}
 
-	return db, nil
-} 
\ No newline at end of file
+	return db, driver, nil
+}
@@ -0,0 +1,505 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// migrationFiles holds the schema migrations, compiled into the binary, with one
+// directory per driver. Each version is a pair of files named
+// NNNN_description.up.sql and NNNN_description.down.sql, and every driver has the
+// same versions. Applied migrations must never be edited; change the schema with a
+// new version.
+//
+// The first MySQL versions create their tables with IF NOT EXISTS, so databases set
+// up by the old single migrations.sql file are adopted without changes.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+const (
+	// migrationLockName is the advisory lock held while migrating, so only one
+	// instance changes the schema at a time
+	migrationLockName = "beatpace_schema_migrations"
+	// migrationLockTimeout is how long to wait for another instance to finish, in seconds
+	migrationLockTimeout = 60
+	// migrationLockStale is how old a SQLite lock row must be before it is assumed
+	// abandoned by a crashed process; MySQL and PostgreSQL release their locks with
+	// the connection
+	migrationLockStale = 10 * time.Minute
+)
+
+var (
+	// ErrMigrationLocked is returned when another instance holds the migration lock
+	// for longer than migrationLockTimeout
+	ErrMigrationLocked = errors.New("migrations are locked by another instance")
+	// ErrChecksumMismatch is returned when an applied migration's file has changed
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	// ErrUnknownMigration is returned when the database has a migration this build
+	// does not have, usually because a newer build has already migrated it
+	ErrUnknownMigration = errors.New("database has a migration this build does not know")
+)
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one schema version
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up, hex encoded
+}
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool // Applied, but the file no longer matches the recorded checksum
+	Unknown   bool // Applied, but missing from this build
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back the embedded migrations, recording them in the
+// schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the driver's migrations compiled into the binary
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	if driver != DriverMySQL && driver != DriverSQLite && driver != DriverPostgres {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+	files, err := fs.Sub(migrationFiles, "migrations/"+driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %v", err)
+	}
+	migrations, err := LoadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// LoadMigrations reads the up and down files of every migration in fsys, ordered by
+// version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file %s in migrations", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		sum := sha256.Sum256([]byte(migration.Up))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order and returns those applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the most recently applied migrations, newest first, and returns
+// those rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.rollback(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration and any applied ones this build does not know,
+// in version order
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if row, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &row.AppliedAt
+				status.Modified = row.Checksum != migration.Checksum
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, row := range applied {
+			statuses = append(statuses, MigrationStatus{
+				Version:   row.Version,
+				Name:      row.Name,
+				Applied:   true,
+				AppliedAt: &row.AppliedAt,
+				Unknown:   true,
+			})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, err
+}
+
+// withLock runs fn on a single connection while holding the migration lock. The
+// schema_migrations table is created first if needed.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	// The lock belongs to the database session, so everything runs on one connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	timestampType := "TIMESTAMP"
+	if m.driver == DriverPostgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+	_, err = conn.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at `+timestampType+` NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return fn(conn)
+}
+
+// lock takes the migration lock and returns the function that releases it. MySQL
+// and PostgreSQL have advisory locks; SQLite has none, so a single-row table stands
+// in.
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	switch m.driver {
+	case DriverMySQL:
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked); err != nil {
+			return nil, fmt.Errorf("failed to take migration lock: %v", err)
+		}
+		if !locked.Valid || locked.Int64 != 1 {
+			return nil, ErrMigrationLocked
+		}
+		return func() {
+			conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLockName)
+		}, nil
+	case DriverPostgres:
+		// Advisory locks take a number, so the name is hashed into one
+		err := m.retryLock(ctx, func() (bool, error) {
+			var locked bool
+			err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", migrationLockName).Scan(&locked)
+			return locked, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", migrationLockName)
+		}, nil
+	}
+
+	_, err := conn.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			locked_at TIMESTAMP NOT NULL
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations_lock table: %v", err)
+	}
+	err = m.retryLock(ctx, func() (bool, error) {
+		now := time.Now().UTC()
+		if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations_lock WHERE locked_at < ?", now.Add(-migrationLockStale)); err != nil {
+			return false, err
+		}
+		result, err := conn.ExecContext(ctx, "INSERT OR IGNORE INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", now)
+		if err != nil {
+			return false, err
+		}
+		affected, err := result.RowsAffected()
+		return affected == 1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "DELETE FROM schema_migrations_lock WHERE id = 1")
+	}, nil
+}
+
+// retryLock calls try once a second until it takes the lock or migrationLockTimeout
+// passes
+func (m *Migrator) retryLock(ctx context.Context, try func() (bool, error)) error {
+	deadline := time.Now().Add(migrationLockTimeout * time.Second)
+	for {
+		locked, err := try()
+		if err != nil {
+			return fmt.Errorf("failed to take migration lock: %v", err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// applied returns the rows of schema_migrations by version
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error listing applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning applied migration: %v", err)
+		}
+		applied[row.Version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing applied migrations: %v", err)
+	}
+	return applied, nil
+}
+
+// verify returns the applied migrations after checking each one is known to this
+// build and unchanged since it was applied
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+	for version, row := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, row.Name)
+		}
+		if row.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+	return applied, nil
+}
+
+// apply runs a migration's up file and records it. On databases with transactional
+// DDL both happen together; elsewhere the schema change commits on its own.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := execStatements(ctx, tx, migration.Up); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %v", migration.Version, migration.Name, err)
+	}
+	_, err = tx.ExecContext(ctx,
+		Rebind(m.driver, "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+		migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error recording migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// rollback runs a migration's down file and removes its record
+func (m *Migrator) rollback(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := execStatements(ctx, tx, migration.Down); err != nil {
+		return fmt.Errorf("rollback of %d_%s failed: %v", migration.Version, migration.Name, err)
+	}
+	_, err = tx.ExecContext(ctx, Rebind(m.driver, "DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	if err != nil {
+		return fmt.Errorf("error removing migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing rollback of %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// execStatements runs a migration file one statement at a time, so the application's
+// connections never need to accept several statements in one query
+func execStatements(ctx context.Context, tx *sql.Tx, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a SQL script on the semicolons that end its statements.
+// Semicolons inside quotes or -- comments do not count, and statements that hold
+// nothing but comments are dropped.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	hasCode := false
+	flush := func() {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+		switch {
+		case ch == '-' && i+1 < len(script) && script[i+1] == '-':
+			stop := len(script)
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				stop = i + end
+			}
+			current.WriteString(script[i:stop])
+			i = stop - 1
+		case ch == '\'' || ch == '"' || ch == '`':
+			stop := len(script)
+			if end := strings.IndexByte(script[i+1:], ch); end >= 0 {
+				stop = i + 1 + end + 1
+			}
+			current.WriteString(script[i:stop])
+			i = stop - 1
+			hasCode = true
+		case ch == ';':
+			flush()
+		default:
+			current.WriteByte(ch)
+			if !unicode.IsSpace(rune(ch)) {
+				hasCode = true
+			}
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- Create a table; the comment has a semicolon
+CREATE TABLE t (
+    id INT PRIMARY KEY, -- trailing; comment
+    note VARCHAR(10) NOT NULL DEFAULT 'a;b'
+);
+CREATE INDEX idx_t ON t (note);
+
+-- Nothing but a comment follows the last statement
+`
+	want := []string{
+		"-- Create a table; the comment has a semicolon\nCREATE TABLE t (\n    id INT PRIMARY KEY, -- trailing; comment\n    note VARCHAR(10) NOT NULL DEFAULT 'a;b'\n)",
+		"CREATE INDEX idx_t ON t (note)",
+	}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitStatements =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestEmbeddedMigrationsSplit(t *testing.T) {
+	for _, driver := range []string{DriverMySQL, DriverSQLite, DriverPostgres} {
+		migrator, err := NewMigrator(nil, driver)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		for _, migration := range migrator.migrations {
+			if len(splitStatements(migration.Up)) == 0 || len(splitStatements(migration.Down)) == 0 {
+				t.Errorf("%s: migration %d_%s has an empty up or down file", driver, migration.Version, migration.Name)
+			}
+		}
+	}
+}
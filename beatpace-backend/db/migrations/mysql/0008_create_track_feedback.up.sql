@@ -0,0 +1,26 @@
+-- Create track_feedback table
+CREATE TABLE IF NOT EXISTS track_feedback (
+    user_id CHAR(36) NOT NULL,
+    spotify_track_id VARCHAR(64) NOT NULL,
+    rating TINYINT NOT NULL DEFAULT 0,
+    wrong_tempo INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    too_slow INT NOT NULL DEFAULT 0,
+    too_fast INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, spotify_track_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create playlist_feedback_effects table
+CREATE TABLE IF NOT EXISTS playlist_feedback_effects (
+    playlist_id CHAR(36) NOT NULL,
+    spotify_track_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    score DOUBLE NOT NULL,
+    tempo_trust DOUBLE NOT NULL,
+    included BOOLEAN NOT NULL,
+    excluded BOOLEAN NOT NULL,
+    PRIMARY KEY (playlist_id, spotify_track_id),
+    FOREIGN KEY (playlist_id) REFERENCES playlists(id) ON DELETE CASCADE
+);
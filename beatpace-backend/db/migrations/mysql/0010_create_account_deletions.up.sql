@@ -0,0 +1,10 @@
+-- Create account_deletions table
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    removed_rows JSON NOT NULL,
+    playlists_unfollowed INT NOT NULL DEFAULT 0,
+    unfollow_failures INT NOT NULL DEFAULT 0,
+    deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_account_deletions_user (user_id)
+);
@@ -0,0 +1,8 @@
+-- Create playlist_templates table
+CREATE TABLE IF NOT EXISTS playlist_templates (
+    user_id UUID PRIMARY KEY,
+    name_template VARCHAR(1024) NOT NULL,
+    description_template TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
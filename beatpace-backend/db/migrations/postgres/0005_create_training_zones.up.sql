@@ -0,0 +1,15 @@
+-- Create training_zones table
+CREATE TABLE IF NOT EXISTS training_zones (
+    user_id UUID PRIMARY KEY,
+    method VARCHAR(16) NOT NULL,
+    race_distance_meters DOUBLE PRECISION NOT NULL,
+    race_time_seconds INT NOT NULL,
+    vdot DOUBLE PRECISION NULL,
+    easy_seconds INT NOT NULL,
+    marathon_seconds INT NOT NULL,
+    threshold_seconds INT NOT NULL,
+    interval_seconds INT NOT NULL,
+    repetition_seconds INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cadence_program_events;
+DROP TABLE IF EXISTS cadence_programs;
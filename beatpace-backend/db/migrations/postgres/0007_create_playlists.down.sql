@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS playlist_tracks;
+DROP TABLE IF EXISTS playlists;
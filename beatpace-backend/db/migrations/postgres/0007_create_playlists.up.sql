@@ -0,0 +1,38 @@
+-- Create playlists table. created_at keeps microseconds so the history cursor has a
+-- stable order.
+CREATE TABLE IF NOT EXISTS playlists (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    spotify_playlist_id VARCHAR(64) NOT NULL,
+    url VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    pace_seconds INT NULL,
+    pace_unit VARCHAR(8) NOT NULL DEFAULT 'km',
+    activity VARCHAR(16) NOT NULL DEFAULT 'running',
+    cadence INT NULL,
+    target_bpm INT NOT NULL,
+    filters JSON NOT NULL,
+    inputs JSON NOT NULL,
+    track_count INT NOT NULL,
+    duration_ms INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_playlists_user_created ON playlists (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_playlists_user_bpm ON playlists (user_id, target_bpm);
+
+-- Create playlist_tracks table
+CREATE TABLE IF NOT EXISTS playlist_tracks (
+    playlist_id UUID NOT NULL,
+    position INT NOT NULL,
+    spotify_track_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    artists JSON NOT NULL,
+    bpm REAL NOT NULL,
+    tempo_measured BOOLEAN NOT NULL DEFAULT FALSE,
+    duration_ms INT NOT NULL,
+    PRIMARY KEY (playlist_id, position),
+    FOREIGN KEY (playlist_id) REFERENCES playlists(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_playlist_tracks_track ON playlist_tracks (spotify_track_id);
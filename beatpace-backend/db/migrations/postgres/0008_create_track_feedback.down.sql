@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS playlist_feedback_effects;
+DROP TABLE IF EXISTS track_feedback;
@@ -0,0 +1,10 @@
+-- Create account_deletions table
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    removed_rows JSON NOT NULL,
+    playlists_unfollowed INT NOT NULL DEFAULT 0,
+    unfollow_failures INT NOT NULL DEFAULT 0,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_user ON account_deletions (user_id);
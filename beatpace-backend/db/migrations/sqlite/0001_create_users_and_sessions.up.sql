@@ -0,0 +1,35 @@
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+    id CHAR(36) PRIMARY KEY,
+    spotify_user_id VARCHAR(255) NOT NULL UNIQUE,
+    email VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login TIMESTAMP NULL
+);
+CREATE INDEX IF NOT EXISTS idx_spotify_user_id ON users (spotify_user_id);
+
+-- Create spotify_tokens table
+CREATE TABLE IF NOT EXISTS spotify_tokens (
+    internal_user_id CHAR(36) NOT NULL,
+    spotify_user_id VARCHAR(255) NOT NULL,
+    access_token TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    generated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (internal_user_id),
+    FOREIGN KEY (internal_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (spotify_user_id) REFERENCES users(spotify_user_id) ON DELETE CASCADE
+);
+
+-- Create sessions table
+CREATE TABLE IF NOT EXISTS sessions (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_token ON sessions (token);
+CREATE INDEX IF NOT EXISTS idx_expires_at ON sessions (expires_at);
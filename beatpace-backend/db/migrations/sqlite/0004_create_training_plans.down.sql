@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS calendar_feeds;
+DROP TABLE IF EXISTS training_sessions;
+DROP TABLE IF EXISTS training_plans;
@@ -0,0 +1,42 @@
+-- Create training_plans table
+CREATE TABLE IF NOT EXISTS training_plans (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    lead_hours INT NOT NULL DEFAULT 12,
+    settings TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_training_plans_user_id ON training_plans (user_id);
+
+-- Create training_sessions table. workout_id has no foreign key so deleting a
+-- workout leaves the session in place; generating it then fails with a clear error.
+CREATE TABLE IF NOT EXISTS training_sessions (
+    id CHAR(36) PRIMARY KEY,
+    plan_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    workout_id CHAR(36) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    notes VARCHAR(500) NOT NULL DEFAULT '',
+    scheduled_at TIMESTAMP NOT NULL,
+    generate_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'scheduled',
+    attempts INT NOT NULL DEFAULT 0,
+    playlist_url VARCHAR(255) NULL,
+    last_error TEXT NULL,
+    generated_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (plan_id) REFERENCES training_plans(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_training_sessions_user_scheduled ON training_sessions (user_id, scheduled_at);
+CREATE INDEX IF NOT EXISTS idx_training_sessions_due ON training_sessions (status, generate_at);
+
+-- Create calendar_feeds table
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id CHAR(36) PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
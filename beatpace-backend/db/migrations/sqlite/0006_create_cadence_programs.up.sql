@@ -0,0 +1,25 @@
+-- Create cadence_programs table
+CREATE TABLE IF NOT EXISTS cadence_programs (
+    user_id CHAR(36) PRIMARY KEY,
+    start_cadence INT NOT NULL,
+    target_cadence INT NOT NULL,
+    step_spm INT NOT NULL,
+    current_stage INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    stage_started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create cadence_program_events table. History outlives the program it belongs to.
+CREATE TABLE IF NOT EXISTS cadence_program_events (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    stage INT NOT NULL,
+    cadence INT NOT NULL,
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_cadence_program_events_user ON cadence_program_events (user_id, created_at);
@@ -0,0 +1,15 @@
+-- Create user_preferences table
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id CHAR(36) PRIMARY KEY,
+    height DOUBLE NULL,
+    height_unit VARCHAR(8) NOT NULL DEFAULT 'cm',
+    gender VARCHAR(16) NOT NULL DEFAULT '',
+    stride_length_cm DOUBLE NULL,
+    pace_unit VARCHAR(8) NOT NULL DEFAULT 'km',
+    tempo_tolerance INT NOT NULL DEFAULT 10,
+    explicit_content VARCHAR(16) NOT NULL DEFAULT 'allow',
+    genres TEXT NOT NULL,
+    playlist_public BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
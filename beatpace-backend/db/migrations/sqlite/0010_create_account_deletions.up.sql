@@ -0,0 +1,10 @@
+-- Create account_deletions table
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    removed_rows TEXT NOT NULL,
+    playlists_unfollowed INT NOT NULL DEFAULT 0,
+    unfollow_failures INT NOT NULL DEFAULT 0,
+    deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_user ON account_deletions (user_id);
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
@@ -16,6 +17,11 @@ import (
 )
 
 func main() {
+	// Schema maintenance runs without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
 	// Set Gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -36,16 +42,30 @@ func main() {
 		log.Printf("Found %s: %s", envVar, value[:min(5, len(value))]+"...")
 	}
 
-	// 1) initialize the DB connection and run migrations
-	sqlDB, err := db.InitDatabase()
+	// 1) initialize the DB connection and apply pending migrations
+	sqlDB, driver, err := db.InitDatabase()
 	if err != nil {
 		log.Fatalf("failed to initialize DB: %v", err)
 	}
 	defer sqlDB.Close()
+	store, err := repository.NewDB(sqlDB, driver)
+	if err != nil {
+		log.Fatalf("failed to initialize DB: %v", err)
+	}
 
 	// 2) wire up your repositories
-	userRepo := repository.NewUserRepo(sqlDB)
-	tokenRepo := repository.NewTokenRepo(sqlDB)
+	userRepo := repository.NewUserRepo(store)
+	tokenRepo := repository.NewTokenRepo(store)
+	templateRepo := repository.NewPlaylistTemplateRepo(store)
+	workoutRepo := repository.NewWorkoutRepo(store)
+	planRepo := repository.NewTrainingPlanRepo(store)
+	feedRepo := repository.NewCalendarFeedRepo(store)
+	zoneRepo := repository.NewTrainingZoneRepo(store)
+	programRepo := repository.NewCadenceProgramRepo(store)
+	playlistRepo := repository.NewPlaylistRepo(store)
+	feedbackRepo := repository.NewTrackFeedbackRepo(store)
+	prefsRepo := repository.NewUserPreferencesRepo(store)
+	accountRepo := repository.NewAccountRepo(store)
 
 	// Print Spotify configuration for debugging
 	log.Printf("Spotify Configuration - Client ID: %s, Redirect URI: %s", os.Getenv("CLIENT_ID"), os.Getenv("REDIRECT_URI"))
@@ -53,11 +73,34 @@ func main() {
 	// 3) wire up your services
 	userService := services.NewUserService(userRepo)
 	authService := services.NewAuthService(userRepo, tokenRepo)
-	spotifyService := services.NewSpotifyService(userRepo, tokenRepo)
+	spotifyService := services.NewSpotifyService(userRepo, tokenRepo, templateRepo, zoneRepo, programRepo, playlistRepo, feedbackRepo, prefsRepo)
+	templateService := services.NewPlaylistTemplateService(templateRepo)
+	workoutService := services.NewWorkoutService(workoutRepo, spotifyService)
+	planService := services.NewTrainingPlanService(planRepo, feedRepo, workoutService)
+	zoneService := services.NewTrainingZoneService(zoneRepo)
+	programService := services.NewCadenceProgramService(programRepo)
+	historyService := services.NewPlaylistHistoryService(playlistRepo)
+	feedbackService := services.NewTrackFeedbackService(feedbackRepo, playlistRepo)
+	prefsService := services.NewPreferencesService(prefsRepo)
+	accountService := services.NewAccountService(accountRepo, userRepo, tokenRepo, templateRepo, workoutRepo, planRepo, feedRepo,
+		zoneRepo, programRepo, playlistRepo, feedbackRepo, prefsRepo, spotifyService)
 
 	// 4) create your controllers
 	userController := controllers.NewUserController(userService, authService, spotifyService)
 	spotifyController := controllers.NewSpotifyController(spotifyService)
+	templateController := controllers.NewPlaylistTemplateController(templateService)
+	workoutController := controllers.NewWorkoutController(workoutService)
+	planController := controllers.NewTrainingPlanController(planService)
+	zoneController := controllers.NewTrainingZoneController(zoneService)
+	calcController := controllers.NewCalculatorController()
+	programController := controllers.NewCadenceProgramController(programService)
+	historyController := controllers.NewPlaylistHistoryController(historyService)
+	feedbackController := controllers.NewTrackFeedbackController(feedbackService)
+	prefsController := controllers.NewPreferencesController(prefsService)
+	accountController := controllers.NewAccountController(accountService)
+
+	// Generate training session playlists in the background as they fall due
+	go services.RunPlanScheduler(context.Background(), planService, planSchedulerInterval())
 
 	// 5) create the Gin router
 	router := gin.Default()
@@ -68,7 +111,7 @@ func main() {
 	// 6) configure CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -83,14 +126,57 @@ func main() {
 		api.POST("/register", userController.Register)
 		api.GET("/callback", userController.Callback)
 		api.GET("/spotify-auth", spotifyController.GetAuthURL)
+		api.GET("/calendar/:token", planController.CalendarFeed)
+		api.GET("/calc/pace", calcController.Pace)
+		api.GET("/calc/predict", calcController.Predict)
+		api.GET("/calc/cadence", calcController.Cadence)
 
 		// Protected routes
 		protected := api.Group("")
 		protected.Use(middleware.JWT(authService))
 		{
 			protected.GET("/me", userController.MeHandler)
+			protected.DELETE("/me", accountController.DeleteAccount)
+			protected.GET("/me/export", accountController.ExportAccount)
 			protected.POST("/generate-playlist", spotifyController.GeneratePlaylist)
+			protected.POST("/generate-ladder", spotifyController.GeneratePaceLadder)
+			protected.POST("/generate-variants", spotifyController.GenerateVariants)
+			protected.POST("/variants/:id/publish", spotifyController.PublishVariants)
+			protected.POST("/generate-workout", spotifyController.GenerateWorkoutPlaylist)
+			protected.POST("/generate-progression", spotifyController.GenerateProgressionRun)
+			protected.POST("/generate-race", spotifyController.GenerateRacePlan)
+			protected.POST("/import-workout", spotifyController.ImportWorkout)
+			protected.POST("/replay-run", spotifyController.ReplayRun)
+			protected.POST("/generate-treadmill", spotifyController.GenerateTreadmillPlaylist)
 			protected.POST("/signout", userController.SignOut)
+			protected.GET("/me/playlist-templates", templateController.GetTemplates)
+			protected.PUT("/me/playlist-templates", templateController.UpdateTemplates)
+			protected.GET("/workouts", workoutController.ListWorkouts)
+			protected.POST("/workouts", workoutController.CreateWorkout)
+			protected.GET("/workouts/:id", workoutController.GetWorkout)
+			protected.PUT("/workouts/:id", workoutController.UpdateWorkout)
+			protected.DELETE("/workouts/:id", workoutController.DeleteWorkout)
+			protected.POST("/workouts/:id/duplicate", workoutController.DuplicateWorkout)
+			protected.POST("/workouts/:id/generate", workoutController.GenerateFromWorkout)
+			protected.GET("/plans", planController.ListPlans)
+			protected.POST("/plans", planController.CreatePlan)
+			protected.GET("/plans/:id", planController.GetPlan)
+			protected.DELETE("/plans/:id", planController.DeletePlan)
+			protected.GET("/me/calendar-feed", planController.GetCalendarFeed)
+			protected.POST("/me/calendar-feed/rotate", planController.RotateCalendarFeed)
+			protected.GET("/me/training-zones", zoneController.GetZones)
+			protected.PUT("/me/training-zones", zoneController.UpdateZones)
+			protected.GET("/me/cadence-program", programController.GetProgram)
+			protected.PUT("/me/cadence-program", programController.SaveProgram)
+			protected.DELETE("/me/cadence-program", programController.DeleteProgram)
+			protected.POST("/me/cadence-program/advance", programController.Advance)
+			protected.POST("/me/cadence-program/hold", programController.Hold)
+			protected.GET("/playlists", historyController.ListPlaylists)
+			protected.GET("/playlists/:id", historyController.GetPlaylist)
+			protected.POST("/playlists/:id/feedback", feedbackController.RateTrack)
+			protected.GET("/me/track-feedback/impact", feedbackController.Impact)
+			protected.GET("/me/preferences", prefsController.GetPreferences)
+			protected.PUT("/me/preferences", prefsController.UpdatePreferences)
 		}
 	}
 
@@ -99,6 +185,20 @@ func main() {
 	router.Run(":3001")
 }
 
+// planSchedulerInterval reads PLAN_SCHEDULER_INTERVAL (e.g. "5m"), defaulting to five minutes
+func planSchedulerInterval() time.Duration {
+	raw := os.Getenv("PLAN_SCHEDULER_INTERVAL")
+	if raw == "" {
+		return 5 * time.Minute
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid PLAN_SCHEDULER_INTERVAL %q, using 5m", raw)
+		return 5 * time.Minute
+	}
+	return interval
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountDeletion is the audit record kept after a user deletes their account. It
+// holds no personal data beyond the internal user ID, which no longer resolves to
+// anything once the account is gone.
+type AccountDeletion struct {
+	ID                  uuid.UUID `db:"id"`                   // Primary key
+	UserID              uuid.UUID `db:"user_id"`              // Internal ID of the deleted user
+	RemovedRows         string    `db:"removed_rows"`         // JSON-encoded row counts per table
+	PlaylistsUnfollowed int       `db:"playlists_unfollowed"` // Playlists unfollowed on Spotify
+	UnfollowFailures    int       `db:"unfollow_failures"`    // Playlists that could not be unfollowed
+	DeletedAt           time.Time `db:"deleted_at"`           // When the account was deleted
+}
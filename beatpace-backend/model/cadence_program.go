@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cadence program history actions
+const (
+	CadenceProgramStarted  = "started"  // Program created or replaced
+	CadenceProgramAdvanced = "advanced" // Moved to the next stage
+	CadenceProgramHeld     = "held"     // Stayed at the current stage for another week
+	CadenceProgramStopped  = "stopped"  // Program removed
+)
+
+// CadenceProgram raises (or lowers) a user's running cadence in steps, one stage at a
+// time, from a starting cadence to a target
+type CadenceProgram struct {
+	UserID         uuid.UUID `db:"user_id"`          // Reference to the user
+	StartCadence   int       `db:"start_cadence"`    // Steps per minute at stage 0
+	TargetCadence  int       `db:"target_cadence"`   // Steps per minute at the final stage
+	StepSPM        int       `db:"step_spm"`         // Change in steps per minute per stage
+	CurrentStage   int       `db:"current_stage"`    // Stage playlists are generated for
+	StartedAt      time.Time `db:"started_at"`       // When the program began
+	StageStartedAt time.Time `db:"stage_started_at"` // When the current stage began or was last held
+	UpdatedAt      time.Time `db:"updated_at"`       // Last update timestamp
+}
+
+// CadenceProgramEvent is one entry of a user's cadence program history
+type CadenceProgramEvent struct {
+	ID        uuid.UUID `db:"id"`         // Primary key
+	UserID    uuid.UUID `db:"user_id"`    // Reference to the user
+	Action    string    `db:"action"`     // One of the CadenceProgram* actions
+	Stage     int       `db:"stage"`      // Stage after the action
+	Cadence   int       `db:"cadence"`    // Target cadence after the action
+	Note      string    `db:"note"`       // Optional note, e.g. how the runner felt
+	CreatedAt time.Time `db:"created_at"` // When the action happened
+}
@@ -1,10 +1,39 @@
 package model
 
-import "time"
+import (
+	"time"
 
-// PlaylistResponse is what you return from /api/generate-playlist
-type PlaylistResponse struct {
-  URL       string    `json:"url"`
-  Tracks    []string  `json:"tracks"`
-  CreatedAt time.Time `json:"created_at"`
+	"github.com/google/uuid"
+)
+
+// Playlist records one generated playlist and the inputs it was generated from
+type Playlist struct {
+	ID                uuid.UUID `db:"id"`                  // Primary key
+	UserID            uuid.UUID `db:"user_id"`             // Reference to the owner
+	Kind              string    `db:"kind"`                // Generation mode, e.g. "pace" or "workout"
+	SpotifyPlaylistID string    `db:"spotify_playlist_id"` // Spotify playlist identifier
+	URL               string    `db:"url"`                 // Spotify playlist URL
+	Name              string    `db:"name"`                // Playlist name as published
+	PaceSeconds       *int      `db:"pace_seconds"`        // Pace the tempo is based on, nil for cycling
+	PaceUnit          string    `db:"pace_unit"`           // "km" or "mile"
+	Activity          string    `db:"activity"`            // Cadence model
+	Cadence           *int      `db:"cadence"`             // Steps or pedal revolutions per minute
+	TargetBPM         int       `db:"target_bpm"`          // Target tempo; the fastest segment's for workouts
+	Filters           string    `db:"filters"`             // JSON-encoded types.TrackFilterParams
+	Inputs            string    `db:"inputs"`              // JSON-encoded generation request
+	TrackCount        int       `db:"track_count"`         // Number of tracks
+	DurationMs        int       `db:"duration_ms"`         // Total length of the tracks
+	CreatedAt         time.Time `db:"created_at"`          // Generation timestamp
+}
+
+// PlaylistTrack is one track of a generated playlist
+type PlaylistTrack struct {
+	PlaylistID     uuid.UUID `db:"playlist_id"`      // Reference to the playlist
+	Position       int       `db:"position"`         // Zero-based position in the playlist
+	SpotifyTrackID string    `db:"spotify_track_id"` // Spotify track identifier
+	Name           string    `db:"name"`             // Track name
+	Artists        string    `db:"artists"`          // JSON-encoded artist names
+	BPM            float32   `db:"bpm"`              // Tempo the track was selected with
+	TempoMeasured  bool      `db:"tempo_measured"`   // Whether BPM was measured rather than estimated
+	DurationMs     int       `db:"duration_ms"`      // Track length
 }
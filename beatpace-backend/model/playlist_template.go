@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PlaylistTemplate holds a user's text/template strings for naming generated playlists
+type PlaylistTemplate struct {
+	UserID              uuid.UUID `db:"user_id"`              // Reference to the user
+	NameTemplate        string    `db:"name_template"`        // Template for the playlist name
+	DescriptionTemplate string    `db:"description_template"` // Template for the playlist description
+	UpdatedAt           time.Time `db:"updated_at"`           // Last update timestamp
+}
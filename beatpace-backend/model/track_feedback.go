@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Track feedback kinds a user can give on a track of a generated playlist
+const (
+	TrackFeedbackUp         = "up"          // Thumbs up; replaces a thumbs down
+	TrackFeedbackDown       = "down"        // Thumbs down; replaces a thumbs up
+	TrackFeedbackWrongTempo = "wrong-tempo" // The track's tempo data is wrong
+	TrackFeedbackSkipped    = "skipped"     // Skipped during a run
+	TrackFeedbackTooSlow    = "too-slow"    // Felt too slow for the run
+	TrackFeedbackTooFast    = "too-fast"    // Felt too fast for the run
+)
+
+// TrackFeedback is everything a user has said about one track. Thumbs are a single
+// rating; the other kinds are counted.
+type TrackFeedback struct {
+	UserID         uuid.UUID `db:"user_id"`          // Reference to the user
+	SpotifyTrackID string    `db:"spotify_track_id"` // Spotify track identifier
+	Rating         int       `db:"rating"`           // 1 thumbs up, -1 thumbs down, 0 neither
+	WrongTempo     int       `db:"wrong_tempo"`      // Number of wrong tempo reports
+	Skipped        int       `db:"skipped"`          // Number of skips
+	TooSlow        int       `db:"too_slow"`         // Number of too slow reports
+	TooFast        int       `db:"too_fast"`         // Number of too fast reports
+	UpdatedAt      time.Time `db:"updated_at"`       // Last feedback timestamp
+}
+
+// PlaylistFeedbackEffect records how a user's feedback on one track affected a
+// generated playlist
+type PlaylistFeedbackEffect struct {
+	PlaylistID     uuid.UUID `db:"playlist_id"`      // Reference to the playlist
+	SpotifyTrackID string    `db:"spotify_track_id"` // Spotify track identifier
+	Name           string    `db:"name"`             // Track name
+	Score          float64   `db:"score"`            // Ranking adjustment; positive boosts, negative penalizes
+	TempoTrust     float64   `db:"tempo_trust"`      // Trust in the track's tempo data, 0-1
+	Included       bool      `db:"included"`         // Whether the track made it into the playlist
+	Excluded       bool      `db:"excluded"`         // Whether the track was dropped for untrusted tempo
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Training session statuses
+const (
+	SessionScheduled  = "scheduled"  // Waiting for its generation time
+	SessionGenerating = "generating" // Claimed by the scheduler
+	SessionReady      = "ready"      // Playlist generated
+	SessionFailed     = "failed"     // Generation failed and will not be retried
+	SessionMissed     = "missed"     // The session passed before its playlist could be generated
+)
+
+// TrainingPlan is a schedule of dated sessions attached to a user
+type TrainingPlan struct {
+	ID        uuid.UUID `db:"id"`         // Primary key
+	UserID    uuid.UUID `db:"user_id"`    // Reference to the owner
+	Name      string    `db:"name"`       // Display name
+	LeadHours int       `db:"lead_hours"` // Hours before a session its playlist is generated
+	Settings  string    `db:"settings"`   // JSON-encoded types.GenerateFromWorkoutRequest
+	CreatedAt time.Time `db:"created_at"` // Creation timestamp
+}
+
+// TrainingSession is one dated session of a training plan
+type TrainingSession struct {
+	ID          uuid.UUID  `db:"id"`           // Primary key
+	PlanID      uuid.UUID  `db:"plan_id"`      // Reference to the training plan
+	UserID      uuid.UUID  `db:"user_id"`      // Reference to the owner
+	WorkoutID   uuid.UUID  `db:"workout_id"`   // Workout template to generate
+	Title       string     `db:"title"`        // Calendar event title
+	Notes       string     `db:"notes"`        // Optional notes
+	ScheduledAt time.Time  `db:"scheduled_at"` // Session start time
+	GenerateAt  time.Time  `db:"generate_at"`  // When the scheduler should generate the playlist
+	Status      string     `db:"status"`       // One of the Session* statuses
+	Attempts    int        `db:"attempts"`     // Generation attempts so far
+	PlaylistURL *string    `db:"playlist_url"` // Spotify URL once generated
+	LastError   *string    `db:"last_error"`   // Last generation error
+	GeneratedAt *time.Time `db:"generated_at"` // When the playlist was generated
+	UpdatedAt   time.Time  `db:"updated_at"`   // Last update timestamp
+}
+
+// CalendarFeed holds the secret token of a user's iCalendar feed URL
+type CalendarFeed struct {
+	UserID    uuid.UUID `db:"user_id"`    // Reference to the owner
+	Token     string    `db:"token"`      // Unguessable feed token
+	CreatedAt time.Time `db:"created_at"` // Creation timestamp
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrainingZones holds a user's training paces derived from a race result. Paces are
+// stored in seconds per km.
+type TrainingZones struct {
+	UserID             uuid.UUID `db:"user_id"`              // Reference to the user
+	Method             string    `db:"method"`               // "vdot" or "riegel"
+	RaceDistanceMeters float64   `db:"race_distance_meters"` // Distance of the race result
+	RaceTimeSeconds    int       `db:"race_time_seconds"`    // Finish time of the race result
+	VDOT               *float64  `db:"vdot"`                 // Effective VO2max, VDOT method only
+	EasySeconds        int       `db:"easy_seconds"`         // Easy pace
+	MarathonSeconds    int       `db:"marathon_seconds"`     // Marathon pace
+	ThresholdSeconds   int       `db:"threshold_seconds"`    // Threshold pace
+	IntervalSeconds    int       `db:"interval_seconds"`     // Interval pace
+	RepetitionSeconds  int       `db:"repetition_seconds"`   // Repetition pace
+	UpdatedAt          time.Time `db:"updated_at"`           // Last update timestamp
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserPreferences are a user's stored profile and generation defaults, used for any
+// field a generation request leaves out
+type UserPreferences struct {
+	UserID          uuid.UUID `db:"user_id"`          // Reference to the user
+	Height          *float64  `db:"height"`           // Height in HeightUnit; nil when not given
+	HeightUnit      string    `db:"height_unit"`      // "cm" or "in"
+	Gender          string    `db:"gender"`           // Stride model: "male", "female" or empty for the default
+	StrideLengthCm  *float64  `db:"stride_length_cm"` // Measured step length; overrides the height-based model
+	PaceUnit        string    `db:"pace_unit"`        // Default pace unit, "km" or "mile"
+	TempoTolerance  int       `db:"tempo_tolerance"`  // Accepted BPM distance from the target
+	ExplicitContent string    `db:"explicit_content"` // "allow" or "exclude"
+	Genres          string    `db:"genres"`           // JSON-encoded preferred genres
+	PlaylistPublic  bool      `db:"playlist_public"`  // Publish generated playlists publicly
+	UpdatedAt       time.Time `db:"updated_at"`       // Last update timestamp
+}
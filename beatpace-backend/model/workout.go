@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Workout is a saved workout template: a named list of segment blocks
+type Workout struct {
+	ID          uuid.UUID `db:"id"`          // Primary key
+	UserID      uuid.UUID `db:"user_id"`     // Reference to the owner
+	Name        string    `db:"name"`        // Display name
+	Description string    `db:"description"` // Optional notes
+	PaceUnit    string    `db:"pace_unit"`   // "km" or "mile", applies to every segment pace
+	Blocks      string    `db:"blocks"`      // JSON-encoded []types.WorkoutBlock
+	CreatedAt   time.Time `db:"created_at"`  // Creation timestamp
+	UpdatedAt   time.Time `db:"updated_at"`  // Last update timestamp
+}
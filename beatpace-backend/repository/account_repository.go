@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+// accountTables lists every table holding user data with the statement that removes
+// one user's rows, children before parents. The users row itself goes last.
+var accountTables = []struct {
+	name  string
+	query string
+}{
+	{"playlist_feedback_effects", "DELETE FROM playlist_feedback_effects WHERE playlist_id IN (SELECT id FROM playlists WHERE user_id = ?)"},
+	{"playlist_tracks", "DELETE FROM playlist_tracks WHERE playlist_id IN (SELECT id FROM playlists WHERE user_id = ?)"},
+	{"playlists", "DELETE FROM playlists WHERE user_id = ?"},
+	{"track_feedback", "DELETE FROM track_feedback WHERE user_id = ?"},
+	{"training_sessions", "DELETE FROM training_sessions WHERE user_id = ?"},
+	{"training_plans", "DELETE FROM training_plans WHERE user_id = ?"},
+	{"calendar_feeds", "DELETE FROM calendar_feeds WHERE user_id = ?"},
+	{"workouts", "DELETE FROM workouts WHERE user_id = ?"},
+	{"cadence_program_events", "DELETE FROM cadence_program_events WHERE user_id = ?"},
+	{"cadence_programs", "DELETE FROM cadence_programs WHERE user_id = ?"},
+	{"training_zones", "DELETE FROM training_zones WHERE user_id = ?"},
+	{"user_preferences", "DELETE FROM user_preferences WHERE user_id = ?"},
+	{"playlist_templates", "DELETE FROM playlist_templates WHERE user_id = ?"},
+	{"sessions", "DELETE FROM sessions WHERE user_id = ?"},
+	{"spotify_tokens", "DELETE FROM spotify_tokens WHERE internal_user_id = ?"},
+	{"users", "DELETE FROM users WHERE id = ?"},
+}
+
+type accountRepository struct {
+	db *DB
+}
+
+func NewAccountRepo(db *DB) *accountRepository {
+	return &accountRepository{db: db}
+}
+
+// DeleteAccount removes the user and everything stored about them, and records the
+// audit entry with the removed row counts, in one transaction. Tables are cleared
+// explicitly rather than through cascades so the counts are exact.
+func (r *accountRepository) DeleteAccount(ctx context.Context, userID string, audit *model.AccountDeletion) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	removed := make(map[string]int64, len(accountTables))
+	for _, table := range accountTables {
+		result, err := tx.ExecContext(ctx, table.query, userID)
+		if err != nil {
+			return fmt.Errorf("error deleting %s: %v", table.name, err)
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error deleting %s: %v", table.name, err)
+		}
+		removed[table.name] = count
+	}
+	if removed["users"] == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	encoded, err := json.Marshal(removed)
+	if err != nil {
+		return fmt.Errorf("error encoding removed rows: %v", err)
+	}
+	audit.RemovedRows = string(encoded)
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO account_deletions (id, user_id, removed_rows, playlists_unfollowed, unfollow_failures, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		audit.ID, audit.UserID, audit.RemovedRows, audit.PlaylistsUnfollowed, audit.UnfollowFailures, audit.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("error recording account deletion: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing account deletion: %v", err)
+	}
+	return nil
+}
+
+// ListSessions returns the user's sessions, oldest first
+func (r *accountRepository) ListSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, user_id, token, created_at, expires_at FROM sessions WHERE user_id = ? ORDER BY created_at",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Token, &session.CreatedAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("error scanning session: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing sessions: %v", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type cadenceProgramRepository struct {
+	db *DB
+}
+
+func NewCadenceProgramRepo(db *DB) *cadenceProgramRepository {
+	return &cadenceProgramRepository{db: db}
+}
+
+func (r *cadenceProgramRepository) GetCadenceProgram(ctx context.Context, userID string) (*model.CadenceProgram, error) {
+	var program model.CadenceProgram
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, start_cadence, target_cadence, step_spm, current_stage, started_at, stage_started_at, updated_at
+		FROM cadence_programs WHERE user_id = ?`,
+		userID).Scan(&program.UserID, &program.StartCadence, &program.TargetCadence, &program.StepSPM,
+		&program.CurrentStage, &program.StartedAt, &program.StageStartedAt, &program.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("cadence program %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting cadence program: %v", err)
+	}
+	return &program, nil
+}
+
+// SaveCadenceProgram creates or replaces the user's program and records the event in
+// the same transaction
+func (r *cadenceProgramRepository) SaveCadenceProgram(ctx context.Context, program *model.CadenceProgram, event *model.CadenceProgramEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO cadence_programs (user_id, start_cadence, target_cadence, step_spm, current_stage, started_at,
+			stage_started_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`+r.db.upsert([]string{"user_id"}, "start_cadence", "target_cadence", "step_spm", "current_stage",
+			"started_at", "stage_started_at", "updated_at"),
+		program.UserID, program.StartCadence, program.TargetCadence, program.StepSPM, program.CurrentStage,
+		program.StartedAt, program.StageStartedAt, program.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving cadence program: %v", err)
+	}
+	if err := insertCadenceProgramEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing cadence program: %v", err)
+	}
+	return nil
+}
+
+// DeleteCadenceProgram removes the user's program and records the event in the same
+// transaction
+func (r *cadenceProgramRepository) DeleteCadenceProgram(ctx context.Context, userID string, event *model.CadenceProgramEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM cadence_programs WHERE user_id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("error deleting cadence program: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("cadence program %w", ErrNotFound)
+	}
+	if err := insertCadenceProgramEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing cadence program: %v", err)
+	}
+	return nil
+}
+
+// ListCadenceProgramEvents returns the user's most recent history entries, newest first
+func (r *cadenceProgramRepository) ListCadenceProgramEvents(ctx context.Context, userID string, limit int) ([]model.CadenceProgramEvent, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, action, stage, cadence, note, created_at
+		FROM cadence_program_events WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing cadence program events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []model.CadenceProgramEvent
+	for rows.Next() {
+		var event model.CadenceProgramEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Action, &event.Stage, &event.Cadence, &event.Note,
+			&event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning cadence program event: %v", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing cadence program events: %v", err)
+	}
+	return events, nil
+}
+
+func insertCadenceProgramEvent(ctx context.Context, tx *Tx, event *model.CadenceProgramEvent) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO cadence_program_events (id, user_id, action, stage, cadence, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		event.ID, event.UserID, event.Action, event.Stage, event.Cadence, event.Note, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording cadence program event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type calendarFeedRepository struct {
+	db *DB
+}
+
+func NewCalendarFeedRepo(db *DB) *calendarFeedRepository {
+	return &calendarFeedRepository{db: db}
+}
+
+func (r *calendarFeedRepository) GetCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error) {
+	var feed model.CalendarFeed
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id, token, created_at FROM calendar_feeds WHERE user_id = ?",
+		userID).Scan(&feed.UserID, &feed.Token, &feed.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("calendar feed %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting calendar feed: %v", err)
+	}
+	return &feed, nil
+}
+
+func (r *calendarFeedRepository) GetCalendarFeedByToken(ctx context.Context, token string) (*model.CalendarFeed, error) {
+	var feed model.CalendarFeed
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id, token, created_at FROM calendar_feeds WHERE token = ?",
+		token).Scan(&feed.UserID, &feed.Token, &feed.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("calendar feed %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting calendar feed: %v", err)
+	}
+	return &feed, nil
+}
+
+// SaveCalendarFeed creates the user's feed or replaces its token
+func (r *calendarFeedRepository) SaveCalendarFeed(ctx context.Context, feed *model.CalendarFeed) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO calendar_feeds (user_id, token, created_at)
+		VALUES (?, ?, ?)
+		`+r.db.upsert([]string{"user_id"}, "token", "created_at"),
+		feed.UserID, feed.Token, feed.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving calendar feed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,490 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yimango/beatpace-backend/db"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+)
+
+// The contract suite runs the same checks against every storage backend, so the
+// repositories behave alike whichever DB_DRIVER is configured. SQLite always runs;
+// MySQL and PostgreSQL run when TEST_MYSQL_DSN and TEST_POSTGRES_DSN point at
+// disposable databases, which the suite migrates up and rolls back down again.
+
+func TestContractSQLite(t *testing.T) {
+	sqlDB, err := db.OpenSQLite(filepath.Join(t.TempDir(), "contract.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	runContract(t, sqlDB, db.DriverSQLite)
+}
+
+func TestContractMySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	sqlDB, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	runContract(t, sqlDB, db.DriverMySQL)
+}
+
+func TestContractPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	sqlDB, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	runContract(t, sqlDB, db.DriverPostgres)
+}
+
+// repos is every repository built on one database
+type repos struct {
+	users     repository.UserRepository
+	tokens    repository.TokenRepository
+	templates repository.PlaylistTemplateRepository
+	plans     repository.TrainingPlanRepository
+	zones     repository.TrainingZoneRepository
+	programs  repository.CadenceProgramRepository
+	playlists repository.PlaylistRepository
+	feedback  repository.TrackFeedbackRepository
+	prefs     repository.UserPreferencesRepository
+	accounts  repository.AccountRepository
+}
+
+func runContract(t *testing.T, sqlDB *sql.DB, driver string) {
+	ctx := context.Background()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := db.NewMigrator(sqlDB, driver)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := migrator.Down(ctx, 1<<20); err != nil {
+			t.Errorf("migrate down: %v", err)
+		}
+	})
+
+	store, err := repository.NewDB(sqlDB, driver)
+	if err != nil {
+		t.Fatalf("new db: %v", err)
+	}
+	r := repos{
+		users:     repository.NewUserRepo(store),
+		tokens:    repository.NewTokenRepo(store),
+		templates: repository.NewPlaylistTemplateRepo(store),
+		plans:     repository.NewTrainingPlanRepo(store),
+		zones:     repository.NewTrainingZoneRepo(store),
+		programs:  repository.NewCadenceProgramRepo(store),
+		playlists: repository.NewPlaylistRepo(store),
+		feedback:  repository.NewTrackFeedbackRepo(store),
+		prefs:     repository.NewUserPreferencesRepo(store),
+		accounts:  repository.NewAccountRepo(store),
+	}
+
+	t.Run("Migrations", func(t *testing.T) { testMigrations(t, migrator) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, r) })
+	t.Run("Tokens", func(t *testing.T) { testTokens(t, r) })
+	t.Run("Upserts", func(t *testing.T) { testUpserts(t, r) })
+	t.Run("TrackFeedback", func(t *testing.T) { testTrackFeedback(t, r) })
+	t.Run("Playlists", func(t *testing.T) { testPlaylists(t, r) })
+	t.Run("TrainingSessions", func(t *testing.T) { testTrainingSessions(t, r) })
+	t.Run("DeleteAccount", func(t *testing.T) { testDeleteAccount(t, r) })
+}
+
+// newUser stores a user with unique identifiers
+func newUser(t *testing.T, r repos) *model.User {
+	t.Helper()
+	user := &model.User{ID: uuid.New(), SpotifyUserID: "spotify-" + uuid.NewString()}
+	if err := r.users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// sameTime compares times at the second, the precision every backend keeps
+func sameTime(a, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+func testMigrations(t *testing.T, migrator *db.Migrator) {
+	ctx := context.Background()
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.Modified || status.Unknown {
+			t.Errorf("migration %d: got %+v, want applied and unchanged", status.Version, status)
+		}
+	}
+
+	// Rolling the newest migration back and forward again must be clean
+	if rolledBack, err := migrator.Down(ctx, 1); err != nil || len(rolledBack) != 1 {
+		t.Fatalf("down: rolled back %d, err %v", len(rolledBack), err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 1 {
+		t.Fatalf("up: applied %d, err %v", len(applied), err)
+	}
+}
+
+func testUsers(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+
+	got, err := r.users.GetUser(ctx, user.ID.String())
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.SpotifyUserID != user.SpotifyUserID || got.Email != nil {
+		t.Errorf("get user: got %+v", got)
+	}
+
+	email := uuid.NewString() + "@example.com"
+	user.Email = &email
+	if err := r.users.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	got, err = r.users.GetUserBySpotifyID(ctx, user.SpotifyUserID)
+	if err != nil {
+		t.Fatalf("get user by spotify id: %v", err)
+	}
+	if got.ID != user.ID || got.Email == nil || *got.Email != email {
+		t.Errorf("get user by spotify id: got %+v", got)
+	}
+
+	if _, err := r.users.GetUser(ctx, uuid.NewString()); err == nil {
+		t.Error("get missing user: want error")
+	}
+}
+
+func testTokens(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+	now := time.Now()
+
+	session := &model.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Token:     uuid.NewString(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := r.tokens.SaveSession(ctx, session); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	got, err := r.tokens.GetSessionByToken(ctx, session.Token)
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if got.ID != session.ID || got.UserID != user.ID || !sameTime(got.ExpiresAt, session.ExpiresAt) {
+		t.Errorf("get session: got %+v, want %+v", got, session)
+	}
+	if err := r.tokens.DeleteSession(ctx, session.Token); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if _, err := r.tokens.GetSessionByToken(ctx, session.Token); err == nil {
+		t.Error("get deleted session: want error")
+	}
+
+	if _, err := r.tokens.GetSpotifyToken(ctx, user.ID.String()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("get missing spotify token: got %v, want ErrNotFound", err)
+	}
+	token := &model.SpotifyToken{
+		InternalUserID: user.ID.String(),
+		SpotifyUserID:  user.SpotifyUserID,
+		AccessToken:    "access-1",
+		RefreshToken:   "refresh-1",
+		GeneratedAt:    now,
+		ExpiresAt:      now.Add(time.Hour),
+	}
+	if err := r.tokens.SaveSpotifyToken(ctx, token); err != nil {
+		t.Fatalf("save spotify token: %v", err)
+	}
+	token.AccessToken = "access-2"
+	token.ExpiresAt = now.Add(2 * time.Hour)
+	if err := r.tokens.SaveSpotifyToken(ctx, token); err != nil {
+		t.Fatalf("replace spotify token: %v", err)
+	}
+	gotToken, err := r.tokens.GetSpotifyToken(ctx, user.ID.String())
+	if err != nil {
+		t.Fatalf("get spotify token: %v", err)
+	}
+	if gotToken.AccessToken != "access-2" || !sameTime(gotToken.ExpiresAt, token.ExpiresAt) {
+		t.Errorf("get spotify token: got %+v, want %+v", gotToken, token)
+	}
+}
+
+func testUpserts(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+	userID := user.ID.String()
+
+	for _, name := range []string{"first {{.Pace}}", "second {{.Pace}}"} {
+		err := r.templates.SavePlaylistTemplate(ctx, &model.PlaylistTemplate{
+			UserID:       user.ID,
+			NameTemplate: name,
+			UpdatedAt:    time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("save template: %v", err)
+		}
+	}
+	template, err := r.templates.GetPlaylistTemplate(ctx, userID)
+	if err != nil || template.NameTemplate != "second {{.Pace}}" {
+		t.Errorf("get template: got %+v, %v", template, err)
+	}
+
+	zones := &model.TrainingZones{UserID: user.ID, Method: "riegel", RaceDistanceMeters: 5000, RaceTimeSeconds: 1500,
+		EasySeconds: 360, UpdatedAt: time.Now()}
+	if err := r.zones.SaveTrainingZones(ctx, zones); err != nil {
+		t.Fatalf("save zones: %v", err)
+	}
+	zones.EasySeconds = 350
+	if err := r.zones.SaveTrainingZones(ctx, zones); err != nil {
+		t.Fatalf("replace zones: %v", err)
+	}
+	gotZones, err := r.zones.GetTrainingZones(ctx, userID)
+	if err != nil || gotZones.EasySeconds != 350 || gotZones.VDOT != nil {
+		t.Errorf("get zones: got %+v, %v", gotZones, err)
+	}
+
+	height := 180.0
+	prefs := &model.UserPreferences{UserID: user.ID, Height: &height, HeightUnit: "cm", PaceUnit: "km",
+		TempoTolerance: 10, ExplicitContent: "allow", Genres: `["rock"]`, UpdatedAt: time.Now()}
+	if err := r.prefs.SavePreferences(ctx, prefs); err != nil {
+		t.Fatalf("save preferences: %v", err)
+	}
+	prefs.PlaylistPublic = true
+	prefs.Genres = `["rock","pop"]`
+	if err := r.prefs.SavePreferences(ctx, prefs); err != nil {
+		t.Fatalf("replace preferences: %v", err)
+	}
+	gotPrefs, err := r.prefs.GetPreferences(ctx, userID)
+	if err != nil || !gotPrefs.PlaylistPublic || gotPrefs.Genres != `["rock","pop"]` || gotPrefs.Height == nil {
+		t.Errorf("get preferences: got %+v, %v", gotPrefs, err)
+	}
+
+	program := &model.CadenceProgram{UserID: user.ID, StartCadence: 160, TargetCadence: 170, StepSPM: 5,
+		StartedAt: time.Now(), StageStartedAt: time.Now(), UpdatedAt: time.Now()}
+	event := &model.CadenceProgramEvent{ID: uuid.New(), UserID: user.ID, Action: model.CadenceProgramStarted,
+		Cadence: 160, CreatedAt: time.Now()}
+	if err := r.programs.SaveCadenceProgram(ctx, program, event); err != nil {
+		t.Fatalf("save cadence program: %v", err)
+	}
+	program.CurrentStage = 1
+	event = &model.CadenceProgramEvent{ID: uuid.New(), UserID: user.ID, Action: model.CadenceProgramAdvanced,
+		Stage: 1, Cadence: 165, CreatedAt: time.Now().Add(time.Second)}
+	if err := r.programs.SaveCadenceProgram(ctx, program, event); err != nil {
+		t.Fatalf("advance cadence program: %v", err)
+	}
+	gotProgram, err := r.programs.GetCadenceProgram(ctx, userID)
+	if err != nil || gotProgram.CurrentStage != 1 {
+		t.Errorf("get cadence program: got %+v, %v", gotProgram, err)
+	}
+	events, err := r.programs.ListCadenceProgramEvents(ctx, userID, 10)
+	if err != nil || len(events) != 2 || events[0].Action != model.CadenceProgramAdvanced {
+		t.Errorf("list cadence events: got %+v, %v", events, err)
+	}
+}
+
+func testTrackFeedback(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+	userID := user.ID.String()
+
+	for _, kind := range []string{model.TrackFeedbackUp, model.TrackFeedbackWrongTempo, model.TrackFeedbackWrongTempo,
+		model.TrackFeedbackDown, model.TrackFeedbackSkipped} {
+		if err := r.feedback.AddTrackFeedback(ctx, userID, "track-1", kind, time.Now()); err != nil {
+			t.Fatalf("add %s feedback: %v", kind, err)
+		}
+	}
+	got, err := r.feedback.GetTrackFeedback(ctx, userID, "track-1")
+	if err != nil {
+		t.Fatalf("get feedback: %v", err)
+	}
+	if got.Rating != -1 || got.WrongTempo != 2 || got.Skipped != 1 || got.TooFast != 0 {
+		t.Errorf("get feedback: got %+v", got)
+	}
+	if _, err := r.feedback.GetTrackFeedback(ctx, userID, "track-2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("get missing feedback: got %v, want ErrNotFound", err)
+	}
+}
+
+func testPlaylists(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+	other := newUser(t, r)
+	userID := user.ID.String()
+
+	// Two playlists share a timestamp so the cursor has to fall back to the ID
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	created := []time.Time{base, base.Add(time.Minute), base.Add(time.Minute)}
+	var ids []uuid.UUID
+	for i, at := range created {
+		pace := 300 + i
+		playlist := &model.Playlist{ID: uuid.New(), UserID: user.ID, Kind: "pace", SpotifyPlaylistID: "sp-" + uuid.NewString(),
+			Name: "Run", PaceSeconds: &pace, PaceUnit: "km", Activity: "running", TargetBPM: 160 + i,
+			Filters: "{}", Inputs: "{}", TrackCount: 1, DurationMs: 200000, CreatedAt: at}
+		tracks := []model.PlaylistTrack{{PlaylistID: playlist.ID, SpotifyTrackID: "track-1", Name: "Song",
+			Artists: `["Band"]`, BPM: 160.5, TempoMeasured: true, DurationMs: 200000}}
+		effects := []model.PlaylistFeedbackEffect{{PlaylistID: playlist.ID, SpotifyTrackID: "track-1", Name: "Song",
+			Score: 2, TempoTrust: 1, Included: true}}
+		if err := r.playlists.CreatePlaylist(ctx, playlist, tracks, effects); err != nil {
+			t.Fatalf("create playlist: %v", err)
+		}
+		ids = append(ids, playlist.ID)
+	}
+
+	got, tracks, err := r.playlists.GetPlaylist(ctx, userID, ids[0].String())
+	if err != nil {
+		t.Fatalf("get playlist: %v", err)
+	}
+	if got.PaceSeconds == nil || *got.PaceSeconds != 300 || got.Cadence != nil || !got.CreatedAt.Equal(base) {
+		t.Errorf("get playlist: got %+v", got)
+	}
+	if len(tracks) != 1 || tracks[0].BPM != 160.5 || !tracks[0].TempoMeasured {
+		t.Errorf("get playlist tracks: got %+v", tracks)
+	}
+	if _, _, err := r.playlists.GetPlaylist(ctx, other.ID.String(), ids[0].String()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("get another user's playlist: got %v, want ErrNotFound", err)
+	}
+
+	// Page through one at a time, newest first
+	var seen []uuid.UUID
+	filter := repository.PlaylistListFilter{Limit: 1}
+	for {
+		page, err := r.playlists.ListPlaylists(ctx, userID, filter)
+		if err != nil {
+			t.Fatalf("list playlists: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		seen = append(seen, page[0].ID)
+		filter.AfterCreatedAt = page[0].CreatedAt
+		filter.AfterID = &page[0].ID
+	}
+	if len(seen) != 3 || seen[2] != ids[0] {
+		t.Errorf("list playlists: got %v, want all three ending with %v", seen, ids[0])
+	}
+
+	minBPM := 161
+	filtered, err := r.playlists.ListPlaylists(ctx, userID, repository.PlaylistListFilter{MinBPM: &minBPM, Limit: 10})
+	if err != nil || len(filtered) != 2 {
+		t.Errorf("list playlists from 161 bpm: got %d, %v", len(filtered), err)
+	}
+
+	effects, err := r.playlists.ListFeedbackEffects(ctx, ids)
+	if err != nil || len(effects) != 3 || !effects[0].Included || effects[0].Excluded {
+		t.Errorf("list feedback effects: got %+v, %v", effects, err)
+	}
+}
+
+func testTrainingSessions(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+	now := time.Now()
+
+	plan := &model.TrainingPlan{ID: uuid.New(), UserID: user.ID, Name: "Base", LeadHours: 12, Settings: "{}", CreatedAt: now}
+	var sessions []model.TrainingSession
+	for i, generateAt := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		sessions = append(sessions, model.TrainingSession{ID: uuid.New(), PlanID: plan.ID, UserID: user.ID,
+			WorkoutID: uuid.New(), Title: "Session", ScheduledAt: generateAt.Add(12 * time.Hour), GenerateAt: generateAt,
+			Status: model.SessionScheduled, UpdatedAt: now.Add(time.Duration(i) * time.Second)})
+	}
+	if err := r.plans.CreatePlan(ctx, plan, sessions); err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+
+	claimed, err := r.plans.ClaimDueSessions(ctx, now, now.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("claim sessions: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != sessions[0].ID || claimed[0].Status != model.SessionGenerating {
+		t.Fatalf("claim sessions: got %+v, want only the due session", claimed)
+	}
+	if again, err := r.plans.ClaimDueSessions(ctx, now, now.Add(-time.Hour), 10); err != nil || len(again) != 0 {
+		t.Errorf("claim sessions again: got %d, %v, want none", len(again), err)
+	}
+
+	url := "https://open.spotify.com/playlist/x"
+	session := claimed[0]
+	session.Status = model.SessionReady
+	session.PlaylistURL = &url
+	session.GeneratedAt = &now
+	if err := r.plans.UpdateSession(ctx, &session); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	stored, err := r.plans.ListPlanSessions(ctx, plan.ID.String())
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("list plan sessions: got %d, %v", len(stored), err)
+	}
+	for _, s := range stored {
+		if s.ID == session.ID && (s.Status != model.SessionReady || s.PlaylistURL == nil || s.Attempts != 1) {
+			t.Errorf("updated session: got %+v", s)
+		}
+	}
+}
+
+func testDeleteAccount(t *testing.T, r repos) {
+	ctx := context.Background()
+	user := newUser(t, r)
+	kept := newUser(t, r)
+	userID := user.ID.String()
+
+	for _, owner := range []*model.User{user, kept} {
+		if err := r.feedback.AddTrackFeedback(ctx, owner.ID.String(), "track-1", model.TrackFeedbackUp, time.Now()); err != nil {
+			t.Fatalf("add feedback: %v", err)
+		}
+		session := &model.Session{ID: uuid.New(), UserID: owner.ID, Token: uuid.NewString(), CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour)}
+		if err := r.tokens.SaveSession(ctx, session); err != nil {
+			t.Fatalf("save session: %v", err)
+		}
+	}
+	if sessions, err := r.accounts.ListSessions(ctx, userID); err != nil || len(sessions) != 1 {
+		t.Fatalf("list sessions: got %d, %v", len(sessions), err)
+	}
+
+	audit := &model.AccountDeletion{ID: uuid.New(), UserID: user.ID, DeletedAt: time.Now()}
+	if err := r.accounts.DeleteAccount(ctx, userID, audit); err != nil {
+		t.Fatalf("delete account: %v", err)
+	}
+	if audit.RemovedRows == "" {
+		t.Error("delete account: removed rows not recorded")
+	}
+	if _, err := r.users.GetUser(ctx, userID); err == nil {
+		t.Error("get deleted user: want error")
+	}
+	if feedback, err := r.feedback.ListTrackFeedback(ctx, userID); err != nil || len(feedback) != 0 {
+		t.Errorf("deleted user's feedback: got %d, %v", len(feedback), err)
+	}
+	if feedback, err := r.feedback.ListTrackFeedback(ctx, kept.ID.String()); err != nil || len(feedback) != 1 {
+		t.Errorf("other user's feedback: got %d, %v", len(feedback), err)
+	}
+
+	audit.ID = uuid.New()
+	if err := r.accounts.DeleteAccount(ctx, userID, audit); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("delete deleted account: got %v, want ErrNotFound", err)
+	}
+}
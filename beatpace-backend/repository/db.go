@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yimango/beatpace-backend/db"
+)
+
+// DB is the database handle shared by the repositories. It knows the SQL dialect of
+// its driver, so the few statements that differ between databases can be written
+// once, and it passes arguments in the form the driver stores consistently. Queries
+// use ? placeholders whatever the driver.
+type DB struct {
+	*sql.DB
+	dialect dialect
+}
+
+// Tx is a transaction started from a DB
+type Tx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+// NewDB wraps a connection opened for one of the db.Driver* drivers
+func NewDB(sqlDB *sql.DB, driver string) (*DB, error) {
+	switch driver {
+	case db.DriverMySQL:
+		return &DB{DB: sqlDB, dialect: mysqlDialect{}}, nil
+	case db.DriverSQLite:
+		return &DB{DB: sqlDB, dialect: sqliteDialect{}}, nil
+	case db.DriverPostgres:
+		return &DB{DB: sqlDB, dialect: postgresDialect{}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.DB.ExecContext(ctx, d.dialect.rebind(query), d.dialect.args(args)...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.DB.QueryContext(ctx, d.dialect.rebind(query), d.dialect.args(args)...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.DB.QueryRowContext(ctx, d.dialect.rebind(query), d.dialect.args(args)...)
+}
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: d.dialect}, nil
+}
+
+// upsert returns the clause that follows INSERT ... VALUES (...) to update the row
+// already stored under the key columns instead. Each update is either a column, set
+// to the inserted value, or a "column = expression" assignment over the stored row.
+func (d *DB) upsert(key []string, updates ...string) string {
+	return d.dialect.upsert(key, updates)
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.dialect.rebind(query), t.dialect.args(args)...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.dialect.rebind(query), t.dialect.args(args)...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, t.dialect.rebind(query), t.dialect.args(args)...)
+}
+
+// dialect covers what differs between the supported databases
+type dialect interface {
+	upsert(key []string, updates []string) string
+	rebind(query string) string
+	args(args []any) []any
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) upsert(key []string, updates []string) string {
+	assignments := make([]string, 0, len(updates))
+	for _, update := range updates {
+		if strings.Contains(update, "=") {
+			assignments = append(assignments, update)
+			continue
+		}
+		assignments = append(assignments, update+" = VALUES("+update+")")
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+func (mysqlDialect) rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) args(args []any) []any {
+	return args
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) upsert(key []string, updates []string) string {
+	return onConflictUpdate(key, updates)
+}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+// args stores times in UTC. SQLite keeps times as text, so comparisons and ordering
+// only work when every value has the same offset.
+func (sqliteDialect) args(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			converted[i] = value.UTC()
+		case *time.Time:
+			if value != nil {
+				converted[i] = value.UTC()
+			} else {
+				converted[i] = value
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) upsert(key []string, updates []string) string {
+	return onConflictUpdate(key, updates)
+}
+
+func (postgresDialect) rebind(query string) string {
+	return db.Rebind(db.DriverPostgres, query)
+}
+
+// args passes values unchanged; timestamptz columns keep the instant whatever the
+// offset
+func (postgresDialect) args(args []any) []any {
+	return args
+}
+
+// onConflictUpdate is the upsert clause shared by SQLite and PostgreSQL, which name
+// the conflicting row's new values "excluded"
+func onConflictUpdate(key []string, updates []string) string {
+	assignments := make([]string, 0, len(updates))
+	for _, update := range updates {
+		if strings.Contains(update, "=") {
+			assignments = append(assignments, update)
+			continue
+		}
+		assignments = append(assignments, update+" = excluded."+update)
+	}
+	return "ON CONFLICT (" + strings.Join(key, ", ") + ") DO UPDATE SET " + strings.Join(assignments, ", ")
+}
@@ -2,10 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/yimango/beatpace-backend/model"
 )
 
+// ErrNotFound is returned (wrapped) when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
 // UserRepository handles user data storage operations
 type UserRepository interface {
 	GetUser(ctx context.Context, id string) (*model.User, error)
@@ -21,4 +27,82 @@ type TokenRepository interface {
 	DeleteSession(ctx context.Context, token string) error
 	SaveSpotifyToken(ctx context.Context, token *model.SpotifyToken) error
 	GetSpotifyToken(ctx context.Context, userID string) (*model.SpotifyToken, error)
-} 
\ No newline at end of file
+}
+
+// PlaylistTemplateRepository handles storage of per-user playlist naming templates
+type PlaylistTemplateRepository interface {
+	GetPlaylistTemplate(ctx context.Context, userID string) (*model.PlaylistTemplate, error)
+	SavePlaylistTemplate(ctx context.Context, template *model.PlaylistTemplate) error
+}
+
+// WorkoutRepository handles storage of saved workout templates. Lookups are scoped
+// to the owning user.
+type WorkoutRepository interface {
+	CreateWorkout(ctx context.Context, workout *model.Workout) error
+	GetWorkout(ctx context.Context, userID string, id string) (*model.Workout, error)
+	ListWorkouts(ctx context.Context, userID string) ([]model.Workout, error)
+	UpdateWorkout(ctx context.Context, workout *model.Workout) error
+	DeleteWorkout(ctx context.Context, userID string, id string) error
+}
+
+// TrainingPlanRepository handles storage of training plans and their sessions
+type TrainingPlanRepository interface {
+	CreatePlan(ctx context.Context, plan *model.TrainingPlan, sessions []model.TrainingSession) error
+	GetPlan(ctx context.Context, userID string, id string) (*model.TrainingPlan, error)
+	ListPlans(ctx context.Context, userID string) ([]model.TrainingPlan, error)
+	DeletePlan(ctx context.Context, userID string, id string) error
+	ListPlanSessions(ctx context.Context, planID string) ([]model.TrainingSession, error)
+	ListUserSessions(ctx context.Context, userID string, since time.Time) ([]model.TrainingSession, error)
+	ClaimDueSessions(ctx context.Context, now time.Time, staleBefore time.Time, limit int) ([]model.TrainingSession, error)
+	UpdateSession(ctx context.Context, session *model.TrainingSession) error
+}
+
+// CalendarFeedRepository handles storage of per-user calendar feed tokens
+type CalendarFeedRepository interface {
+	GetCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error)
+	GetCalendarFeedByToken(ctx context.Context, token string) (*model.CalendarFeed, error)
+	SaveCalendarFeed(ctx context.Context, feed *model.CalendarFeed) error
+}
+
+// TrainingZoneRepository handles storage of per-user training zones
+type TrainingZoneRepository interface {
+	GetTrainingZones(ctx context.Context, userID string) (*model.TrainingZones, error)
+	SaveTrainingZones(ctx context.Context, zones *model.TrainingZones) error
+}
+
+// CadenceProgramRepository handles storage of per-user cadence programs and their
+// history
+type CadenceProgramRepository interface {
+	GetCadenceProgram(ctx context.Context, userID string) (*model.CadenceProgram, error)
+	SaveCadenceProgram(ctx context.Context, program *model.CadenceProgram, event *model.CadenceProgramEvent) error
+	DeleteCadenceProgram(ctx context.Context, userID string, event *model.CadenceProgramEvent) error
+	ListCadenceProgramEvents(ctx context.Context, userID string, limit int) ([]model.CadenceProgramEvent, error)
+}
+
+// PlaylistRepository handles storage of generated playlists and their tracks. Lookups
+// are scoped to the owning user.
+type PlaylistRepository interface {
+	CreatePlaylist(ctx context.Context, playlist *model.Playlist, tracks []model.PlaylistTrack, effects []model.PlaylistFeedbackEffect) error
+	GetPlaylist(ctx context.Context, userID string, id string) (*model.Playlist, []model.PlaylistTrack, error)
+	ListPlaylists(ctx context.Context, userID string, filter PlaylistListFilter) ([]model.Playlist, error)
+	ListFeedbackEffects(ctx context.Context, playlistIDs []uuid.UUID) ([]model.PlaylistFeedbackEffect, error)
+}
+
+// TrackFeedbackRepository handles storage of per-user track feedback
+type TrackFeedbackRepository interface {
+	AddTrackFeedback(ctx context.Context, userID string, trackID string, kind string, at time.Time) error
+	GetTrackFeedback(ctx context.Context, userID string, trackID string) (*model.TrackFeedback, error)
+	ListTrackFeedback(ctx context.Context, userID string) ([]model.TrackFeedback, error)
+}
+
+// UserPreferencesRepository handles storage of per-user profile and generation defaults
+type UserPreferencesRepository interface {
+	GetPreferences(ctx context.Context, userID string) (*model.UserPreferences, error)
+	SavePreferences(ctx context.Context, prefs *model.UserPreferences) error
+}
+
+// AccountRepository handles whole-account operations that span every user table
+type AccountRepository interface {
+	DeleteAccount(ctx context.Context, userID string, audit *model.AccountDeletion) error
+	ListSessions(ctx context.Context, userID string) ([]model.Session, error)
+}
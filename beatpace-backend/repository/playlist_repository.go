@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+)
+
+const playlistColumns = `id, user_id, kind, spotify_playlist_id, url, name, pace_seconds, pace_unit, activity,
+	cadence, target_bpm, filters, inputs, track_count, duration_ms, created_at`
+
+// PlaylistListFilter narrows and pages a user's playlist history. Results are ordered
+// newest first; when AfterID is set only playlists strictly after the (AfterCreatedAt,
+// AfterID) position in that order are returned.
+type PlaylistListFilter struct {
+	AfterCreatedAt time.Time
+	AfterID        *uuid.UUID
+	From           *time.Time // Created at or after
+	To             *time.Time // Created before
+	MinBPM         *int
+	MaxBPM         *int
+	Limit          int
+}
+
+type playlistRepository struct {
+	db *DB
+}
+
+func NewPlaylistRepo(db *DB) *playlistRepository {
+	return &playlistRepository{db: db}
+}
+
+// CreatePlaylist stores a playlist, its tracks and the effects of the user's feedback
+// on it in one transaction
+func (r *playlistRepository) CreatePlaylist(ctx context.Context, playlist *model.Playlist, tracks []model.PlaylistTrack, effects []model.PlaylistFeedbackEffect) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO playlists (`+playlistColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		playlist.ID, playlist.UserID, playlist.Kind, playlist.SpotifyPlaylistID, playlist.URL, playlist.Name,
+		playlist.PaceSeconds, playlist.PaceUnit, playlist.Activity, playlist.Cadence, playlist.TargetBPM,
+		playlist.Filters, playlist.Inputs, playlist.TrackCount, playlist.DurationMs, playlist.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	for _, track := range tracks {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO playlist_tracks (playlist_id, position, spotify_track_id, name, artists, bpm,
+				tempo_measured, duration_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			track.PlaylistID, track.Position, track.SpotifyTrackID, track.Name, track.Artists, track.BPM,
+			track.TempoMeasured, track.DurationMs)
+		if err != nil {
+			return fmt.Errorf("error creating playlist track: %v", err)
+		}
+	}
+
+	for _, effect := range effects {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO playlist_feedback_effects (playlist_id, spotify_track_id, name, score, tempo_trust,
+				included, excluded)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			effect.PlaylistID, effect.SpotifyTrackID, effect.Name, effect.Score, effect.TempoTrust,
+			effect.Included, effect.Excluded)
+		if err != nil {
+			return fmt.Errorf("error creating playlist feedback effect: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing playlist: %v", err)
+	}
+	return nil
+}
+
+// GetPlaylist returns one of the user's playlists with its tracks in order
+func (r *playlistRepository) GetPlaylist(ctx context.Context, userID string, id string) (*model.Playlist, []model.PlaylistTrack, error) {
+	playlist, err := scanPlaylist(r.db.QueryRowContext(ctx,
+		`SELECT `+playlistColumns+` FROM playlists WHERE id = ? AND user_id = ?`,
+		id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("playlist %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting playlist: %v", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT playlist_id, position, spotify_track_id, name, artists, bpm, tempo_measured, duration_ms
+		FROM playlist_tracks WHERE playlist_id = ? ORDER BY position`,
+		id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing playlist tracks: %v", err)
+	}
+	defer rows.Close()
+
+	var tracks []model.PlaylistTrack
+	for rows.Next() {
+		var track model.PlaylistTrack
+		if err := rows.Scan(&track.PlaylistID, &track.Position, &track.SpotifyTrackID, &track.Name,
+			&track.Artists, &track.BPM, &track.TempoMeasured, &track.DurationMs); err != nil {
+			return nil, nil, fmt.Errorf("error scanning playlist track: %v", err)
+		}
+		tracks = append(tracks, track)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error listing playlist tracks: %v", err)
+	}
+	return playlist, tracks, nil
+}
+
+// ListPlaylists returns up to filter.Limit of the user's playlists, newest first
+func (r *playlistRepository) ListPlaylists(ctx context.Context, userID string, filter PlaylistListFilter) ([]model.Playlist, error) {
+	conditions := []string{"user_id = ?"}
+	args := []any{userID}
+	if filter.AfterID != nil {
+		conditions = append(conditions, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, filter.AfterCreatedAt, filter.AfterCreatedAt, *filter.AfterID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.To)
+	}
+	if filter.MinBPM != nil {
+		conditions = append(conditions, "target_bpm >= ?")
+		args = append(args, *filter.MinBPM)
+	}
+	if filter.MaxBPM != nil {
+		conditions = append(conditions, "target_bpm <= ?")
+		args = append(args, *filter.MaxBPM)
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+playlistColumns+` FROM playlists
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY created_at DESC, id DESC LIMIT ?`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing playlists: %v", err)
+	}
+	defer rows.Close()
+
+	var playlists []model.Playlist
+	for rows.Next() {
+		playlist, err := scanPlaylist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning playlist: %v", err)
+		}
+		playlists = append(playlists, *playlist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing playlists: %v", err)
+	}
+	return playlists, nil
+}
+
+// ListFeedbackEffects returns the recorded feedback effects of the given playlists
+func (r *playlistRepository) ListFeedbackEffects(ctx context.Context, playlistIDs []uuid.UUID) ([]model.PlaylistFeedbackEffect, error) {
+	if len(playlistIDs) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(playlistIDs)), ", ")
+	args := make([]any, len(playlistIDs))
+	for i, id := range playlistIDs {
+		args[i] = id
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT playlist_id, spotify_track_id, name, score, tempo_trust, included, excluded
+		FROM playlist_feedback_effects WHERE playlist_id IN (`+placeholders+`)
+		ORDER BY score DESC, spotify_track_id`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing playlist feedback effects: %v", err)
+	}
+	defer rows.Close()
+
+	var effects []model.PlaylistFeedbackEffect
+	for rows.Next() {
+		var effect model.PlaylistFeedbackEffect
+		if err := rows.Scan(&effect.PlaylistID, &effect.SpotifyTrackID, &effect.Name, &effect.Score,
+			&effect.TempoTrust, &effect.Included, &effect.Excluded); err != nil {
+			return nil, fmt.Errorf("error scanning playlist feedback effect: %v", err)
+		}
+		effects = append(effects, effect)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing playlist feedback effects: %v", err)
+	}
+	return effects, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPlaylist(row rowScanner) (*model.Playlist, error) {
+	var playlist model.Playlist
+	err := row.Scan(&playlist.ID, &playlist.UserID, &playlist.Kind, &playlist.SpotifyPlaylistID, &playlist.URL,
+		&playlist.Name, &playlist.PaceSeconds, &playlist.PaceUnit, &playlist.Activity, &playlist.Cadence,
+		&playlist.TargetBPM, &playlist.Filters, &playlist.Inputs, &playlist.TrackCount, &playlist.DurationMs,
+		&playlist.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
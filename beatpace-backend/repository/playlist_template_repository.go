@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type playlistTemplateRepository struct {
+	db *DB
+}
+
+func NewPlaylistTemplateRepo(db *DB) *playlistTemplateRepository {
+	return &playlistTemplateRepository{db: db}
+}
+
+func (r *playlistTemplateRepository) GetPlaylistTemplate(ctx context.Context, userID string) (*model.PlaylistTemplate, error) {
+	var template model.PlaylistTemplate
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id, name_template, description_template, updated_at FROM playlist_templates WHERE user_id = ?",
+		userID).Scan(&template.UserID, &template.NameTemplate, &template.DescriptionTemplate, &template.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("playlist template %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlist template: %v", err)
+	}
+	return &template, nil
+}
+
+func (r *playlistTemplateRepository) SavePlaylistTemplate(ctx context.Context, template *model.PlaylistTemplate) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO playlist_templates (user_id, name_template, description_template, updated_at)
+		VALUES (?, ?, ?, ?)
+		`+r.db.upsert([]string{"user_id"}, "name_template", "description_template", "updated_at"),
+		template.UserID, template.NameTemplate, template.DescriptionTemplate, template.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving playlist template: %v", err)
+	}
+	return nil
+}
@@ -2,31 +2,25 @@ package repository
 
 import (
   "context"
-  "database/sql"
 
   "github.com/yimango/beatpace-backend/model"
 )
 
 type SpotifyTokenRepo struct {
-  db *sql.DB
+  db *DB
 }
 
-func NewSpotifyTokenRepo(db *sql.DB) *SpotifyTokenRepo {
+func NewSpotifyTokenRepo(db *DB) *SpotifyTokenRepo {
   return &SpotifyTokenRepo{db: db}
 }
 
 // Upsert inserts or updates the Spotify tokens for a given internal user ID.
 func (r *SpotifyTokenRepo) Upsert(ctx context.Context, t *model.SpotifyToken) error {
-  const q = `
+  q := `
     INSERT INTO spotify_tokens
       (internal_user_id, spotify_user_id, access_token, refresh_token, generated_at, expires_at)
     VALUES (?, ?, ?, ?, ?, ?)
-    ON DUPLICATE KEY UPDATE
-      access_token  = VALUES(access_token),
-      refresh_token = VALUES(refresh_token),
-      generated_at  = VALUES(generated_at),
-      expires_at    = VALUES(expires_at)
-  `
+    ` + r.db.upsert([]string{"internal_user_id"}, "access_token", "refresh_token", "generated_at", "expires_at")
   _, err := r.db.ExecContext(ctx, q,
     t.InternalUserID,
     t.SpotifyUserID,
@@ -9,10 +9,10 @@ import (
 )
 
 type tokenRepository struct {
-	db *sql.DB
+	db *DB
 }
 
-func NewTokenRepo(db *sql.DB) *tokenRepository {
+func NewTokenRepo(db *DB) *tokenRepository {
 	return &tokenRepository{db: db}
 }
 
@@ -56,11 +56,7 @@ func (r *tokenRepository) SaveSpotifyToken(ctx context.Context, token *model.Spo
 	_, err := r.db.ExecContext(ctx,
 		`INSERT INTO spotify_tokens (internal_user_id, spotify_user_id, access_token, refresh_token, generated_at, expires_at)
 		VALUES (?, ?, ?, ?, ?, ?)
-		ON DUPLICATE KEY UPDATE
-			access_token = VALUES(access_token),
-			refresh_token = VALUES(refresh_token),
-			generated_at = VALUES(generated_at),
-			expires_at = VALUES(expires_at)`,
+		`+r.db.upsert([]string{"internal_user_id"}, "access_token", "refresh_token", "generated_at", "expires_at"),
 		token.InternalUserID, token.SpotifyUserID, token.AccessToken, token.RefreshToken, token.GeneratedAt, token.ExpiresAt)
 	if err != nil {
 		return fmt.Errorf("error saving spotify token: %v", err)
@@ -74,7 +70,7 @@ func (r *tokenRepository) GetSpotifyToken(ctx context.Context, userID string) (*
 		"SELECT internal_user_id, spotify_user_id, access_token, refresh_token, generated_at, expires_at FROM spotify_tokens WHERE internal_user_id = ?",
 		userID).Scan(&token.InternalUserID, &token.SpotifyUserID, &token.AccessToken, &token.RefreshToken, &token.GeneratedAt, &token.ExpiresAt)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("spotify token not found")
+		return nil, fmt.Errorf("spotify token %w", ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error getting spotify token: %v", err)
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type trackFeedbackRepository struct {
+	db *DB
+}
+
+func NewTrackFeedbackRepo(db *DB) *trackFeedbackRepository {
+	return &trackFeedbackRepository{db: db}
+}
+
+// AddTrackFeedback records one piece of feedback: thumbs set the rating, the other
+// kinds increment their count
+func (r *trackFeedbackRepository) AddTrackFeedback(ctx context.Context, userID string, trackID string, kind string, at time.Time) error {
+	var rating, wrongTempo, skipped, tooSlow, tooFast int
+	// Counters name their table: PostgreSQL finds a bare column ambiguous between the
+	// stored row and the excluded one
+	var update string
+	switch kind {
+	case model.TrackFeedbackUp:
+		rating, update = 1, "rating"
+	case model.TrackFeedbackDown:
+		rating, update = -1, "rating"
+	case model.TrackFeedbackWrongTempo:
+		wrongTempo, update = 1, "wrong_tempo = track_feedback.wrong_tempo + 1"
+	case model.TrackFeedbackSkipped:
+		skipped, update = 1, "skipped = track_feedback.skipped + 1"
+	case model.TrackFeedbackTooSlow:
+		tooSlow, update = 1, "too_slow = track_feedback.too_slow + 1"
+	case model.TrackFeedbackTooFast:
+		tooFast, update = 1, "too_fast = track_feedback.too_fast + 1"
+	default:
+		return fmt.Errorf("unknown track feedback %q", kind)
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO track_feedback (user_id, spotify_track_id, rating, wrong_tempo, skipped, too_slow, too_fast, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`+r.db.upsert([]string{"user_id", "spotify_track_id"}, update, "updated_at"),
+		userID, trackID, rating, wrongTempo, skipped, tooSlow, tooFast, at)
+	if err != nil {
+		return fmt.Errorf("error saving track feedback: %v", err)
+	}
+	return nil
+}
+
+func (r *trackFeedbackRepository) GetTrackFeedback(ctx context.Context, userID string, trackID string) (*model.TrackFeedback, error) {
+	var feedback model.TrackFeedback
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, spotify_track_id, rating, wrong_tempo, skipped, too_slow, too_fast, updated_at
+		FROM track_feedback WHERE user_id = ? AND spotify_track_id = ?`,
+		userID, trackID).Scan(&feedback.UserID, &feedback.SpotifyTrackID, &feedback.Rating, &feedback.WrongTempo,
+		&feedback.Skipped, &feedback.TooSlow, &feedback.TooFast, &feedback.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("track feedback %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting track feedback: %v", err)
+	}
+	return &feedback, nil
+}
+
+// ListTrackFeedback returns all of the user's track feedback
+func (r *trackFeedbackRepository) ListTrackFeedback(ctx context.Context, userID string) ([]model.TrackFeedback, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT user_id, spotify_track_id, rating, wrong_tempo, skipped, too_slow, too_fast, updated_at
+		FROM track_feedback WHERE user_id = ?`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing track feedback: %v", err)
+	}
+	defer rows.Close()
+
+	var feedback []model.TrackFeedback
+	for rows.Next() {
+		var f model.TrackFeedback
+		if err := rows.Scan(&f.UserID, &f.SpotifyTrackID, &f.Rating, &f.WrongTempo, &f.Skipped, &f.TooSlow,
+			&f.TooFast, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning track feedback: %v", err)
+		}
+		feedback = append(feedback, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing track feedback: %v", err)
+	}
+	return feedback, nil
+}
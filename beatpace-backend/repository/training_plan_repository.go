@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+const trainingSessionColumns = `id, plan_id, user_id, workout_id, title, notes, scheduled_at, generate_at,
+	status, attempts, playlist_url, last_error, generated_at, updated_at`
+
+type trainingPlanRepository struct {
+	db *DB
+}
+
+func NewTrainingPlanRepo(db *DB) *trainingPlanRepository {
+	return &trainingPlanRepository{db: db}
+}
+
+// CreatePlan stores a plan and all of its sessions in one transaction
+func (r *trainingPlanRepository) CreatePlan(ctx context.Context, plan *model.TrainingPlan, sessions []model.TrainingSession) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO training_plans (id, user_id, name, lead_hours, settings, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		plan.ID, plan.UserID, plan.Name, plan.LeadHours, plan.Settings, plan.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating training plan: %v", err)
+	}
+
+	for _, session := range sessions {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO training_sessions (`+trainingSessionColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.ID, session.PlanID, session.UserID, session.WorkoutID, session.Title, session.Notes,
+			session.ScheduledAt, session.GenerateAt, session.Status, session.Attempts, session.PlaylistURL,
+			session.LastError, session.GeneratedAt, session.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("error creating training session: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing training plan: %v", err)
+	}
+	return nil
+}
+
+func (r *trainingPlanRepository) GetPlan(ctx context.Context, userID string, id string) (*model.TrainingPlan, error) {
+	var plan model.TrainingPlan
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, name, lead_hours, settings, created_at
+		FROM training_plans WHERE id = ? AND user_id = ?`,
+		id, userID).Scan(&plan.ID, &plan.UserID, &plan.Name, &plan.LeadHours, &plan.Settings, &plan.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("training plan %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting training plan: %v", err)
+	}
+	return &plan, nil
+}
+
+func (r *trainingPlanRepository) ListPlans(ctx context.Context, userID string) ([]model.TrainingPlan, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, name, lead_hours, settings, created_at
+		FROM training_plans WHERE user_id = ? ORDER BY created_at DESC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing training plans: %v", err)
+	}
+	defer rows.Close()
+
+	var plans []model.TrainingPlan
+	for rows.Next() {
+		var plan model.TrainingPlan
+		if err := rows.Scan(&plan.ID, &plan.UserID, &plan.Name, &plan.LeadHours, &plan.Settings, &plan.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning training plan: %v", err)
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing training plans: %v", err)
+	}
+	return plans, nil
+}
+
+// DeletePlan removes a plan; its sessions are removed by the foreign key cascade
+func (r *trainingPlanRepository) DeletePlan(ctx context.Context, userID string, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM training_plans WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting training plan: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("training plan %w", ErrNotFound)
+	}
+	return nil
+}
+
+func (r *trainingPlanRepository) ListPlanSessions(ctx context.Context, planID string) ([]model.TrainingSession, error) {
+	return r.querySessions(ctx,
+		`SELECT `+trainingSessionColumns+` FROM training_sessions WHERE plan_id = ? ORDER BY scheduled_at`,
+		planID)
+}
+
+// ListUserSessions returns the user's sessions across all plans scheduled at or after since
+func (r *trainingPlanRepository) ListUserSessions(ctx context.Context, userID string, since time.Time) ([]model.TrainingSession, error) {
+	return r.querySessions(ctx,
+		`SELECT `+trainingSessionColumns+` FROM training_sessions
+		WHERE user_id = ? AND scheduled_at >= ? ORDER BY scheduled_at`,
+		userID, since)
+}
+
+// ClaimDueSessions marks up to limit sessions whose generation time has come as
+// generating and returns them. Sessions left generating since before staleBefore are
+// assumed abandoned by a crashed worker and claimed again. Each claim is a conditional
+// update, so concurrent workers never claim the same session.
+func (r *trainingPlanRepository) ClaimDueSessions(ctx context.Context, now time.Time, staleBefore time.Time, limit int) ([]model.TrainingSession, error) {
+	candidates, err := r.querySessions(ctx,
+		`SELECT `+trainingSessionColumns+` FROM training_sessions
+		WHERE generate_at <= ? AND (status = ? OR (status = ? AND updated_at < ?))
+		ORDER BY generate_at LIMIT ?`,
+		now, model.SessionScheduled, model.SessionGenerating, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed []model.TrainingSession
+	for _, session := range candidates {
+		result, err := r.db.ExecContext(ctx,
+			`UPDATE training_sessions SET status = ?, attempts = attempts + 1, updated_at = ?
+			WHERE id = ? AND (status = ? OR (status = ? AND updated_at < ?))`,
+			model.SessionGenerating, now,
+			session.ID, model.SessionScheduled, model.SessionGenerating, staleBefore)
+		if err != nil {
+			return nil, fmt.Errorf("error claiming training session: %v", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		session.Status = model.SessionGenerating
+		session.Attempts++
+		session.UpdatedAt = now
+		claimed = append(claimed, session)
+	}
+	return claimed, nil
+}
+
+// UpdateSession stores the outcome of a generation attempt
+func (r *trainingPlanRepository) UpdateSession(ctx context.Context, session *model.TrainingSession) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE training_sessions SET status = ?, generate_at = ?, playlist_url = ?, last_error = ?,
+			generated_at = ?, updated_at = ?
+		WHERE id = ?`,
+		session.Status, session.GenerateAt, session.PlaylistURL, session.LastError,
+		session.GeneratedAt, session.UpdatedAt, session.ID)
+	if err != nil {
+		return fmt.Errorf("error updating training session: %v", err)
+	}
+	return nil
+}
+
+func (r *trainingPlanRepository) querySessions(ctx context.Context, query string, args ...any) ([]model.TrainingSession, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing training sessions: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []model.TrainingSession
+	for rows.Next() {
+		var session model.TrainingSession
+		if err := rows.Scan(&session.ID, &session.PlanID, &session.UserID, &session.WorkoutID, &session.Title,
+			&session.Notes, &session.ScheduledAt, &session.GenerateAt, &session.Status, &session.Attempts,
+			&session.PlaylistURL, &session.LastError, &session.GeneratedAt, &session.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning training session: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing training sessions: %v", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type trainingZoneRepository struct {
+	db *DB
+}
+
+func NewTrainingZoneRepo(db *DB) *trainingZoneRepository {
+	return &trainingZoneRepository{db: db}
+}
+
+func (r *trainingZoneRepository) GetTrainingZones(ctx context.Context, userID string) (*model.TrainingZones, error) {
+	var zones model.TrainingZones
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, method, race_distance_meters, race_time_seconds, vdot, easy_seconds, marathon_seconds,
+			threshold_seconds, interval_seconds, repetition_seconds, updated_at
+		FROM training_zones WHERE user_id = ?`,
+		userID).Scan(&zones.UserID, &zones.Method, &zones.RaceDistanceMeters, &zones.RaceTimeSeconds, &zones.VDOT,
+		&zones.EasySeconds, &zones.MarathonSeconds, &zones.ThresholdSeconds, &zones.IntervalSeconds,
+		&zones.RepetitionSeconds, &zones.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("training zones %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting training zones: %v", err)
+	}
+	return &zones, nil
+}
+
+func (r *trainingZoneRepository) SaveTrainingZones(ctx context.Context, zones *model.TrainingZones) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO training_zones (user_id, method, race_distance_meters, race_time_seconds, vdot, easy_seconds,
+			marathon_seconds, threshold_seconds, interval_seconds, repetition_seconds, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`+r.db.upsert([]string{"user_id"}, "method", "race_distance_meters", "race_time_seconds", "vdot",
+			"easy_seconds", "marathon_seconds", "threshold_seconds", "interval_seconds", "repetition_seconds", "updated_at"),
+		zones.UserID, zones.Method, zones.RaceDistanceMeters, zones.RaceTimeSeconds, zones.VDOT, zones.EasySeconds,
+		zones.MarathonSeconds, zones.ThresholdSeconds, zones.IntervalSeconds, zones.RepetitionSeconds, zones.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving training zones: %v", err)
+	}
+	return nil
+}
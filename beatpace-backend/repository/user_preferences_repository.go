@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type userPreferencesRepository struct {
+	db *DB
+}
+
+func NewUserPreferencesRepo(db *DB) *userPreferencesRepository {
+	return &userPreferencesRepository{db: db}
+}
+
+func (r *userPreferencesRepository) GetPreferences(ctx context.Context, userID string) (*model.UserPreferences, error) {
+	var prefs model.UserPreferences
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, height, height_unit, gender, stride_length_cm, pace_unit, tempo_tolerance,
+			explicit_content, genres, playlist_public, updated_at
+		FROM user_preferences WHERE user_id = ?`,
+		userID).Scan(&prefs.UserID, &prefs.Height, &prefs.HeightUnit, &prefs.Gender, &prefs.StrideLengthCm,
+		&prefs.PaceUnit, &prefs.TempoTolerance, &prefs.ExplicitContent, &prefs.Genres, &prefs.PlaylistPublic,
+		&prefs.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("preferences %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting preferences: %v", err)
+	}
+	return &prefs, nil
+}
+
+func (r *userPreferencesRepository) SavePreferences(ctx context.Context, prefs *model.UserPreferences) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_preferences (user_id, height, height_unit, gender, stride_length_cm, pace_unit,
+			tempo_tolerance, explicit_content, genres, playlist_public, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`+r.db.upsert([]string{"user_id"}, "height", "height_unit", "gender", "stride_length_cm", "pace_unit",
+			"tempo_tolerance", "explicit_content", "genres", "playlist_public", "updated_at"),
+		prefs.UserID, prefs.Height, prefs.HeightUnit, prefs.Gender, prefs.StrideLengthCm, prefs.PaceUnit,
+		prefs.TempoTolerance, prefs.ExplicitContent, prefs.Genres, prefs.PlaylistPublic, prefs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving preferences: %v", err)
+	}
+	return nil
+}
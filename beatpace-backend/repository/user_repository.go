@@ -4,15 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/yimango/beatpace-backend/model"
 )
 
 type userRepository struct {
-	db *sql.DB
+	db *DB
 }
 
-func NewUserRepo(db *sql.DB) *userRepository {
+func NewUserRepo(db *DB) *userRepository {
 	return &userRepository{db: db}
 }
 
@@ -31,9 +32,10 @@ func (r *userRepository) GetUser(ctx context.Context, id string) (*model.User, e
 }
 
 func (r *userRepository) CreateUser(ctx context.Context, user *model.User) error {
+	now := time.Now()
 	_, err := r.db.ExecContext(ctx,
-		"INSERT INTO users (id, spotify_user_id, email, created_at, updated_at) VALUES (?, ?, ?, NOW(), NOW())",
-		user.ID, user.SpotifyUserID, user.Email)
+		"INSERT INTO users (id, spotify_user_id, email, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		user.ID, user.SpotifyUserID, user.Email, now, now)
 	if err != nil {
 		return fmt.Errorf("error creating user: %v", err)
 	}
@@ -42,8 +44,8 @@ func (r *userRepository) CreateUser(ctx context.Context, user *model.User) error
 
 func (r *userRepository) UpdateUser(ctx context.Context, user *model.User) error {
 	_, err := r.db.ExecContext(ctx,
-		"UPDATE users SET spotify_user_id = ?, email = ?, updated_at = NOW() WHERE id = ?",
-		user.SpotifyUserID, user.Email, user.ID)
+		"UPDATE users SET spotify_user_id = ?, email = ?, updated_at = ? WHERE id = ?",
+		user.SpotifyUserID, user.Email, time.Now(), user.ID)
 	if err != nil {
 		return fmt.Errorf("error updating user: %v", err)
 	}
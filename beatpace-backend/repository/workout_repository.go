@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/model"
+)
+
+type workoutRepository struct {
+	db *DB
+}
+
+func NewWorkoutRepo(db *DB) *workoutRepository {
+	return &workoutRepository{db: db}
+}
+
+func (r *workoutRepository) CreateWorkout(ctx context.Context, workout *model.Workout) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO workouts (id, user_id, name, description, pace_unit, blocks, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		workout.ID, workout.UserID, workout.Name, workout.Description, workout.PaceUnit, workout.Blocks,
+		workout.CreatedAt, workout.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating workout: %v", err)
+	}
+	return nil
+}
+
+func (r *workoutRepository) GetWorkout(ctx context.Context, userID string, id string) (*model.Workout, error) {
+	var workout model.Workout
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, name, description, pace_unit, blocks, created_at, updated_at
+		FROM workouts WHERE id = ? AND user_id = ?`,
+		id, userID).Scan(&workout.ID, &workout.UserID, &workout.Name, &workout.Description, &workout.PaceUnit,
+		&workout.Blocks, &workout.CreatedAt, &workout.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("workout %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting workout: %v", err)
+	}
+	return &workout, nil
+}
+
+func (r *workoutRepository) ListWorkouts(ctx context.Context, userID string) ([]model.Workout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, name, description, pace_unit, blocks, created_at, updated_at
+		FROM workouts WHERE user_id = ? ORDER BY updated_at DESC, name`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing workouts: %v", err)
+	}
+	defer rows.Close()
+
+	var workouts []model.Workout
+	for rows.Next() {
+		var workout model.Workout
+		if err := rows.Scan(&workout.ID, &workout.UserID, &workout.Name, &workout.Description, &workout.PaceUnit,
+			&workout.Blocks, &workout.CreatedAt, &workout.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning workout: %v", err)
+		}
+		workouts = append(workouts, workout)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing workouts: %v", err)
+	}
+	return workouts, nil
+}
+
+func (r *workoutRepository) UpdateWorkout(ctx context.Context, workout *model.Workout) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE workouts SET name = ?, description = ?, pace_unit = ?, blocks = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`,
+		workout.Name, workout.Description, workout.PaceUnit, workout.Blocks, workout.UpdatedAt,
+		workout.ID, workout.UserID)
+	if err != nil {
+		return fmt.Errorf("error updating workout: %v", err)
+	}
+	return nil
+}
+
+func (r *workoutRepository) DeleteWorkout(ctx context.Context, userID string, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM workouts WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting workout: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("workout %w", ErrNotFound)
+	}
+	return nil
+}
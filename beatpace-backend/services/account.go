@@ -0,0 +1,491 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zmb3/spotify/v2"
+
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+)
+
+// AccountArchiveVersion is the version of the export archive format. Bump it whenever
+// the shape of AccountArchive or any type it embeds changes.
+//
+// Version 1 is a single JSON document with these sections:
+//
+//   - version, exportedAt: the format version and when the archive was made
+//   - user: the account record
+//   - sessions: login sessions; the session tokens themselves are omitted
+//   - spotify: the linked Spotify account; the OAuth tokens are omitted
+//   - calendarFeed: when the calendar feed was created; the feed token is omitted
+//   - preferences, playlistTemplates, trainingZones: stored settings, absent if never saved
+//   - cadenceProgram: the current cadence program, absent if none, and its full history
+//   - workouts, trainingPlans: saved workouts and plans, plans with their sessions
+//   - playlists: every generated playlist with its inputs, tracks and feedback effects
+//   - trackFeedback: everything the user has said about individual tracks
+//
+// Lists are always present and empty when there is nothing to export.
+const AccountArchiveVersion = 1
+
+const (
+	// accountPlaylistPageSize is how many playlists are read at a time when exporting
+	// or unfollowing
+	accountPlaylistPageSize = 100
+	// accountHistoryLimit caps the cadence program history read for an export
+	accountHistoryLimit = 10000
+)
+
+// AccountArchive is everything stored about a user; see AccountArchiveVersion
+type AccountArchive struct {
+	Version           int                      `json:"version"`
+	ExportedAt        time.Time                `json:"exportedAt"`
+	User              ArchiveUser              `json:"user"`
+	Sessions          []ArchiveSession         `json:"sessions"`
+	Spotify           *ArchiveSpotify          `json:"spotify,omitempty"`
+	CalendarFeed      *ArchiveCalendarFeed     `json:"calendarFeed,omitempty"`
+	Preferences       *PreferencesResponse     `json:"preferences,omitempty"`
+	PlaylistTemplates *ArchivePlaylistTemplate `json:"playlistTemplates,omitempty"`
+	TrainingZones     *TrainingZonesResponse   `json:"trainingZones,omitempty"`
+	CadenceProgram    *ArchiveCadenceProgram   `json:"cadenceProgram,omitempty"`
+	CadenceHistory    []CadenceHistoryRow      `json:"cadenceHistory"`
+	Workouts          []WorkoutTemplate        `json:"workouts"`
+	TrainingPlans     []TrainingPlanView       `json:"trainingPlans"`
+	Playlists         []ArchivePlaylist        `json:"playlists"`
+	TrackFeedback     []TrackFeedbackView      `json:"trackFeedback"`
+}
+
+// ArchiveUser is the account record
+type ArchiveUser struct {
+	ID            string     `json:"id"`
+	SpotifyUserID string     `json:"spotifyUserId"`
+	Email         *string    `json:"email,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	LastLogin     *time.Time `json:"lastLogin,omitempty"`
+}
+
+// ArchiveSession is one login session
+type ArchiveSession struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ArchiveSpotify is the linked Spotify account
+type ArchiveSpotify struct {
+	SpotifyUserID string    `json:"spotifyUserId"`
+	GeneratedAt   time.Time `json:"generatedAt"` // When the access token was issued
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+// ArchiveCalendarFeed is the user's calendar feed
+type ArchiveCalendarFeed struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ArchivePlaylistTemplate is the user's playlist naming templates
+type ArchivePlaylistTemplate struct {
+	NameTemplate        string    `json:"nameTemplate"`
+	DescriptionTemplate string    `json:"descriptionTemplate"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+// ArchiveCadenceProgram is the user's current cadence program
+type ArchiveCadenceProgram struct {
+	StartCadence   int       `json:"startCadence"`
+	TargetCadence  int       `json:"targetCadence"`
+	StepSPM        int       `json:"stepSpm"`
+	CurrentStage   int       `json:"currentStage"`
+	StartedAt      time.Time `json:"startedAt"`
+	StageStartedAt time.Time `json:"stageStartedAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// ArchivePlaylist is a generated playlist with how feedback shaped it
+type ArchivePlaylist struct {
+	PlaylistDetail
+	FeedbackEffects []ArchiveFeedbackEffect `json:"feedbackEffects"`
+}
+
+// ArchiveFeedbackEffect is how feedback on one track affected a playlist
+type ArchiveFeedbackEffect struct {
+	TrackID    string  `json:"trackId"`
+	Name       string  `json:"name"`
+	Score      float64 `json:"score"`
+	TempoTrust float64 `json:"tempoTrust"`
+	Included   bool    `json:"included"`
+	Excluded   bool    `json:"excluded"`
+}
+
+// AccountDeletionResponse summarises a completed account deletion
+type AccountDeletionResponse struct {
+	DeletedAt           time.Time        `json:"deletedAt"`
+	RemovedRows         map[string]int64 `json:"removedRows"` // Rows removed per table
+	PlaylistsUnfollowed int              `json:"playlistsUnfollowed"`
+	UnfollowFailures    int              `json:"unfollowFailures"`
+}
+
+type accountService struct {
+	accountRepo    repository.AccountRepository
+	userRepo       repository.UserRepository
+	tokenRepo      repository.TokenRepository
+	templateRepo   repository.PlaylistTemplateRepository
+	workoutRepo    repository.WorkoutRepository
+	planRepo       repository.TrainingPlanRepository
+	feedRepo       repository.CalendarFeedRepository
+	zoneRepo       repository.TrainingZoneRepository
+	programRepo    repository.CadenceProgramRepository
+	playlistRepo   repository.PlaylistRepository
+	feedbackRepo   repository.TrackFeedbackRepository
+	prefsRepo      repository.UserPreferencesRepository
+	spotifyService SpotifyService
+}
+
+func NewAccountService(
+	accountRepo repository.AccountRepository,
+	userRepo repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	templateRepo repository.PlaylistTemplateRepository,
+	workoutRepo repository.WorkoutRepository,
+	planRepo repository.TrainingPlanRepository,
+	feedRepo repository.CalendarFeedRepository,
+	zoneRepo repository.TrainingZoneRepository,
+	programRepo repository.CadenceProgramRepository,
+	playlistRepo repository.PlaylistRepository,
+	feedbackRepo repository.TrackFeedbackRepository,
+	prefsRepo repository.UserPreferencesRepository,
+	spotifyService SpotifyService,
+) AccountService {
+	return &accountService{
+		accountRepo:    accountRepo,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		templateRepo:   templateRepo,
+		workoutRepo:    workoutRepo,
+		planRepo:       planRepo,
+		feedRepo:       feedRepo,
+		zoneRepo:       zoneRepo,
+		programRepo:    programRepo,
+		playlistRepo:   playlistRepo,
+		feedbackRepo:   feedbackRepo,
+		prefsRepo:      prefsRepo,
+		spotifyService: spotifyService,
+	}
+}
+
+// DeleteAccount removes the user and everything stored about them. When asked, the
+// playlists in their history are first unfollowed on Spotify; that cannot be part of
+// the database transaction, so it is best effort and the outcome is recorded in the
+// audit entry.
+func (s *accountService) DeleteAccount(ctx context.Context, userID string, unfollowPlaylists bool) (*AccountDeletionResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	audit := &model.AccountDeletion{
+		ID:     uuid.New(),
+		UserID: uid,
+	}
+	if unfollowPlaylists {
+		audit.PlaylistsUnfollowed, audit.UnfollowFailures, err = s.unfollowPlaylists(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	audit.DeletedAt = time.Now()
+	err = s.accountRepo.DeleteAccount(ctx, userID, audit)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: user %s", ErrNotFound, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AccountDeletionResponse{
+		DeletedAt:           audit.DeletedAt,
+		PlaylistsUnfollowed: audit.PlaylistsUnfollowed,
+		UnfollowFailures:    audit.UnfollowFailures,
+	}
+	if err := json.Unmarshal([]byte(audit.RemovedRows), &response.RemovedRows); err != nil {
+		return nil, fmt.Errorf("failed to decode removed rows: %v", err)
+	}
+	return response, nil
+}
+
+// unfollowPlaylists unfollows every playlist in the user's history, which for
+// playlists the user owns removes them from their Spotify library
+func (s *accountService) unfollowPlaylists(ctx context.Context, userID string) (int, int, error) {
+	playlists, err := s.allPlaylists(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(playlists) == 0 {
+		return 0, 0, nil
+	}
+
+	client := s.spotifyService.GetClient(ctx, userID)
+	if client == nil {
+		fmt.Printf("Failed to get Spotify client, not unfollowing %d playlists\n", len(playlists))
+		return 0, len(playlists), nil
+	}
+
+	unfollowed, failed := 0, 0
+	for _, playlist := range playlists {
+		if err := client.UnfollowPlaylist(ctx, spotify.ID(playlist.SpotifyPlaylistID)); err != nil {
+			fmt.Printf("Failed to unfollow playlist %s: %v\n", playlist.SpotifyPlaylistID, err)
+			failed++
+			continue
+		}
+		unfollowed++
+	}
+	return unfollowed, failed, nil
+}
+
+// allPlaylists reads the user's whole playlist history, newest first
+func (s *accountService) allPlaylists(ctx context.Context, userID string) ([]model.Playlist, error) {
+	var all []model.Playlist
+	filter := repository.PlaylistListFilter{Limit: accountPlaylistPageSize}
+	for {
+		page, err := s.playlistRepo.ListPlaylists(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < accountPlaylistPageSize {
+			return all, nil
+		}
+		last := page[len(page)-1]
+		filter.AfterCreatedAt = last.CreatedAt
+		filter.AfterID = &last.ID
+	}
+}
+
+// ExportAccount gathers everything stored about the user into a versioned archive.
+// Credentials (session, OAuth and calendar feed tokens) are left out.
+func (s *accountService) ExportAccount(ctx context.Context, userID string) (*AccountArchive, error) {
+	user, err := s.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &AccountArchive{
+		Version:    AccountArchiveVersion,
+		ExportedAt: time.Now(),
+		User: ArchiveUser{
+			ID:            user.ID.String(),
+			SpotifyUserID: user.SpotifyUserID,
+			Email:         user.Email,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			LastLogin:     user.LastLogin,
+		},
+		Sessions:       []ArchiveSession{},
+		CadenceHistory: []CadenceHistoryRow{},
+		Workouts:       []WorkoutTemplate{},
+		TrainingPlans:  []TrainingPlanView{},
+		Playlists:      []ArchivePlaylist{},
+		TrackFeedback:  []TrackFeedbackView{},
+	}
+
+	sessions, err := s.accountRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		archive.Sessions = append(archive.Sessions, ArchiveSession{
+			ID:        session.ID.String(),
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+		})
+	}
+
+	if err := s.exportSettings(ctx, userID, archive); err != nil {
+		return nil, err
+	}
+	if err := s.exportTraining(ctx, userID, archive); err != nil {
+		return nil, err
+	}
+	if err := s.exportPlaylists(ctx, userID, archive); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// exportSettings adds the linked accounts and the records a user has at most one of
+func (s *accountService) exportSettings(ctx context.Context, userID string, archive *AccountArchive) error {
+	token, err := s.tokenRepo.GetSpotifyToken(ctx, userID)
+	if err == nil {
+		archive.Spotify = &ArchiveSpotify{
+			SpotifyUserID: token.SpotifyUserID,
+			GeneratedAt:   token.GeneratedAt,
+			ExpiresAt:     token.ExpiresAt,
+		}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	feed, err := s.feedRepo.GetCalendarFeed(ctx, userID)
+	if err == nil {
+		archive.CalendarFeed = &ArchiveCalendarFeed{CreatedAt: feed.CreatedAt}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	prefs, err := loadPreferences(ctx, s.prefsRepo, userID)
+	if err != nil {
+		return err
+	}
+	if prefs != nil {
+		if archive.Preferences, err = newPreferencesResponse(prefs); err != nil {
+			return err
+		}
+	}
+
+	template, err := s.templateRepo.GetPlaylistTemplate(ctx, userID)
+	if err == nil {
+		archive.PlaylistTemplates = &ArchivePlaylistTemplate{
+			NameTemplate:        template.NameTemplate,
+			DescriptionTemplate: template.DescriptionTemplate,
+			UpdatedAt:           template.UpdatedAt,
+		}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	zones, err := s.zoneRepo.GetTrainingZones(ctx, userID)
+	if err == nil {
+		archive.TrainingZones = newTrainingZonesResponse(zones)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	feedback, err := s.feedbackRepo.ListTrackFeedback(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range feedback {
+		archive.TrackFeedback = append(archive.TrackFeedback, *newTrackFeedbackView(&feedback[i]))
+	}
+	return nil
+}
+
+// exportTraining adds the cadence program, saved workouts and training plans
+func (s *accountService) exportTraining(ctx context.Context, userID string, archive *AccountArchive) error {
+	program, err := s.programRepo.GetCadenceProgram(ctx, userID)
+	if err == nil {
+		archive.CadenceProgram = &ArchiveCadenceProgram{
+			StartCadence:   program.StartCadence,
+			TargetCadence:  program.TargetCadence,
+			StepSPM:        program.StepSPM,
+			CurrentStage:   program.CurrentStage,
+			StartedAt:      program.StartedAt,
+			StageStartedAt: program.StageStartedAt,
+			UpdatedAt:      program.UpdatedAt,
+		}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	// History outlives a stopped program, so it is exported either way
+	events, err := s.programRepo.ListCadenceProgramEvents(ctx, userID, accountHistoryLimit)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		archive.CadenceHistory = append(archive.CadenceHistory, CadenceHistoryRow{
+			Action:    event.Action,
+			Stage:     event.Stage,
+			Cadence:   event.Cadence,
+			Note:      event.Note,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	workouts, err := s.workoutRepo.ListWorkouts(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range workouts {
+		template, err := newWorkoutTemplate(&workouts[i])
+		if err != nil {
+			return err
+		}
+		archive.Workouts = append(archive.Workouts, *template)
+	}
+
+	plans, err := s.planRepo.ListPlans(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range plans {
+		sessions, err := s.planRepo.ListPlanSessions(ctx, plans[i].ID.String())
+		if err != nil {
+			return err
+		}
+		view, err := newTrainingPlanView(&plans[i], sessions)
+		if err != nil {
+			return err
+		}
+		archive.TrainingPlans = append(archive.TrainingPlans, *view)
+	}
+	return nil
+}
+
+// exportPlaylists adds the playlist history with tracks and feedback effects
+func (s *accountService) exportPlaylists(ctx context.Context, userID string, archive *AccountArchive) error {
+	playlists, err := s.allPlaylists(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(playlists); start += accountPlaylistPageSize {
+		page := playlists[start:min(start+accountPlaylistPageSize, len(playlists))]
+		ids := make([]uuid.UUID, 0, len(page))
+		for _, playlist := range page {
+			ids = append(ids, playlist.ID)
+		}
+		effects, err := s.playlistRepo.ListFeedbackEffects(ctx, ids)
+		if err != nil {
+			return err
+		}
+		byPlaylist := make(map[uuid.UUID][]ArchiveFeedbackEffect)
+		for _, effect := range effects {
+			byPlaylist[effect.PlaylistID] = append(byPlaylist[effect.PlaylistID], ArchiveFeedbackEffect{
+				TrackID:    effect.SpotifyTrackID,
+				Name:       effect.Name,
+				Score:      effect.Score,
+				TempoTrust: effect.TempoTrust,
+				Included:   effect.Included,
+				Excluded:   effect.Excluded,
+			})
+		}
+
+		for _, playlist := range page {
+			_, tracks, err := s.playlistRepo.GetPlaylist(ctx, userID, playlist.ID.String())
+			if err != nil {
+				return err
+			}
+			detail, err := newPlaylistDetail(&playlist, tracks)
+			if err != nil {
+				return err
+			}
+			entry := ArchivePlaylist{
+				PlaylistDetail:  *detail,
+				FeedbackEffects: byPlaylist[playlist.ID],
+			}
+			if entry.FeedbackEffects == nil {
+				entry.FeedbackEffects = []ArchiveFeedbackEffect{}
+			}
+			archive.Playlists = append(archive.Playlists, entry)
+		}
+	}
+	return nil
+}
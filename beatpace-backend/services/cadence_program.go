@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+const (
+	// cadenceStageLength is how long each stage is meant to last before advancing
+	cadenceStageLength = 7 * 24 * time.Hour
+	// cadenceHistoryLimit caps the history entries returned with a program
+	cadenceHistoryLimit = 100
+)
+
+// CadenceProgramResponse is a user's cadence program with its stages and history
+type CadenceProgramResponse struct {
+	StartCadence   int                 `json:"startCadence"`
+	TargetCadence  int                 `json:"targetCadence"`
+	StepSPM        int                 `json:"stepSpm"`
+	CurrentStage   int                 `json:"currentStage"`
+	FinalStage     int                 `json:"finalStage"`
+	CurrentCadence int                 `json:"currentCadence"` // Target cadence of generated playlists
+	Complete       bool                `json:"complete"`
+	StartedAt      time.Time           `json:"startedAt"`
+	StageStartedAt time.Time           `json:"stageStartedAt"`
+	NextAdvanceAt  *time.Time          `json:"nextAdvanceAt,omitempty"` // A week after the stage began; absent once complete
+	Stages         []CadenceStage      `json:"stages"`
+	History        []CadenceHistoryRow `json:"history"`
+}
+
+// CadenceStage is one stage of a cadence program
+type CadenceStage struct {
+	Stage   int `json:"stage"`
+	Cadence int `json:"cadence"`
+}
+
+// CadenceHistoryRow is one entry of a user's cadence program history
+type CadenceHistoryRow struct {
+	Action    string    `json:"action"`
+	Stage     int       `json:"stage"`
+	Cadence   int       `json:"cadence"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type cadenceProgramService struct {
+	programRepo repository.CadenceProgramRepository
+}
+
+func NewCadenceProgramService(programRepo repository.CadenceProgramRepository) CadenceProgramService {
+	return &cadenceProgramService{
+		programRepo: programRepo,
+	}
+}
+
+// GetCadenceProgram returns the user's program with its history
+func (s *cadenceProgramService) GetCadenceProgram(ctx context.Context, userID string) (*CadenceProgramResponse, error) {
+	program, err := s.getProgram(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.newResponse(ctx, program)
+}
+
+// SaveCadenceProgram starts a program at stage 0, replacing any the user had
+func (s *cadenceProgramService) SaveCadenceProgram(ctx context.Context, userID string, req types.CadenceProgramRequest) (*CadenceProgramResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	step := req.StepSPM
+	if step == 0 {
+		step = max(int(math.Round(float64(req.StartCadence)*req.StepPercent/100)), 1)
+	}
+
+	now := time.Now()
+	program := &model.CadenceProgram{
+		UserID:         uid,
+		StartCadence:   req.StartCadence,
+		TargetCadence:  req.TargetCadence,
+		StepSPM:        step,
+		StartedAt:      now,
+		StageStartedAt: now,
+		UpdatedAt:      now,
+	}
+	if err := s.save(ctx, program, model.CadenceProgramStarted, ""); err != nil {
+		return nil, err
+	}
+	return s.newResponse(ctx, program)
+}
+
+// AdvanceStage moves the program to its next stage
+func (s *cadenceProgramService) AdvanceStage(ctx context.Context, userID string, note string) (*CadenceProgramResponse, error) {
+	program, err := s.getProgram(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if program.CurrentStage >= cadenceFinalStage(program) {
+		return nil, fmt.Errorf("%w: the program is already at its target cadence", ErrInvalidRequest)
+	}
+
+	now := time.Now()
+	program.CurrentStage++
+	program.StageStartedAt = now
+	program.UpdatedAt = now
+	if err := s.save(ctx, program, model.CadenceProgramAdvanced, note); err != nil {
+		return nil, err
+	}
+	return s.newResponse(ctx, program)
+}
+
+// HoldStage keeps the program at its current stage for another week
+func (s *cadenceProgramService) HoldStage(ctx context.Context, userID string, note string) (*CadenceProgramResponse, error) {
+	program, err := s.getProgram(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	program.StageStartedAt = now
+	program.UpdatedAt = now
+	if err := s.save(ctx, program, model.CadenceProgramHeld, note); err != nil {
+		return nil, err
+	}
+	return s.newResponse(ctx, program)
+}
+
+// DeleteCadenceProgram stops the user's program. Its history is kept.
+func (s *cadenceProgramService) DeleteCadenceProgram(ctx context.Context, userID string) error {
+	program, err := s.getProgram(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	event := newCadenceProgramEvent(program, model.CadenceProgramStopped, "")
+	err = s.programRepo.DeleteCadenceProgram(ctx, userID, event)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: no cadence program", ErrNotFound)
+	}
+	return err
+}
+
+func (s *cadenceProgramService) getProgram(ctx context.Context, userID string) (*model.CadenceProgram, error) {
+	program, err := s.programRepo.GetCadenceProgram(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: no cadence program", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (s *cadenceProgramService) save(ctx context.Context, program *model.CadenceProgram, action string, note string) error {
+	event := newCadenceProgramEvent(program, action, note)
+	if err := s.programRepo.SaveCadenceProgram(ctx, program, event); err != nil {
+		return fmt.Errorf("failed to save cadence program: %v", err)
+	}
+	return nil
+}
+
+func (s *cadenceProgramService) newResponse(ctx context.Context, program *model.CadenceProgram) (*CadenceProgramResponse, error) {
+	events, err := s.programRepo.ListCadenceProgramEvents(ctx, program.UserID.String(), cadenceHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	final := cadenceFinalStage(program)
+	response := &CadenceProgramResponse{
+		StartCadence:   program.StartCadence,
+		TargetCadence:  program.TargetCadence,
+		StepSPM:        program.StepSPM,
+		CurrentStage:   program.CurrentStage,
+		FinalStage:     final,
+		CurrentCadence: cadenceForStage(program, program.CurrentStage),
+		Complete:       program.CurrentStage >= final,
+		StartedAt:      program.StartedAt,
+		StageStartedAt: program.StageStartedAt,
+		History:        []CadenceHistoryRow{},
+	}
+	if !response.Complete {
+		next := program.StageStartedAt.Add(cadenceStageLength)
+		response.NextAdvanceAt = &next
+	}
+	for stage := 0; stage <= final; stage++ {
+		response.Stages = append(response.Stages, CadenceStage{Stage: stage, Cadence: cadenceForStage(program, stage)})
+	}
+	for _, event := range events {
+		response.History = append(response.History, CadenceHistoryRow{
+			Action:    event.Action,
+			Stage:     event.Stage,
+			Cadence:   event.Cadence,
+			Note:      event.Note,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// currentProgramCadence returns the cadence and number of the user's current program
+// stage, and false when the user has no program
+func currentProgramCadence(ctx context.Context, programRepo repository.CadenceProgramRepository, userID string) (int, int, bool, error) {
+	program, err := programRepo.GetCadenceProgram(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return cadenceForStage(program, program.CurrentStage), program.CurrentStage, true, nil
+}
+
+// ignoredProgramStage returns the user's current program stage for the generation
+// modes that do not apply it, so their responses can say so. It is nil when the user
+// has no program or it cannot be loaded.
+func ignoredProgramStage(ctx context.Context, programRepo repository.CadenceProgramRepository, userID string) *int {
+	_, stage, ok, err := currentProgramCadence(ctx, programRepo, userID)
+	if err != nil {
+		fmt.Printf("Failed to load cadence program: %v\n", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return &stage
+}
+
+// cadenceFinalStage is the first stage that reaches the target cadence
+func cadenceFinalStage(program *model.CadenceProgram) int {
+	distance := abs(program.TargetCadence - program.StartCadence)
+	return (distance + program.StepSPM - 1) / program.StepSPM
+}
+
+// cadenceForStage moves from the start cadence towards the target one step per stage,
+// stopping at the target
+func cadenceForStage(program *model.CadenceProgram, stage int) int {
+	if program.TargetCadence >= program.StartCadence {
+		return min(program.StartCadence+stage*program.StepSPM, program.TargetCadence)
+	}
+	return max(program.StartCadence-stage*program.StepSPM, program.TargetCadence)
+}
+
+func newCadenceProgramEvent(program *model.CadenceProgram, action string, note string) *model.CadenceProgramEvent {
+	return &model.CadenceProgramEvent{
+		ID:        uuid.New(),
+		UserID:    program.UserID,
+		Action:    action,
+		Stage:     program.CurrentStage,
+		Cadence:   cadenceForStage(program, program.CurrentStage),
+		Note:      note,
+		CreatedAt: program.UpdatedAt,
+	}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/utils"
+)
+
+// standardRaceOrder lists the standard race distances from shortest to longest
+var standardRaceOrder = []string{"5k", "10k", "half", "marathon"}
+
+// PaceCalculation is a pace in every unit the calculator offers, with the finish time
+// when a distance was given
+type PaceCalculation struct {
+	PaceSecondsKm        int      `json:"paceSecondsKm"`
+	PaceKm               string   `json:"paceKm"`
+	PaceSecondsMile      int      `json:"paceSecondsMile"`
+	PaceMile             string   `json:"paceMile"`
+	SpeedKmh             float64  `json:"speedKmh"`
+	SpeedMph             float64  `json:"speedMph"`
+	SpeedMetersPerSecond float64  `json:"speedMetersPerSecond"`
+	DistanceMeters       *float64 `json:"distanceMeters,omitempty"`
+	TimeSeconds          *int     `json:"timeSeconds,omitempty"`
+	Time                 string   `json:"time,omitempty"`
+}
+
+// RacePrediction lists predicted times for other distances from one race result
+type RacePrediction struct {
+	DistanceMeters float64            `json:"distanceMeters"`
+	TimeSeconds    int                `json:"timeSeconds"`
+	Time           string             `json:"time"`
+	Exponent       float64            `json:"exponent"`
+	Predictions    []PredictedRaceRun `json:"predictions"`
+}
+
+// PredictedRaceRun is the predicted time and pace for one distance
+type PredictedRaceRun struct {
+	Distance        string  `json:"distance,omitempty"`
+	DistanceMeters  float64 `json:"distanceMeters"`
+	TimeSeconds     int     `json:"timeSeconds"`
+	Time            string  `json:"time"`
+	PaceSecondsKm   int     `json:"paceSecondsKm"`
+	PaceKm          string  `json:"paceKm"`
+	PaceSecondsMile int     `json:"paceSecondsMile"`
+	PaceMile        string  `json:"paceMile"`
+}
+
+// CadenceCalculation walks from a pace to a music tempo through the same cadence
+// models playlist generation uses. The stride and step fields are the length of each
+// step at that pace and cadence, so stride × cadence gives back the speed.
+type CadenceCalculation struct {
+	PaceSeconds          int     `json:"paceSeconds"`
+	PaceUnit             string  `json:"paceUnit"`
+	SpeedMetersPerSecond float64 `json:"speedMetersPerSecond"`
+
+	StrideLengthMeters float64 `json:"strideLengthMeters"`
+	StrideSource       string  `json:"strideSource"` // "strideLength", "height", "default" or "pace"
+	StepsPerKm         float64 `json:"stepsPerKm"`
+	StepsPerUnit       float64 `json:"stepsPerUnit"` // Steps per km or mile, matching PaceUnit
+
+	Activity    string `json:"activity"`
+	Cadence     int    `json:"cadence"`     // Steps per minute under the activity's cadence model
+	TargetBPM   int    `json:"targetBpm"`   // Tempo playlist generation targets
+	HalfTimeBPM int    `json:"halfTimeBpm"` // One beat per two steps; generation also matches tracks at half time
+	MinTempoBPM int    `json:"minTempoBpm"`
+	MaxTempoBPM int    `json:"maxTempoBpm"`
+	TargetInfo  string `json:"targetInfo"`
+}
+
+// CalculatePace converts whichever of pace, speed or finish time was given into the
+// others
+func CalculatePace(req types.PaceCalcRequest) (*PaceCalculation, error) {
+	given := 0
+	for _, set := range []bool{req.PaceSeconds > 0, req.Speed > 0, req.TimeSeconds > 0} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("%w: give exactly one of paceSeconds, speed and timeSeconds", ErrInvalidRequest)
+	}
+
+	var distance float64
+	if req.Distance != "" {
+		var err error
+		if distance, err = raceDistance(req.Distance, req.DistanceMeters); err != nil {
+			return nil, err
+		}
+	} else if req.DistanceMeters > 0 {
+		distance = req.DistanceMeters
+	}
+
+	// Work in meters per second
+	var speed float64
+	switch {
+	case req.PaceSeconds > 0:
+		speed = unitMeters(normalizePaceUnit(req.PaceUnit)) / float64(req.PaceSeconds)
+	case req.Speed > 0:
+		speed = treadmillSpeed(req.Speed, req.SpeedUnit)
+	default:
+		if distance <= 0 {
+			return nil, fmt.Errorf("%w: a distance is required to convert a finish time", ErrInvalidRequest)
+		}
+		speed = distance / float64(req.TimeSeconds)
+	}
+
+	perKm := roundSeconds(1000 / speed)
+	perMile := roundSeconds(metersPerMile / speed)
+	calculation := &PaceCalculation{
+		PaceSecondsKm:        perKm,
+		PaceKm:               formatClock(perKm),
+		PaceSecondsMile:      perMile,
+		PaceMile:             formatClock(perMile),
+		SpeedKmh:             roundTo(speed*3.6, 2),
+		SpeedMph:             roundTo(speed*3600/metersPerMile, 2),
+		SpeedMetersPerSecond: roundTo(speed, 3),
+	}
+	if distance > 0 {
+		seconds := roundSeconds(distance / speed)
+		calculation.DistanceMeters = &distance
+		calculation.TimeSeconds = &seconds
+		calculation.Time = formatClock(seconds)
+	}
+	return calculation, nil
+}
+
+// PredictRaceTimes predicts times for a target distance, or every standard distance,
+// from a race result using Riegel's formula
+func PredictRaceTimes(req types.RacePredictionRequest) (*RacePrediction, error) {
+	distance, err := raceDistance(req.Distance, req.DistanceMeters)
+	if err != nil {
+		return nil, err
+	}
+	exponent := utils.DefaultRiegelExponent
+	if req.Exponent != nil {
+		exponent = *req.Exponent
+	}
+
+	type target struct {
+		name   string
+		meters float64
+	}
+	var targets []target
+	if req.TargetDistance != "" {
+		meters, err := raceDistance(req.TargetDistance, req.TargetDistanceMeters)
+		if err != nil {
+			return nil, err
+		}
+		name := req.TargetDistance
+		if name == RaceDistanceCustom {
+			name = ""
+		}
+		targets = append(targets, target{name, meters})
+	} else {
+		for _, name := range standardRaceOrder {
+			targets = append(targets, target{name, raceDistances[name]})
+		}
+	}
+
+	prediction := &RacePrediction{
+		DistanceMeters: distance,
+		TimeSeconds:    req.TimeSeconds,
+		Time:           formatClock(req.TimeSeconds),
+		Exponent:       exponent,
+	}
+	for _, t := range targets {
+		seconds := utils.RiegelTime(float64(req.TimeSeconds), distance, t.meters, exponent)
+		perKm := roundSeconds(seconds / t.meters * 1000)
+		perMile := roundSeconds(seconds / t.meters * metersPerMile)
+		prediction.Predictions = append(prediction.Predictions, PredictedRaceRun{
+			Distance:        t.name,
+			DistanceMeters:  t.meters,
+			TimeSeconds:     roundSeconds(seconds),
+			Time:            formatClock(roundSeconds(seconds)),
+			PaceSecondsKm:   perKm,
+			PaceKm:          formatClock(perKm),
+			PaceSecondsMile: perMile,
+			PaceMile:        formatClock(perMile),
+		})
+	}
+	return prediction, nil
+}
+
+// CalculateCadence converts a pace to stride length, steps, cadence and music BPM with
+// the same models playlist generation uses
+func CalculateCadence(req types.CadenceCalcRequest) (*CadenceCalculation, error) {
+	activity, err := utils.NewActivity(req.Activity, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	unit := unitMeters(paceUnit)
+	speed := unit / float64(req.PaceSeconds)
+
+	// Step length feeds the walking and hiking models the same way it does during
+	// generation; running derives cadence from pace alone
+	input := utils.ActivityInput{
+		PaceInSeconds: req.PaceSeconds,
+		PaceUnit:      paceUnit,
+		GradePercent:  req.GradePercent,
+	}
+	strideSource := "default"
+	switch {
+	case req.StrideLengthCm > 0:
+		input.StepLength = req.StrideLengthCm / 100
+		strideSource = "strideLength"
+	case req.Height > 0:
+		input.StepLength = utils.CalculateStrideLength(req.Height, req.HeightUnit, req.Gender)
+		strideSource = "height"
+	}
+	if activity.Name() == utils.ActivityRunning {
+		strideSource = "pace"
+	}
+
+	low, high := activity.TempoBounds()
+	cadence := activity.Cadence(input)
+	targetBPM := utils.TargetBPM(activity, input)
+
+	// The length each step covers at this pace and cadence. It is the step length
+	// given for walking; on a hiking grade the same cadence covers less ground. Very
+	// slow running paces fall off the running model, so they step at the raised tempo.
+	stepCadence := cadence
+	if stepCadence <= 0 {
+		stepCadence = float64(targetBPM)
+	}
+	stride := speed * 60 / stepCadence
+
+	calculation := &CadenceCalculation{
+		PaceSeconds:          req.PaceSeconds,
+		PaceUnit:             paceUnit,
+		SpeedMetersPerSecond: roundTo(speed, 3),
+		StrideLengthMeters:   roundTo(stride, 3),
+		StrideSource:         strideSource,
+		StepsPerKm:           roundTo(1000/stride, 1),
+		StepsPerUnit:         roundTo(unit/stride, 1),
+		Activity:             activity.Name(),
+		Cadence:              int(cadence),
+		TargetBPM:            targetBPM,
+		HalfTimeBPM:          utils.CalculateTargetBPM(req.PaceSeconds, paceUnit, stride),
+		MinTempoBPM:          low,
+		MaxTempoBPM:          high,
+	}
+	switch {
+	case int(activity.BPM(cadence)) < low:
+		calculation.TargetInfo = fmt.Sprintf("raised to the %s minimum of %d BPM", activity.Name(), low)
+	case int(activity.BPM(cadence)) > high:
+		calculation.TargetInfo = fmt.Sprintf("capped at the %s maximum of %d BPM", activity.Name(), high)
+	default:
+		calculation.TargetInfo = "one beat per step"
+	}
+	return calculation, nil
+}
+
+// roundTo rounds v to the given number of decimal places
+func roundTo(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+	"time"
+)
+
+// calendarEvent is one VEVENT of an iCalendar feed
+type calendarEvent struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	URL         string
+	Updated     time.Time
+}
+
+// renderCalendar writes events as an RFC 5545 calendar. Times are written in UTC so
+// calendar apps convert them to the viewer's zone.
+func renderCalendar(name string, events []calendarEvent, now time.Time) []byte {
+	var b strings.Builder
+	writeCalendarLine(&b, "BEGIN:VCALENDAR")
+	writeCalendarLine(&b, "VERSION:2.0")
+	writeCalendarLine(&b, "PRODID:-//BeatPace//Training Plans//EN")
+	writeCalendarLine(&b, "CALSCALE:GREGORIAN")
+	writeCalendarLine(&b, "METHOD:PUBLISH")
+	writeCalendarLine(&b, "X-WR-CALNAME:"+escapeCalendarText(name))
+	for _, event := range events {
+		writeCalendarLine(&b, "BEGIN:VEVENT")
+		writeCalendarLine(&b, "UID:"+event.UID)
+		writeCalendarLine(&b, "DTSTAMP:"+calendarTime(now))
+		writeCalendarLine(&b, "DTSTART:"+calendarTime(event.Start))
+		writeCalendarLine(&b, "DTEND:"+calendarTime(event.End))
+		writeCalendarLine(&b, "LAST-MODIFIED:"+calendarTime(event.Updated))
+		writeCalendarLine(&b, "SUMMARY:"+escapeCalendarText(event.Summary))
+		if event.Description != "" {
+			writeCalendarLine(&b, "DESCRIPTION:"+escapeCalendarText(event.Description))
+		}
+		if event.URL != "" {
+			writeCalendarLine(&b, "URL:"+event.URL)
+		}
+		writeCalendarLine(&b, "END:VEVENT")
+	}
+	writeCalendarLine(&b, "END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// calendarTime formats t as an iCalendar UTC date-time
+func calendarTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeCalendarText escapes a TEXT value: backslashes, separators and newlines
+func escapeCalendarText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// writeCalendarLine writes a content line folded at 75 octets, never splitting a
+// UTF-8 sequence, and terminated by CRLF
+func writeCalendarLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/utils"
+)
+
+// Environment bounds in metric. Request bindings only bound the raw numbers, since
+// the unit they are in is not known until conversion.
+const (
+	minTemperatureC   = -50.0
+	maxTemperatureC   = 50.0
+	maxAltitudeMeters = 5500.0
+)
+
+// EnvironmentAdjustment reports how conditions changed the pace and tempo of a playlist
+type EnvironmentAdjustment struct {
+	NominalPaceSeconds  int      `json:"nominalPaceSeconds"`
+	NominalPace         string   `json:"nominalPace"`
+	AdjustedPaceSeconds int      `json:"adjustedPaceSeconds"`
+	AdjustedPace        string   `json:"adjustedPace"`
+	NominalBPM          int      `json:"nominalBpm"`
+	AdjustedBPM         int      `json:"adjustedBpm"`
+	HeatPercent         float64  `json:"heatPercent"`
+	AltitudePercent     float64  `json:"altitudePercent"`
+	TotalPercent        float64  `json:"totalPercent"`
+	DewPointC           *float64 `json:"dewPointC,omitempty"` // Dew point used, given or derived from humidity
+	Warning             string   `json:"warning,omitempty"`
+}
+
+// environmentConditions converts request conditions to metric, returning false when
+// none were given
+func environmentConditions(params types.EnvironmentParams) (utils.Conditions, bool, error) {
+	var conditions utils.Conditions
+	hasHeat := params.Temperature != nil || params.DewPoint != nil || params.Humidity != nil
+	if !hasHeat && params.Altitude == nil {
+		return conditions, false, nil
+	}
+
+	if hasHeat {
+		if params.Temperature == nil || (params.DewPoint == nil && params.Humidity == nil) {
+			return conditions, false, fmt.Errorf("%w: heat adjustment needs temperature and either dewPoint or humidity", ErrInvalidRequest)
+		}
+		toCelsius := func(v float64) float64 { return v }
+		if params.TemperatureUnit == "f" {
+			toCelsius = utils.FahrenheitToCelsius
+		}
+		temperature := toCelsius(*params.Temperature)
+		if temperature < minTemperatureC || temperature > maxTemperatureC {
+			return conditions, false, fmt.Errorf("%w: temperature must be between %.0f and %.0f °C", ErrInvalidRequest, minTemperatureC, maxTemperatureC)
+		}
+		conditions.TemperatureC = &temperature
+		if params.DewPoint != nil {
+			dewPoint := toCelsius(*params.DewPoint)
+			if dewPoint > temperature {
+				return conditions, false, fmt.Errorf("%w: dewPoint cannot be above the temperature", ErrInvalidRequest)
+			}
+			conditions.DewPointC = &dewPoint
+		}
+		conditions.HumidityPercent = params.Humidity
+	}
+
+	if params.Altitude != nil {
+		conditions.AltitudeMeters = *params.Altitude
+		if params.AltitudeUnit == "ft" {
+			conditions.AltitudeMeters = utils.FeetToMeters(*params.Altitude)
+		}
+		if conditions.AltitudeMeters > maxAltitudeMeters {
+			return conditions, false, fmt.Errorf("%w: altitude must be at most %.0f m", ErrInvalidRequest, maxAltitudeMeters)
+		}
+	}
+	return conditions, true, nil
+}
+
+func newEnvironmentAdjustment(adjustment utils.PaceAdjustment, nominalPace int, adjustedPace int, nominalBPM int, adjustedBPM int) *EnvironmentAdjustment {
+	report := &EnvironmentAdjustment{
+		NominalPaceSeconds:  nominalPace,
+		NominalPace:         formatClock(nominalPace),
+		AdjustedPaceSeconds: adjustedPace,
+		AdjustedPace:        formatClock(adjustedPace),
+		NominalBPM:          nominalBPM,
+		AdjustedBPM:         adjustedBPM,
+		HeatPercent:         roundTo(adjustment.HeatPercent, 1),
+		AltitudePercent:     roundTo(adjustment.AltitudePercent, 1),
+		TotalPercent:        roundTo(adjustment.TotalPercent, 1),
+	}
+	if adjustment.DewPointC != nil {
+		dewPoint := roundTo(*adjustment.DewPointC, 1)
+		report.DewPointC = &dewPoint
+	}
+	if adjustment.Extreme {
+		report.Warning = "conditions are beyond the heat model's range; hard running is not advised"
+	}
+	return report
+}
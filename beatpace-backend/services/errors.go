@@ -0,0 +1,13 @@
+package services
+
+import "errors"
+
+var (
+	// ErrInvalidRequest is returned (wrapped) when request parameters fail validation,
+	// so controllers can answer with 400 instead of 500
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrNotFound is returned (wrapped) when the requested resource does not exist
+	// or belongs to another user
+	ErrNotFound = errors.New("not found")
+)
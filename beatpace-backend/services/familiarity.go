@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Track labels reported for the familiarity mix
+const (
+	FamiliarityKnown = "known"
+	FamiliarityNew   = "new"
+)
+
+// Spotify's "check saved tracks" endpoint accepts at most 50 IDs per call
+const libraryContainsBatchSize = 50
+
+// FamiliarityLabel returns "known" or "new" for a track
+func FamiliarityLabel(track TrackInfo) string {
+	if track.Known {
+		return FamiliarityKnown
+	}
+	return FamiliarityNew
+}
+
+// fetchListeningHistory returns the IDs of the user's recently played and top tracks.
+// Failures are logged and ignored so that tokens missing the newer scopes still work.
+func (s *PlaylistGenerator) fetchListeningHistory(ctx context.Context, client *spotify.Client) map[spotify.ID]bool {
+	history := make(map[spotify.ID]bool)
+
+	recent, err := client.PlayerRecentlyPlayedOpt(ctx, &spotify.RecentlyPlayedOptions{Limit: 50})
+	if err != nil {
+		fmt.Printf("PlaylistGenerator: Failed to get recently played tracks: %v\n", err)
+	}
+	for _, item := range recent {
+		history[item.Track.ID] = true
+	}
+
+	top, err := client.CurrentUsersTopTracks(ctx, spotify.Limit(50), spotify.Timerange(spotify.MediumTermRange))
+	if err != nil {
+		fmt.Printf("PlaylistGenerator: Failed to get top tracks: %v\n", err)
+	} else {
+		for _, track := range top.Tracks {
+			history[track.ID] = true
+		}
+	}
+
+	return history
+}
+
+// fetchLibraryCandidates returns saved tracks not already in the candidate pool, so a
+// familiar-heavy mix has known tracks to draw on. Saved tracks were not found by a
+// tempo search, so they carry no BPM until enrichTempos measures one.
+func (s *PlaylistGenerator) fetchLibraryCandidates(ctx context.Context, client *spotify.Client, filters TrackFilters, existing map[string]TrackInfo) []TrackInfo {
+	saved, err := client.CurrentUsersTracks(ctx, spotify.Limit(50))
+	if err != nil {
+		fmt.Printf("PlaylistGenerator: Failed to get saved tracks: %v\n", err)
+		return nil
+	}
+
+	var candidates []TrackInfo
+	for _, item := range saved.Tracks {
+		if _, ok := existing[item.ID.String()]; ok {
+			continue
+		}
+		if item.Duration < 60000 || item.Duration > 600000 {
+			continue
+		}
+		if !filters.matches(item.FullTrack) {
+			continue
+		}
+		track := item.SimpleTrack
+		track.Album = item.Album
+		candidates = append(candidates, TrackInfo{
+			Track:      track,
+			Known:      true,
+			Popularity: int(item.Popularity),
+		})
+	}
+	return candidates
+}
+
+// labelKnownTracks marks tracks found in the listening history or the user's saved
+// tracks. Saved tracks are checked in batches through the library contains endpoint.
+func (s *PlaylistGenerator) labelKnownTracks(ctx context.Context, client *spotify.Client, tracks []TrackInfo, history map[spotify.ID]bool) {
+	var toCheck []int
+	for i := range tracks {
+		if tracks[i].Known {
+			continue
+		}
+		if history[tracks[i].Track.ID] {
+			tracks[i].Known = true
+			continue
+		}
+		toCheck = append(toCheck, i)
+	}
+
+	for start := 0; start < len(toCheck); start += libraryContainsBatchSize {
+		end := min(start+libraryContainsBatchSize, len(toCheck))
+		batch := toCheck[start:end]
+
+		ids := make([]spotify.ID, len(batch))
+		for i, idx := range batch {
+			ids[i] = tracks[idx].Track.ID
+		}
+
+		saved, err := client.UserHasTracks(ctx, ids...)
+		if err != nil {
+			fmt.Printf("PlaylistGenerator: Failed to check saved tracks: %v\n", err)
+			return
+		}
+		for i, idx := range batch {
+			if i < len(saved) && saved[i] {
+				tracks[idx].Known = true
+			}
+		}
+	}
+}
+
+// selectByFamiliarity picks up to limit tracks so that the fraction of known tracks
+// matches ratio. If either pool is short the playlist is shortened rather than
+// letting the mix drift.
+func selectByFamiliarity(candidates []TrackInfo, ratio float64, limit int) []TrackInfo {
+	var known, unknown []TrackInfo
+	for _, track := range candidates {
+		if track.Known {
+			known = append(known, track)
+		} else {
+			unknown = append(unknown, track)
+		}
+	}
+
+	total := float64(limit)
+	if ratio > 0 {
+		total = math.Min(total, float64(len(known))/ratio)
+	}
+	if ratio < 1 {
+		total = math.Min(total, float64(len(unknown))/(1-ratio))
+	}
+
+	wantKnown := int(math.Round(total * ratio))
+	wantNew := int(math.Floor(total)) - wantKnown
+	wantKnown = min(wantKnown, len(known))
+	wantNew = max(0, min(wantNew, len(unknown)))
+
+	fmt.Printf("PlaylistGenerator: Selecting %d known and %d new tracks (pool: %d known, %d new)\n",
+		wantKnown, wantNew, len(known), len(unknown))
+
+	// Interleave so the familiar tracks are spread across the playlist
+	selected := make([]TrackInfo, 0, wantKnown+wantNew)
+	k, n := 0, 0
+	for k < wantKnown || n < wantNew {
+		if k < wantKnown && (n >= wantNew || float64(k) <= ratio*float64(k+n)) {
+			selected = append(selected, known[k])
+			k++
+		} else {
+			selected = append(selected, unknown[n])
+			n++
+		}
+	}
+	return selected
+}
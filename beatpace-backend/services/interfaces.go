@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/types"
 	"github.com/zmb3/spotify/v2"
 )
 
@@ -27,7 +28,85 @@ type SpotifyService interface {
 	GetUserProfile(ctx context.Context, userID string) (*model.SpotifyToken, error)
 	RefreshToken(ctx context.Context, userID string) (*model.SpotifyToken, error)
 	HandleCallback(ctx context.Context, code string) (string, error)
-	GeneratePlaylistForPace(ctx context.Context, userID string, paceSeconds int, gender string, height int) (*PlaylistResponse, error)
+	GeneratePlaylistForPace(ctx context.Context, userID string, req types.GeneratePlaylistRequest) (*PlaylistResponse, error)
+	GeneratePaceLadder(ctx context.Context, userID string, req types.PaceLadderRequest) (*PaceLadderResponse, error)
+	GeneratePlaylistVariants(ctx context.Context, userID string, req types.PlaylistVariantsRequest) (*VariantSetResponse, error)
+	PublishPlaylistVariants(ctx context.Context, userID string, setID string, req types.PublishVariantsRequest) (*VariantSetResponse, error)
+	GenerateWorkoutPlaylist(ctx context.Context, userID string, req types.WorkoutPlaylistRequest) (*WorkoutPlaylistResponse, error)
+	GenerateProgressionRun(ctx context.Context, userID string, req types.ProgressionRunRequest) (*ProgressionRunResponse, error)
+	GenerateRacePlan(ctx context.Context, userID string, req types.RacePlanRequest) (*RacePlanResponse, error)
+	ImportWorkout(ctx context.Context, userID string, data []byte, form types.ImportWorkoutForm) (*ImportedWorkoutResponse, error)
+	ReplayRun(ctx context.Context, userID string, data []byte, form types.ReplayRunForm) (*ReplayRunResponse, error)
+	GenerateTreadmillPlaylist(ctx context.Context, userID string, req types.TreadmillRequest) (*TreadmillResponse, error)
 	GetClient(ctx context.Context, userID string) *spotify.Client
 	GetAuthURL() string
-} 
\ No newline at end of file
+}
+
+// PlaylistTemplateService handles per-user playlist naming templates
+type PlaylistTemplateService interface {
+	GetPlaylistTemplates(ctx context.Context, userID string) (*model.PlaylistTemplate, error)
+	SavePlaylistTemplates(ctx context.Context, userID string, nameTemplate string, descriptionTemplate string) (*model.PlaylistTemplate, error)
+}
+
+// WorkoutService handles saved workout templates
+type WorkoutService interface {
+	ListWorkouts(ctx context.Context, userID string) ([]WorkoutTemplate, error)
+	GetWorkout(ctx context.Context, userID string, id string) (*WorkoutTemplate, error)
+	CreateWorkout(ctx context.Context, userID string, req types.WorkoutTemplateRequest) (*WorkoutTemplate, error)
+	UpdateWorkout(ctx context.Context, userID string, id string, req types.WorkoutTemplateRequest) (*WorkoutTemplate, error)
+	DeleteWorkout(ctx context.Context, userID string, id string) error
+	DuplicateWorkout(ctx context.Context, userID string, id string) (*WorkoutTemplate, error)
+	GenerateFromWorkout(ctx context.Context, userID string, id string, req types.GenerateFromWorkoutRequest) (*WorkoutPlaylistResponse, error)
+}
+
+// TrainingPlanService handles training plans, session playlist generation and the
+// calendar feed
+type TrainingPlanService interface {
+	CreatePlan(ctx context.Context, userID string, req types.TrainingPlanRequest) (*TrainingPlanView, error)
+	ListPlans(ctx context.Context, userID string) ([]TrainingPlanView, error)
+	GetPlan(ctx context.Context, userID string, id string) (*TrainingPlanView, error)
+	DeletePlan(ctx context.Context, userID string, id string) error
+	GetCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error)
+	RotateCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error)
+	RenderCalendarFeed(ctx context.Context, token string) ([]byte, error)
+	GenerateDueSessions(ctx context.Context, limit int) (int, error)
+}
+
+// TrainingZoneService handles per-user training zones derived from race results
+type TrainingZoneService interface {
+	GetTrainingZones(ctx context.Context, userID string) (*TrainingZonesResponse, error)
+	SaveTrainingZones(ctx context.Context, userID string, req types.TrainingZonesRequest) (*TrainingZonesResponse, error)
+}
+
+// CadenceProgramService handles per-user cadence retraining programs
+type CadenceProgramService interface {
+	GetCadenceProgram(ctx context.Context, userID string) (*CadenceProgramResponse, error)
+	SaveCadenceProgram(ctx context.Context, userID string, req types.CadenceProgramRequest) (*CadenceProgramResponse, error)
+	AdvanceStage(ctx context.Context, userID string, note string) (*CadenceProgramResponse, error)
+	HoldStage(ctx context.Context, userID string, note string) (*CadenceProgramResponse, error)
+	DeleteCadenceProgram(ctx context.Context, userID string) error
+}
+
+// PlaylistHistoryService lists and shows the playlists a user has generated
+type PlaylistHistoryService interface {
+	ListPlaylists(ctx context.Context, userID string, query types.PlaylistListQuery) (*PlaylistHistoryPage, error)
+	GetPlaylist(ctx context.Context, userID string, id string) (*PlaylistDetail, error)
+}
+
+// TrackFeedbackService handles per-track feedback and reports how it shapes generation
+type TrackFeedbackService interface {
+	RateTrack(ctx context.Context, userID string, playlistID string, req types.TrackFeedbackRequest) (*TrackFeedbackView, error)
+	GetFeedbackImpact(ctx context.Context, userID string, query types.FeedbackImpactQuery) (*FeedbackImpactResponse, error)
+}
+
+// PreferencesService handles per-user profile and generation defaults
+type PreferencesService interface {
+	GetPreferences(ctx context.Context, userID string) (*PreferencesResponse, error)
+	SavePreferences(ctx context.Context, userID string, req types.PreferencesRequest) (*PreferencesResponse, error)
+}
+
+// AccountService handles deleting and exporting a user's whole account
+type AccountService interface {
+	DeleteAccount(ctx context.Context, userID string, unfollowPlaylists bool) (*AccountDeletionResponse, error)
+	ExportAccount(ctx context.Context, userID string) (*AccountArchive, error)
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/zmb3/spotify/v2"
+)
+
+const (
+	// maxLadderRungs caps how many playlists one ladder request may create
+	maxLadderRungs = 20
+	// defaultLadderTracks is the playlist length when the request does not set one
+	defaultLadderTracks = 10
+	// ladderTempoTolerance matches the ±10 BPM window used for single playlists
+	ladderTempoTolerance = 10
+)
+
+// LadderRung is one target pace of a pace ladder
+type LadderRung struct {
+	PaceSeconds int
+	TargetBPM   int
+}
+
+// LadderResult is the playlist generated for one rung. Playlist is nil when no tracks
+// matched or when publishing failed, in which case Err says why.
+type LadderResult struct {
+	Rung     LadderRung
+	Playlist *spotify.FullPlaylist
+	Tracks   []TrackInfo
+	Err      error
+}
+
+// PaceLadderResponse is the shape returned by GeneratePaceLadder
+type PaceLadderResponse struct {
+	PoolSize       int              `json:"poolSize"`
+	MeasuredTempos int              `json:"measuredTempos"`
+	Playlists      []LadderPlaylist `json:"playlists"`
+}
+
+// LadderPlaylist is one pace of the ladder with its playlist and coverage report
+type LadderPlaylist struct {
+	PaceSeconds int               `json:"paceSeconds"`
+	Pace        string            `json:"pace"`
+	TargetBPM   int               `json:"targetBpm"`
+	Playlist    *PlaylistResponse `json:"playlist,omitempty"`
+	Error       string            `json:"error,omitempty"` // Why this pace has no playlist, if publishing failed
+	Coverage    LadderCoverage    `json:"coverage"`
+}
+
+// LadderCoverage reports how well the shared pool covered one pace
+type LadderCoverage struct {
+	Requested    int     `json:"requested"`
+	Matched      int     `json:"matched"`
+	Measured     int     `json:"measured"`     // Tracks placed by measured tempo
+	Estimated    int     `json:"estimated"`    // Tracks without tempo data, used to fill
+	MeanBPMError float64 `json:"meanBpmError"` // Mean |tempo - target| over measured tracks
+	DurationMs   int     `json:"durationMs"`
+	Complete     bool    `json:"complete"`
+}
+
+// GeneratePaceLadder creates one playlist per pace step from a single shared candidate pool
+func (s *SpotifyServiceImpl) GeneratePaceLadder(ctx context.Context, internalUserID string, req types.PaceLadderRequest) (*PaceLadderResponse, error) {
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	rungs, err := buildLadderRungs(req.FromPaceSeconds, req.ToPaceSeconds, req.StepSeconds, paceUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	perPlaylist := req.TracksPerPlaylist
+	if perPlaylist == 0 {
+		perPlaylist = defaultLadderTracks
+	}
+
+	templates := loadPlaylistTemplates(ctx, s.templateRepo, internalUserID)
+
+	generator := s.newGenerator(ctx, internalUserID)
+	results, poolSize, err := generator.GenerateLadder(ctx, internalUserID, rungs, perPlaylist, func(rung LadderRung) PlaylistOptions {
+		return PlaylistOptions{
+			Templates: templates,
+			NameData:  NewPlaylistNameData(rung.PaceSeconds, paceUnit, rung.TargetBPM, rung.TargetBPM, req.WorkoutName),
+			Filters:   filters,
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pace ladder: %v", err)
+	}
+
+	response := &PaceLadderResponse{PoolSize: poolSize}
+	programStage := ignoredProgramStage(ctx, s.programRepo, internalUserID)
+	for _, result := range results {
+		entry := LadderPlaylist{
+			PaceSeconds: result.Rung.PaceSeconds,
+			Pace:        formatClock(result.Rung.PaceSeconds),
+			TargetBPM:   result.Rung.TargetBPM,
+			Coverage:    ladderCoverage(result, perPlaylist),
+		}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		if result.Playlist != nil {
+			generated := &GeneratedPlaylist{Playlist: result.Playlist, Tracks: result.Tracks}
+			entry.Playlist = newPlaylistResponse(generated)
+			entry.Playlist.CadenceProgramIgnoredStage = programStage
+			s.recordPlaylist(ctx, internalUserID, playlistRecord{
+				Kind:        PlaylistKindLadder,
+				PaceSeconds: result.Rung.PaceSeconds,
+				PaceUnit:    paceUnit,
+				TargetBPM:   result.Rung.TargetBPM,
+				Filters:     req.TrackFilterParams,
+				Inputs:      req,
+				Feedback:    generator.feedbackEffects(result.Tracks),
+			}, generated, entry.Playlist)
+		}
+		response.MeasuredTempos += entry.Coverage.Measured
+		response.Playlists = append(response.Playlists, entry)
+	}
+	return response, nil
+}
+
+// buildLadderRungs expands a pace range into rungs; the range may run in either direction
+func buildLadderRungs(fromPace int, toPace int, step int, paceUnit string) ([]LadderRung, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("%w: stepSeconds must be positive", ErrInvalidRequest)
+	}
+	span := toPace - fromPace
+	direction := 1
+	if span < 0 {
+		direction = -1
+		span = -span
+	}
+	count := span/step + 1
+	if count > maxLadderRungs {
+		return nil, fmt.Errorf("%w: ladder would create %d playlists, the maximum is %d", ErrInvalidRequest, count, maxLadderRungs)
+	}
+
+	rungs := make([]LadderRung, 0, count)
+	for i := 0; i < count; i++ {
+		pace := fromPace + direction*i*step
+		rungs = append(rungs, LadderRung{
+			PaceSeconds: pace,
+			TargetBPM:   targetBPMForPace(pace, paceUnit),
+		})
+	}
+	return rungs, nil
+}
+
+// GenerateLadder builds one candidate pool, enriches its tempos once, partitions it
+// across the rungs so no track is reused, and publishes a playlist per rung. A rung
+// that fails to publish records its error and the remaining rungs still run, so the
+// playlists already created are returned; the call only fails if none were.
+func (s *PlaylistGenerator) GenerateLadder(ctx context.Context, userID string, rungs []LadderRung, perPlaylist int, optsFor func(LadderRung) PlaylistOptions) ([]LadderResult, int, error) {
+	if len(rungs) == 0 {
+		return nil, 0, fmt.Errorf("no paces to generate")
+	}
+
+	// Searching takes the same time as for one playlist; publishing scales with the rungs
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second+time.Duration(len(rungs))*2*time.Second)
+	defer cancel()
+
+	client := s.spotifyService.GetClient(ctx, userID)
+	if client == nil {
+		fmt.Printf("PlaylistGenerator: Failed to get Spotify client\n")
+		return nil, 0, fmt.Errorf("failed to get spotify client")
+	}
+
+	// Seed the search at the middle of the ladder; tempos come from enrichment.
+	// Filters are the same for every rung, so any rung's options will do.
+	middle := rungs[len(rungs)/2].TargetBPM
+	pool, err := s.collectCandidates(ctx, client, middle, optsFor(rungs[0]).Filters, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.enrichTempos(ctx, client, pool)
+	pool = s.applyFeedback(pool)
+
+	assignments := partitionPool(pool, rungs, perPlaylist)
+
+	results := make([]LadderResult, len(rungs))
+	published := 0
+	var firstErr error
+	for i, rung := range rungs {
+		results[i] = LadderResult{Rung: rung, Tracks: assignments[i]}
+		if len(assignments[i]) == 0 {
+			fmt.Printf("PlaylistGenerator: No tracks for pace %d (%d BPM), skipping\n", rung.PaceSeconds, rung.TargetBPM)
+			continue
+		}
+
+		opts := optsFor(rung)
+		name, description := RenderPlaylistName(opts.Templates, opts.NameData.WithDuration(totalDurationMs(assignments[i])))
+		playlist, err := s.publishPlaylist(ctx, client, userID, name, description, assignments[i], opts.Public)
+		if err != nil {
+			fmt.Printf("PlaylistGenerator: Failed to publish pace %d (%d BPM): %v\n", rung.PaceSeconds, rung.TargetBPM, err)
+			results[i].Err = err
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		results[i].Playlist = playlist
+		published++
+	}
+	if published == 0 && firstErr != nil {
+		return nil, 0, firstErr
+	}
+
+	return results, len(pool), nil
+}
+
+// partitionPool assigns each track to at most one rung. Tracks with a measured tempo go
+// to the closest rung within tolerance, best matches first; tracks without tempo data
+// then fill the emptiest rungs, taking that rung's target as their estimated BPM.
+func partitionPool(pool []TrackInfo, rungs []LadderRung, perPlaylist int) [][]TrackInfo {
+	type match struct {
+		track    int
+		rung     int
+		distance float64
+	}
+
+	var matches []match
+	var estimated []int
+	for i, track := range pool {
+		if !track.TempoMeasured {
+			estimated = append(estimated, i)
+			continue
+		}
+		for r, rung := range rungs {
+			if d := tempoDistance(track.BPM, float64(rung.TargetBPM)); d <= ladderTempoTolerance {
+				// Feedback shifts the match order, as it does when fitting workouts
+				matches = append(matches, match{track: i, rung: r, distance: d - track.Feedback*feedbackBPMWeight})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].distance < matches[b].distance
+	})
+
+	assignments := make([][]TrackInfo, len(rungs))
+	used := make(map[int]bool)
+	for _, m := range matches {
+		if used[m.track] || len(assignments[m.rung]) >= perPlaylist {
+			continue
+		}
+		used[m.track] = true
+		assignments[m.rung] = append(assignments[m.rung], pool[m.track])
+	}
+
+	for _, i := range estimated {
+		emptiest := -1
+		for r := range rungs {
+			if len(assignments[r]) >= perPlaylist {
+				continue
+			}
+			if emptiest == -1 || len(assignments[r]) < len(assignments[emptiest]) {
+				emptiest = r
+			}
+		}
+		if emptiest == -1 {
+			break
+		}
+		track := pool[i]
+		track.BPM = float32(rungs[emptiest].TargetBPM)
+		assignments[emptiest] = append(assignments[emptiest], track)
+	}
+
+	return assignments
+}
+
+// ladderCoverage summarises how one rung was filled
+func ladderCoverage(result LadderResult, requested int) LadderCoverage {
+	coverage := LadderCoverage{
+		Requested:  requested,
+		Matched:    len(result.Tracks),
+		DurationMs: totalDurationMs(result.Tracks),
+		Complete:   result.Err == nil && len(result.Tracks) >= requested,
+	}
+
+	totalError := 0.0
+	for _, track := range result.Tracks {
+		if !track.TempoMeasured {
+			coverage.Estimated++
+			continue
+		}
+		coverage.Measured++
+		totalError += tempoDistance(track.BPM, float64(result.Rung.TargetBPM))
+	}
+	if coverage.Measured > 0 {
+		coverage.MeanBPMError = math.Round(totalError/float64(coverage.Measured)*10) / 10
+	}
+	return coverage
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// planSchedulerBatch is how many sessions one scheduler tick claims
+const planSchedulerBatch = 20
+
+// RunPlanScheduler generates training session playlists as they fall due, checking
+// every interval until ctx is cancelled. A full batch is followed straight away by
+// another, so a backlog drains without waiting for the next tick.
+func RunPlanScheduler(ctx context.Context, service TrainingPlanService, interval time.Duration) {
+	fmt.Printf("PlanScheduler: checking for due sessions every %s\n", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			claimed, err := service.GenerateDueSessions(ctx, planSchedulerBatch)
+			if err != nil {
+				fmt.Printf("PlanScheduler: failed to claim due sessions: %v\n", err)
+			}
+			if err != nil || claimed < planSchedulerBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
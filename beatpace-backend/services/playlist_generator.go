@@ -3,15 +3,16 @@ package services
 import (
 	"context"
 	"fmt"
-	"math"
 	"sync"
 	"time"
 
+	"github.com/yimango/beatpace-backend/model"
 	"github.com/zmb3/spotify/v2"
 )
 
 type PlaylistGenerator struct {
 	spotifyService SpotifyService
+	feedback       *feedbackRanking // The user's track feedback; nil leaves pools unranked
 }
 
 func NewPlaylistGenerator(spotifyService SpotifyService) *PlaylistGenerator {
@@ -20,13 +21,60 @@ func NewPlaylistGenerator(spotifyService SpotifyService) *PlaylistGenerator {
 	}
 }
 
+const (
+	// maxPlaylistTracks caps the number of tracks added to a generated playlist
+	maxPlaylistTracks = 25
+	// defaultTempoTolerance is how far in BPM a track's tempo may be from the target
+	defaultTempoTolerance = 10
+)
+
 type TrackInfo struct {
-	Track spotify.SimpleTrack
-	BPM   float32
+	Track         spotify.SimpleTrack
+	BPM           float32
+	TempoMeasured bool    // BPM comes from Spotify's audio features rather than the search target
+	Energy        float32 // Spotify energy (0-1) when audio features are available
+	Known         bool    // In the user's library or listening history
+	Popularity    int     // Spotify popularity, 0-100
+	Feedback      float64 // Ranking points from the user's feedback; positive boosts
+}
+
+// GeneratedPlaylist is the created Spotify playlist and the tracks added to it, in order
+type GeneratedPlaylist struct {
+	Playlist *spotify.FullPlaylist
+	Tracks   []TrackInfo
+}
+
+// PlaylistOptions carries the per-request settings for GeneratePlaylist
+type PlaylistOptions struct {
+	Templates *model.PlaylistTemplate // Naming templates; nil uses the defaults
+	NameData  PlaylistNameData        // Template data; Duration is filled in after track selection
+
+	// Familiarity is the target fraction of known tracks (0-1); nil leaves the mix unconstrained
+	Familiarity *float64
+
+	// Filters restricts candidates by popularity, release era and explicit content
+	Filters TrackFilters
+
+	// TempoTolerance is the accepted BPM distance from the target; nil uses the default
+	TempoTolerance *int
+
+	// Genres are searched for candidates alongside the user's top tracks
+	Genres []string
+
+	// Public publishes the playlist on the user's profile instead of privately
+	Public bool
+}
+
+// tempoTolerance returns the accepted BPM distance from the target
+func (o PlaylistOptions) tempoTolerance() int {
+	if o.TempoTolerance != nil {
+		return *o.TempoTolerance
+	}
+	return defaultTempoTolerance
 }
 
 // GeneratePlaylist creates a playlist based on the target BPM
-func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string, targetBPM int) (*spotify.FullPlaylist, error) {
+func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string, targetBPM int, opts PlaylistOptions) (*GeneratedPlaylist, error) {
 	fmt.Printf("PlaylistGenerator: Starting playlist generation for user %s with target BPM %d\n", userID, targetBPM)
 
 	// Create a context with timeout
@@ -41,6 +89,88 @@ func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string,
 	}
 	fmt.Printf("PlaylistGenerator: Successfully got Spotify client\n")
 
+	candidates, trackMap, err := s.tempoPool(ctx, client, targetBPM, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Label tracks as known or new, enforcing the requested mix if there is one
+	history := s.fetchListeningHistory(ctx, client)
+	var selected []TrackInfo
+	if opts.Familiarity != nil {
+		candidates = append(candidates, s.libraryPool(ctx, client, targetBPM, opts, trackMap)...)
+		candidates = rankByFeedback(candidates)
+		s.labelKnownTracks(ctx, client, candidates, history)
+		selected = selectByFamiliarity(candidates, *opts.Familiarity, maxPlaylistTracks)
+	} else {
+		if len(candidates) > maxPlaylistTracks {
+			candidates = candidates[:maxPlaylistTracks]
+		}
+		selected = candidates
+		s.labelKnownTracks(ctx, client, selected, history)
+	}
+
+	if len(selected) == 0 {
+		fmt.Printf("PlaylistGenerator: No suitable tracks found\n")
+		return nil, fmt.Errorf("no suitable tracks found")
+	}
+
+	name, description := RenderPlaylistName(opts.Templates, opts.NameData.WithDuration(totalDurationMs(selected)))
+	playlist, err := s.publishPlaylist(ctx, client, userID, name, description, selected, opts.Public)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GeneratedPlaylist{
+		Playlist: playlist,
+		Tracks:   selected,
+	}, nil
+}
+
+// tempoPool collects candidates, enriches their tempos and keeps those within the tempo
+// tolerance of the target, ranked by the user's feedback. The map indexes the kept
+// tracks by ID.
+func (s *PlaylistGenerator) tempoPool(ctx context.Context, client *spotify.Client, targetBPM int, opts PlaylistOptions) ([]TrackInfo, map[string]TrackInfo, error) {
+	pool, err := s.collectCandidates(ctx, client, targetBPM, opts.Filters, opts.Genres)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.enrichTempos(ctx, client, pool)
+	candidates := withinTempo(s.applyFeedback(pool), targetBPM, opts.tempoTolerance())
+
+	trackMap := make(map[string]TrackInfo)
+	for _, track := range candidates {
+		trackMap[track.Track.ID.String()] = track
+	}
+	fmt.Printf("PlaylistGenerator: Found %d unique tracks within BPM range\n", len(candidates))
+	return candidates, trackMap, nil
+}
+
+// libraryPool returns the user's saved tracks that are not in the candidate pool and
+// whose measured tempo is within the tolerance of the target, ranked by feedback
+func (s *PlaylistGenerator) libraryPool(ctx context.Context, client *spotify.Client, targetBPM int, opts PlaylistOptions, existing map[string]TrackInfo) []TrackInfo {
+	library := s.fetchLibraryCandidates(ctx, client, opts.Filters, existing)
+	s.enrichTempos(ctx, client, library)
+	library = withinTempo(s.applyFeedback(library), targetBPM, opts.tempoTolerance())
+	fmt.Printf("PlaylistGenerator: Found %d saved tracks within BPM range\n", len(library))
+	return library
+}
+
+// withinTempo keeps the tracks within tolerance BPM of the target
+func withinTempo(tracks []TrackInfo, targetBPM int, tolerance int) []TrackInfo {
+	var kept []TrackInfo
+	for _, track := range tracks {
+		if tempoDistance(track.BPM, float64(targetBPM)) <= float64(tolerance) {
+			kept = append(kept, track)
+		}
+	}
+	return kept
+}
+
+// collectCandidates searches for tracks seeded by the user's top tracks and any
+// preferred genres and returns the de-duplicated pool. BPMs are estimates until
+// enrichTempos has run.
+func (s *PlaylistGenerator) collectCandidates(ctx context.Context, client *spotify.Client, targetBPM int, filters TrackFilters, genres []string) ([]TrackInfo, error) {
 	// Create channels with appropriate buffer sizes
 	tracksChan := make(chan TrackInfo, 100)
 	errorsChan := make(chan error, 10)
@@ -126,7 +256,7 @@ func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string,
 					fmt.Printf("  Artists: %v\n", seeds.Artists)
 					fmt.Printf("  Genres: %v\n", seeds.Genres)
 
-					if tracks := s.tryGetRecommendations(ctx, client, seeds, targetBPM, 5); len(tracks) > 0 {
+					if tracks := s.tryGetRecommendations(ctx, client, seeds, targetBPM, 5, filters); len(tracks) > 0 {
 						for _, track := range tracks {
 							select {
 							case <-ctx.Done():
@@ -139,6 +269,27 @@ func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string,
 			}
 		}
 
+		// Search the preferred genres as well
+		for _, genre := range genres {
+			select {
+			case <-ctx.Done():
+				return
+			case <-rateLimiter.C:
+				wg.Add(1)
+				go func(genre string) {
+					defer wg.Done()
+					fmt.Printf("Making recommendation request for preferred genre: %s\n", genre)
+					for _, track := range s.tryGetRecommendations(ctx, client, spotify.Seeds{Genres: []string{genre}}, targetBPM, 5, filters) {
+						select {
+						case <-ctx.Done():
+							return
+						case tracksChan <- track:
+						}
+					}
+				}(genre)
+			}
+		}
+
 		// Wait for all searches to complete
 		wg.Wait()
 	}()
@@ -150,7 +301,7 @@ func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string,
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("playlist generation timed out after 30 seconds")
+			return nil, fmt.Errorf("track search timed out: %v", ctx.Err())
 		case err := <-errorsChan:
 			if err != nil {
 				errCount++
@@ -161,64 +312,74 @@ func (s *PlaylistGenerator) GeneratePlaylist(ctx context.Context, userID string,
 			}
 		case track, ok := <-tracksChan:
 			if !ok {
-				goto CREATE_PLAYLIST
-			}
-			// Accept tracks within ±10 BPM of target
-			if math.Abs(float64(targetBPM)-float64(track.BPM)) <= 10 {
-				trackMap[track.Track.ID.String()] = track
+				candidates := make([]TrackInfo, 0, len(trackMap))
+				for _, track := range trackMap {
+					candidates = append(candidates, track)
+				}
+				fmt.Printf("PlaylistGenerator: Found %d unique candidate tracks\n", len(candidates))
+				return candidates, nil
 			}
+			trackMap[track.Track.ID.String()] = track
 		}
 	}
 
-CREATE_PLAYLIST:
-	if len(trackMap) == 0 {
-		fmt.Printf("PlaylistGenerator: No suitable tracks found\n")
-		return nil, fmt.Errorf("no suitable tracks found")
-	}
+}
 
-	fmt.Printf("PlaylistGenerator: Found %d unique tracks within BPM range\n", len(trackMap))
+// publishPlaylist creates a playlist on the user's account, private unless public is
+// set, and adds the tracks in order
+func (s *PlaylistGenerator) publishPlaylist(ctx context.Context, client *spotify.Client, userID string, name string, description string, tracks []TrackInfo, public bool) (*spotify.FullPlaylist, error) {
+	// Create a rate limiter for Spotify API calls (5 requests per second)
+	rateLimiter := time.NewTicker(200 * time.Millisecond)
+	defer rateLimiter.Stop()
 
-	// Convert map to slice and limit to 25 tracks
-	var selectedTracks []spotify.ID
-	for _, track := range trackMap {
-		selectedTracks = append(selectedTracks, track.Track.ID)
-		if len(selectedTracks) >= 25 {
-			break
-		}
+	var trackIDs []spotify.ID
+	for _, track := range tracks {
+		trackIDs = append(trackIDs, track.Track.ID)
 	}
 
 	// Create a new playlist
 	<-rateLimiter.C // Rate limit
 	fmt.Printf("PlaylistGenerator: Creating new playlist...\n")
-	
+
 	// Get the Spotify user ID from the token
 	storedToken, err := s.spotifyService.GetUserProfile(ctx, userID)
 	if err != nil {
 		fmt.Printf("PlaylistGenerator: Failed to get user profile: %v\n", err)
 		return nil, fmt.Errorf("failed to get user profile: %v", err)
 	}
-	
-	playlist, err := client.CreatePlaylistForUser(ctx, storedToken.SpotifyUserID, fmt.Sprintf("BeatPace - %d BPM", targetBPM), "", false, false)
+
+	playlist, err := client.CreatePlaylistForUser(ctx, storedToken.SpotifyUserID, name, description, public, false)
 	if err != nil {
 		fmt.Printf("PlaylistGenerator: Failed to create playlist: %v\n", err)
 		return nil, fmt.Errorf("failed to create playlist: %v", err)
 	}
 	fmt.Printf("PlaylistGenerator: Created playlist with ID: %s\n", playlist.ID)
 
-	// Add tracks to the playlist
-	<-rateLimiter.C // Rate limit
-	fmt.Printf("PlaylistGenerator: Adding tracks to playlist...\n")
-	_, err = client.AddTracksToPlaylist(ctx, playlist.ID, selectedTracks...)
-	if err != nil {
-		fmt.Printf("PlaylistGenerator: Failed to add tracks to playlist: %v\n", err)
-		return nil, fmt.Errorf("failed to add tracks to playlist: %v", err)
+	// Add tracks to the playlist, 100 at a time as Spotify requires
+	for start := 0; start < len(trackIDs); start += 100 {
+		end := min(start+100, len(trackIDs))
+		<-rateLimiter.C // Rate limit
+		fmt.Printf("PlaylistGenerator: Adding tracks to playlist...\n")
+		if _, err := client.AddTracksToPlaylist(ctx, playlist.ID, trackIDs[start:end]...); err != nil {
+			fmt.Printf("PlaylistGenerator: Failed to add tracks to playlist: %v\n", err)
+			return nil, fmt.Errorf("failed to add tracks to playlist: %v", err)
+		}
 	}
-	fmt.Printf("PlaylistGenerator: Successfully added %d tracks to playlist\n", len(selectedTracks))
+	fmt.Printf("PlaylistGenerator: Successfully added %d tracks to playlist\n", len(trackIDs))
 
 	return playlist, nil
 }
 
-func (s *PlaylistGenerator) searchSimilarTracks(ctx context.Context, client *spotify.Client, seedTrack spotify.ID, targetBPM int, bpmOffset int, tracksChan chan<- TrackInfo, errorsChan chan<- error) {
+// totalDurationMs sums the track lengths in milliseconds
+func totalDurationMs(tracks []TrackInfo) int {
+	total := 0
+	for _, track := range tracks {
+		total += int(track.Track.Duration)
+	}
+	return total
+}
+
+func (s *PlaylistGenerator) searchSimilarTracks(ctx context.Context, client *spotify.Client, seedTrack spotify.ID, targetBPM int, bpmOffset int, filters TrackFilters, tracksChan chan<- TrackInfo, errorsChan chan<- error) {
 	// Get track info first to try genre seeds
 	track, err := client.GetTrack(ctx, seedTrack)
 	if err != nil {
@@ -233,7 +394,7 @@ func (s *PlaylistGenerator) searchSimilarTracks(ctx context.Context, client *spo
 			// Try 1: Genre seed
 			if tracks := s.tryGetRecommendations(ctx, client, spotify.Seeds{
 				Genres: []string{artist.Genres[0]},
-			}, targetBPM, bpmOffset); len(tracks) > 0 {
+			}, targetBPM, bpmOffset, filters); len(tracks) > 0 {
 				for _, track := range tracks {
 					select {
 					case <-ctx.Done():
@@ -248,7 +409,7 @@ func (s *PlaylistGenerator) searchSimilarTracks(ctx context.Context, client *spo
 		// Try 2: Artist seed
 		if tracks := s.tryGetRecommendations(ctx, client, spotify.Seeds{
 			Artists: []spotify.ID{track.Artists[0].ID},
-		}, targetBPM, bpmOffset); len(tracks) > 0 {
+		}, targetBPM, bpmOffset, filters); len(tracks) > 0 {
 			for _, track := range tracks {
 				select {
 				case <-ctx.Done():
@@ -263,7 +424,7 @@ func (s *PlaylistGenerator) searchSimilarTracks(ctx context.Context, client *spo
 	// Try 3: Track seed as last resort
 	if tracks := s.tryGetRecommendations(ctx, client, spotify.Seeds{
 		Tracks: []spotify.ID{seedTrack},
-	}, targetBPM, bpmOffset); len(tracks) > 0 {
+	}, targetBPM, bpmOffset, filters); len(tracks) > 0 {
 		for _, track := range tracks {
 			select {
 			case <-ctx.Done():
@@ -274,7 +435,7 @@ func (s *PlaylistGenerator) searchSimilarTracks(ctx context.Context, client *spo
 	}
 }
 
-func (s *PlaylistGenerator) searchSimilarTracksFromArtist(ctx context.Context, client *spotify.Client, artistID spotify.ID, targetBPM int, bpmOffset int, tracksChan chan<- TrackInfo, errorsChan chan<- error) {
+func (s *PlaylistGenerator) searchSimilarTracksFromArtist(ctx context.Context, client *spotify.Client, artistID spotify.ID, targetBPM int, bpmOffset int, filters TrackFilters, tracksChan chan<- TrackInfo, errorsChan chan<- error) {
 	// Get artist info to try genre seeds first
 	artist, err := client.GetArtist(ctx, artistID)
 	if err != nil {
@@ -286,7 +447,7 @@ func (s *PlaylistGenerator) searchSimilarTracksFromArtist(ctx context.Context, c
 	if len(artist.Genres) > 0 {
 		if tracks := s.tryGetRecommendations(ctx, client, spotify.Seeds{
 			Genres: []string{artist.Genres[0]},
-		}, targetBPM, bpmOffset); len(tracks) > 0 {
+		}, targetBPM, bpmOffset, filters); len(tracks) > 0 {
 			for _, track := range tracks {
 				select {
 				case <-ctx.Done():
@@ -301,7 +462,7 @@ func (s *PlaylistGenerator) searchSimilarTracksFromArtist(ctx context.Context, c
 	// Try 2: Artist seed
 	if tracks := s.tryGetRecommendations(ctx, client, spotify.Seeds{
 		Artists: []spotify.ID{artistID},
-	}, targetBPM, bpmOffset); len(tracks) > 0 {
+	}, targetBPM, bpmOffset, filters); len(tracks) > 0 {
 		for _, track := range tracks {
 			select {
 			case <-ctx.Done():
@@ -317,7 +478,7 @@ func (s *PlaylistGenerator) searchSimilarTracksFromArtist(ctx context.Context, c
 	if err == nil && len(topTracks) > 0 {
 		if tracks := s.tryGetRecommendations(ctx, client, spotify.Seeds{
 			Tracks: []spotify.ID{topTracks[0].ID},
-		}, targetBPM, bpmOffset); len(tracks) > 0 {
+		}, targetBPM, bpmOffset, filters); len(tracks) > 0 {
 			for _, track := range tracks {
 				select {
 				case <-ctx.Done():
@@ -330,7 +491,7 @@ func (s *PlaylistGenerator) searchSimilarTracksFromArtist(ctx context.Context, c
 }
 
 // Helper function to try getting recommendations with given seeds
-func (s *PlaylistGenerator) tryGetRecommendations(ctx context.Context, client *spotify.Client, seeds spotify.Seeds, targetBPM int, bpmOffset int) []TrackInfo {
+func (s *PlaylistGenerator) tryGetRecommendations(ctx context.Context, client *spotify.Client, seeds spotify.Seeds, targetBPM int, bpmOffset int, filters TrackFilters) []TrackInfo {
 	// Build search query from seeds
 	var searchQuery string
 	if len(seeds.Genres) > 0 {
@@ -354,6 +515,11 @@ func (s *PlaylistGenerator) tryGetRecommendations(ctx context.Context, client *s
 		return nil
 	}
 
+	// Narrow by release year in the query; the album date is checked again below
+	if qualifier := filters.yearQualifier(); qualifier != "" {
+		searchQuery += " " + qualifier
+	}
+
 	fmt.Printf("Searching with query: %s\n", searchQuery)
 
 	// Search for tracks with a wider range of results
@@ -383,15 +549,22 @@ func (s *PlaylistGenerator) tryGetRecommendations(ctx context.Context, client *s
 			continue
 		}
 
+		// Skip tracks outside the requested popularity and release-era range
+		if !filters.matches(track) {
+			continue
+		}
+
 		// Add track to results
 		filteredTracks = append(filteredTracks, TrackInfo{
 			Track: spotify.SimpleTrack{
 				ID:       track.ID,
 				Name:     track.Name,
 				Artists:  track.Artists,
+				Album:    track.Album,
 				Duration: track.Duration,
 			},
-			BPM: float32(targetBPM), // Use target BPM since we can't get actual BPM
+			BPM:        float32(targetBPM), // Use target BPM since we can't get actual BPM
+			Popularity: int(track.Popularity),
 		})
 		fmt.Printf("Added track: %s (Duration: %d ms)\n",
 			track.Name, track.Duration)
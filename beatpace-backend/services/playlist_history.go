@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// Playlist kinds recorded in the history, one per generation mode
+const (
+	PlaylistKindPace        = "pace"
+	PlaylistKindLadder      = "ladder"
+	PlaylistKindVariant     = "variant"
+	PlaylistKindWorkout     = "workout"
+	PlaylistKindProgression = "progression"
+	PlaylistKindRace        = "race"
+	PlaylistKindReplay      = "replay"
+	PlaylistKindTreadmill   = "treadmill"
+)
+
+// defaultHistoryPageSize is the number of playlists returned per page when no limit is given
+const defaultHistoryPageSize = 20
+
+// PlaylistHistoryPage is one page of a user's playlist history, newest first
+type PlaylistHistoryPage struct {
+	Playlists  []PlaylistSummary `json:"playlists"`
+	NextCursor string            `json:"nextCursor,omitempty"` // Absent on the last page
+}
+
+// PlaylistSummary is a generated playlist without its tracks
+type PlaylistSummary struct {
+	ID                string    `json:"id"`
+	Kind              string    `json:"kind"`
+	Name              string    `json:"name"`
+	URL               string    `json:"url"`
+	SpotifyPlaylistID string    `json:"spotifyPlaylistId"`
+	PaceSeconds       *int      `json:"paceSeconds,omitempty"`
+	PaceUnit          string    `json:"paceUnit"`
+	Activity          string    `json:"activity"`
+	Cadence           *int      `json:"cadence,omitempty"`
+	TargetBPM         int       `json:"targetBpm"`
+	TrackCount        int       `json:"trackCount"`
+	DurationMs        int       `json:"durationMs"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// PlaylistDetail is a generated playlist with the inputs it was built from and its tracks
+type PlaylistDetail struct {
+	PlaylistSummary
+	Filters json.RawMessage        `json:"filters"`
+	Inputs  json.RawMessage        `json:"inputs"` // The generation request as it was received
+	Tracks  []PlaylistHistoryTrack `json:"tracks"`
+}
+
+// PlaylistHistoryTrack is one stored track of a generated playlist
+type PlaylistHistoryTrack struct {
+	Position   int      `json:"position"`
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Artists    []string `json:"artists"`
+	URL        string   `json:"url"`
+	BPM        float32  `json:"bpm"`
+	Measured   bool     `json:"tempoMeasured"`
+	DurationMs int      `json:"durationMs"`
+}
+
+// playlistRecord describes how a playlist was generated, for the history
+type playlistRecord struct {
+	Kind        string
+	PaceSeconds int // Zero when the tempo is not pace based
+	PaceUnit    string
+	Activity    string
+	Cadence     int // Zero when not known
+	TargetBPM   int
+	Filters     types.TrackFilterParams
+	Inputs      any // The request, stored as JSON
+	Feedback    []feedbackEffect
+}
+
+// recordPlaylist stores a published playlist in the user's history and sets the
+// response's history ID. The playlist already exists on Spotify, so failures are
+// logged rather than failing the request.
+func (s *SpotifyServiceImpl) recordPlaylist(ctx context.Context, internalUserID string, record playlistRecord, generated *GeneratedPlaylist, response *PlaylistResponse) {
+	playlist, tracks, err := newPlaylistModel(internalUserID, record, generated)
+	if err == nil {
+		err = s.playlistRepo.CreatePlaylist(ctx, playlist, tracks, newFeedbackEffectModels(playlist.ID, record.Feedback))
+	}
+	if err != nil {
+		fmt.Printf("Failed to record playlist %s: %v\n", generated.Playlist.ID, err)
+		return
+	}
+	response.HistoryID = playlist.ID.String()
+}
+
+func newPlaylistModel(internalUserID string, record playlistRecord, generated *GeneratedPlaylist) (*model.Playlist, []model.PlaylistTrack, error) {
+	uid, err := uuid.Parse(internalUserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+	filters, err := json.Marshal(record.Filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode filters: %v", err)
+	}
+	inputs, err := json.Marshal(record.Inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode inputs: %v", err)
+	}
+
+	activity := record.Activity
+	if activity == "" {
+		activity = "running"
+	}
+	playlist := &model.Playlist{
+		ID:                uuid.New(),
+		UserID:            uid,
+		Kind:              record.Kind,
+		SpotifyPlaylistID: generated.Playlist.ID.String(),
+		URL:               fmt.Sprintf("https://open.spotify.com/playlist/%s", generated.Playlist.ID),
+		Name:              generated.Playlist.Name,
+		PaceUnit:          normalizePaceUnit(record.PaceUnit),
+		Activity:          activity,
+		TargetBPM:         record.TargetBPM,
+		Filters:           string(filters),
+		Inputs:            string(inputs),
+		TrackCount:        len(generated.Tracks),
+		DurationMs:        totalDurationMs(generated.Tracks),
+		// The history cursor compares timestamps, so keep only what the column stores
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if record.PaceSeconds > 0 {
+		playlist.PaceSeconds = &record.PaceSeconds
+	}
+	if record.Cadence > 0 {
+		playlist.Cadence = &record.Cadence
+	}
+
+	tracks := make([]model.PlaylistTrack, 0, len(generated.Tracks))
+	for position, track := range generated.Tracks {
+		item := newPlaylistTrack(track)
+		artists, err := json.Marshal(item.Artists)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode artists: %v", err)
+		}
+		tracks = append(tracks, model.PlaylistTrack{
+			PlaylistID:     playlist.ID,
+			Position:       position,
+			SpotifyTrackID: item.ID,
+			Name:           truncateRunes(item.Name, 255),
+			Artists:        string(artists),
+			BPM:            item.BPM,
+			TempoMeasured:  item.Measured,
+			DurationMs:     item.DurationMs,
+		})
+	}
+	return playlist, tracks, nil
+}
+
+func newFeedbackEffectModels(playlistID uuid.UUID, effects []feedbackEffect) []model.PlaylistFeedbackEffect {
+	models := make([]model.PlaylistFeedbackEffect, 0, len(effects))
+	for _, effect := range effects {
+		models = append(models, model.PlaylistFeedbackEffect{
+			PlaylistID:     playlistID,
+			SpotifyTrackID: effect.TrackID,
+			Name:           truncateRunes(effect.Name, 255),
+			Score:          effect.Signal.Score,
+			TempoTrust:     effect.Signal.TempoTrust,
+			Included:       effect.Included,
+			Excluded:       effect.Excluded,
+		})
+	}
+	return models
+}
+
+type playlistHistoryService struct {
+	playlistRepo repository.PlaylistRepository
+}
+
+func NewPlaylistHistoryService(playlistRepo repository.PlaylistRepository) PlaylistHistoryService {
+	return &playlistHistoryService{
+		playlistRepo: playlistRepo,
+	}
+}
+
+// ListPlaylists returns one page of the user's generated playlists, newest first
+func (s *playlistHistoryService) ListPlaylists(ctx context.Context, userID string, query types.PlaylistListQuery) (*PlaylistHistoryPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultHistoryPageSize
+	}
+	filter := repository.PlaylistListFilter{
+		MinBPM: query.MinBPM,
+		MaxBPM: query.MaxBPM,
+		Limit:  limit + 1, // One extra shows whether there is another page
+	}
+	if query.MinBPM != nil && query.MaxBPM != nil && *query.MinBPM > *query.MaxBPM {
+		return nil, fmt.Errorf("%w: minBpm must not exceed maxBpm", ErrInvalidRequest)
+	}
+
+	var err error
+	if query.Cursor != "" {
+		var id uuid.UUID
+		if filter.AfterCreatedAt, id, err = decodeHistoryCursor(query.Cursor); err != nil {
+			return nil, err
+		}
+		filter.AfterID = &id
+	}
+	if query.From != "" {
+		from, _, err := parseHistoryTime("from", query.From)
+		if err != nil {
+			return nil, err
+		}
+		filter.From = &from
+	}
+	if query.To != "" {
+		to, isDate, err := parseHistoryTime("to", query.To)
+		if err != nil {
+			return nil, err
+		}
+		if isDate {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	playlists, err := s.playlistRepo.ListPlaylists(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &PlaylistHistoryPage{Playlists: []PlaylistSummary{}}
+	if len(playlists) > limit {
+		playlists = playlists[:limit]
+		last := playlists[limit-1]
+		page.NextCursor = encodeHistoryCursor(last.CreatedAt, last.ID)
+	}
+	for _, playlist := range playlists {
+		page.Playlists = append(page.Playlists, newPlaylistSummary(&playlist))
+	}
+	return page, nil
+}
+
+// GetPlaylist returns one of the user's generated playlists with its inputs and tracks
+func (s *playlistHistoryService) GetPlaylist(ctx context.Context, userID string, id string) (*PlaylistDetail, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("%w: playlist %s", ErrNotFound, id)
+	}
+	playlist, tracks, err := s.playlistRepo.GetPlaylist(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: playlist %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newPlaylistDetail(playlist, tracks)
+}
+
+func newPlaylistDetail(playlist *model.Playlist, tracks []model.PlaylistTrack) (*PlaylistDetail, error) {
+	detail := &PlaylistDetail{
+		PlaylistSummary: newPlaylistSummary(playlist),
+		Filters:         json.RawMessage(playlist.Filters),
+		Inputs:          json.RawMessage(playlist.Inputs),
+		Tracks:          []PlaylistHistoryTrack{},
+	}
+	for _, track := range tracks {
+		var artists []string
+		if err := json.Unmarshal([]byte(track.Artists), &artists); err != nil {
+			return nil, fmt.Errorf("failed to decode artists: %v", err)
+		}
+		detail.Tracks = append(detail.Tracks, PlaylistHistoryTrack{
+			Position:   track.Position,
+			ID:         track.SpotifyTrackID,
+			Name:       track.Name,
+			Artists:    artists,
+			URL:        fmt.Sprintf("https://open.spotify.com/track/%s", track.SpotifyTrackID),
+			BPM:        track.BPM,
+			Measured:   track.TempoMeasured,
+			DurationMs: track.DurationMs,
+		})
+	}
+	return detail, nil
+}
+
+func newPlaylistSummary(playlist *model.Playlist) PlaylistSummary {
+	return PlaylistSummary{
+		ID:                playlist.ID.String(),
+		Kind:              playlist.Kind,
+		Name:              playlist.Name,
+		URL:               playlist.URL,
+		SpotifyPlaylistID: playlist.SpotifyPlaylistID,
+		PaceSeconds:       playlist.PaceSeconds,
+		PaceUnit:          playlist.PaceUnit,
+		Activity:          playlist.Activity,
+		Cadence:           playlist.Cadence,
+		TargetBPM:         playlist.TargetBPM,
+		TrackCount:        playlist.TrackCount,
+		DurationMs:        playlist.DurationMs,
+		CreatedAt:         playlist.CreatedAt,
+	}
+}
+
+// encodeHistoryCursor makes an opaque cursor from the last playlist on a page
+func encodeHistoryCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeHistoryCursor(cursor string) (time.Time, uuid.UUID, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidRequest)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	return t, parsed, nil
+}
+
+// parseHistoryTime accepts a date or an RFC 3339 timestamp and reports which it was.
+// Dates are midnight UTC.
+func parseHistoryTime(field string, value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %s must be a date (YYYY-MM-DD) or an RFC 3339 timestamp", ErrInvalidRequest, field)
+	}
+	return t.UTC(), false, nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+)
+
+// Spotify rejects playlist names and descriptions beyond these lengths
+const (
+	maxPlaylistNameLength        = 100
+	maxPlaylistDescriptionLength = 300
+)
+
+// renderLimitFactor bounds template output at this many times the final length, so
+// a template cannot build an arbitrarily large string before it is truncated
+const renderLimitFactor = 4
+
+// errTemplateOutputTooLong is returned when a template writes past its render limit
+var errTemplateOutputTooLong = errors.New("template output is too long")
+
+// Default templates, matching the name BeatPace has always used
+const (
+	DefaultPlaylistNameTemplate        = "BeatPace - {{.BPM}} BPM"
+	DefaultPlaylistDescriptionTemplate = ""
+)
+
+// PlaylistNameData is the data available to playlist name and description templates
+type PlaylistNameData struct {
+	Pace            string // Pace formatted as m:ss, e.g. "5:00"
+	PaceSeconds     int    // Pace in seconds per unit
+	Unit            string // "km" or "mile"
+	Cadence         int    // Target cadence in steps per minute
+	BPM             int    // Target music BPM
+	WorkoutName     string // Optional workout name from the request
+	Date            string // Generation date, e.g. "2024-05-01"
+	Duration        string // Total playlist length formatted as h:mm:ss or m:ss
+	DurationMinutes int    // Total playlist length in whole minutes
+}
+
+// samplePlaylistNameData is used to validate templates before they are saved
+var samplePlaylistNameData = PlaylistNameData{
+	Pace:            "5:00",
+	PaceSeconds:     300,
+	Unit:            "km",
+	Cadence:         170,
+	BPM:             170,
+	WorkoutName:     "Tempo Tuesday",
+	Date:            "2024-01-01",
+	Duration:        "45:00",
+	DurationMinutes: 45,
+}
+
+type playlistTemplateService struct {
+	templateRepo repository.PlaylistTemplateRepository
+}
+
+func NewPlaylistTemplateService(templateRepo repository.PlaylistTemplateRepository) PlaylistTemplateService {
+	return &playlistTemplateService{
+		templateRepo: templateRepo,
+	}
+}
+
+// GetPlaylistTemplates returns the user's templates, or the defaults if none are stored
+func (s *playlistTemplateService) GetPlaylistTemplates(ctx context.Context, userID string) (*model.PlaylistTemplate, error) {
+	return storedPlaylistTemplates(ctx, s.templateRepo, userID)
+}
+
+// SavePlaylistTemplates validates and stores the user's templates
+func (s *playlistTemplateService) SavePlaylistTemplates(ctx context.Context, userID string, nameTemplate string, descriptionTemplate string) (*model.PlaylistTemplate, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	if err := ValidatePlaylistTemplates(nameTemplate, descriptionTemplate); err != nil {
+		return nil, err
+	}
+
+	templates := &model.PlaylistTemplate{
+		UserID:              uid,
+		NameTemplate:        nameTemplate,
+		DescriptionTemplate: descriptionTemplate,
+		UpdatedAt:           time.Now(),
+	}
+	if err := s.templateRepo.SavePlaylistTemplate(ctx, templates); err != nil {
+		return nil, fmt.Errorf("failed to save playlist template: %v", err)
+	}
+	return templates, nil
+}
+
+// loadPlaylistTemplates returns the templates to name a new playlist with. A failed
+// lookup is logged and yields nil, which RenderPlaylistName treats as the defaults,
+// so naming never fails a generation request.
+func loadPlaylistTemplates(ctx context.Context, templateRepo repository.PlaylistTemplateRepository, userID string) *model.PlaylistTemplate {
+	templates, err := storedPlaylistTemplates(ctx, templateRepo, userID)
+	if err != nil {
+		fmt.Printf("Failed to load playlist templates: %v\n", err)
+		return nil
+	}
+	return templates
+}
+
+// storedPlaylistTemplates falls back to the default templates when the user has not set any
+func storedPlaylistTemplates(ctx context.Context, templateRepo repository.PlaylistTemplateRepository, userID string) (*model.PlaylistTemplate, error) {
+	templates, err := templateRepo.GetPlaylistTemplate(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &model.PlaylistTemplate{
+			NameTemplate:        DefaultPlaylistNameTemplate,
+			DescriptionTemplate: DefaultPlaylistDescriptionTemplate,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ValidatePlaylistTemplates checks that both templates parse and render against sample data
+func ValidatePlaylistTemplates(nameTemplate string, descriptionTemplate string) error {
+	if err := checkPlaylistTemplateActions(nameTemplate); err != nil {
+		return fmt.Errorf("invalid name template: %v", err)
+	}
+	if err := checkPlaylistTemplateActions(descriptionTemplate); err != nil {
+		return fmt.Errorf("invalid description template: %v", err)
+	}
+
+	name, err := renderPlaylistTemplate(nameTemplate, samplePlaylistNameData, maxPlaylistNameLength)
+	if err != nil {
+		return fmt.Errorf("invalid name template: %v", err)
+	}
+	if name == "" {
+		return fmt.Errorf("invalid name template: renders to an empty name")
+	}
+	if _, err := renderPlaylistTemplate(descriptionTemplate, samplePlaylistNameData, maxPlaylistDescriptionLength); err != nil {
+		return fmt.Errorf("invalid description template: %v", err)
+	}
+	return nil
+}
+
+// RenderPlaylistName renders the name and description for a playlist, falling back
+// to the default name if the stored template can no longer be rendered
+func RenderPlaylistName(templates *model.PlaylistTemplate, data PlaylistNameData) (string, string) {
+	nameTemplate := DefaultPlaylistNameTemplate
+	descriptionTemplate := DefaultPlaylistDescriptionTemplate
+	if templates != nil {
+		nameTemplate = templates.NameTemplate
+		descriptionTemplate = templates.DescriptionTemplate
+	}
+
+	name, err := renderPlaylistTemplate(nameTemplate, data, maxPlaylistNameLength)
+	if err != nil || name == "" {
+		fmt.Printf("RenderPlaylistName: falling back to default name: %v\n", err)
+		name, _ = renderPlaylistTemplate(DefaultPlaylistNameTemplate, data, maxPlaylistNameLength)
+	}
+
+	description, err := renderPlaylistTemplate(descriptionTemplate, data, maxPlaylistDescriptionLength)
+	if err != nil {
+		fmt.Printf("RenderPlaylistName: dropping description: %v\n", err)
+		description = ""
+	}
+
+	return name, description
+}
+
+// renderPlaylistTemplate executes a template, collapses whitespace (Spotify drops
+// newlines) and truncates the result to maxLength characters
+func renderPlaylistTemplate(text string, data PlaylistNameData, maxLength int) (string, error) {
+	tmpl, err := template.New("playlist").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &limitedBuffer{limit: maxLength * renderLimitFactor}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+
+	rendered := strings.Join(strings.Fields(buf.String()), " ")
+	return strings.TrimSpace(truncateRunes(rendered, maxLength)), nil
+}
+
+// checkPlaylistTemplateActions rejects the actions that can repeat or nest output.
+// Names only need fields, conditionals and pipelines; range over a number such as
+// {{.BPM}} and nested template calls grow the output exponentially.
+func checkPlaylistTemplateActions(text string) error {
+	tmpl, err := template.New("playlist").Parse(text)
+	if err != nil {
+		return err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return fmt.Errorf("define and block are not allowed")
+	}
+	if tmpl.Tree == nil {
+		return nil
+	}
+	return checkTemplateNodes(tmpl.Tree.Root)
+}
+
+func checkTemplateNodes(list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.RangeNode:
+			return fmt.Errorf("range is not allowed")
+		case *parse.TemplateNode:
+			return fmt.Errorf("template is not allowed")
+		case *parse.IfNode:
+			if err := checkTemplateNodes(n.List); err != nil {
+				return err
+			}
+			if err := checkTemplateNodes(n.ElseList); err != nil {
+				return err
+			}
+		case *parse.WithNode:
+			if err := checkTemplateNodes(n.List); err != nil {
+				return err
+			}
+			if err := checkTemplateNodes(n.ElseList); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// limitedBuffer is a bytes.Buffer that fails writes past limit bytes
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputTooLong
+	}
+	return b.Buffer.Write(p)
+}
+
+// truncateRunes shortens s to at most max characters
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// NewPlaylistNameData builds the template data known before tracks are selected
+func NewPlaylistNameData(paceSeconds int, unit string, cadence int, bpm int, workoutName string) PlaylistNameData {
+	return PlaylistNameData{
+		Pace:        formatClock(paceSeconds),
+		PaceSeconds: paceSeconds,
+		Unit:        unit,
+		Cadence:     cadence,
+		BPM:         bpm,
+		WorkoutName: workoutName,
+		Date:        time.Now().Format("2006-01-02"),
+	}
+}
+
+// WithDuration returns a copy of the data with the playlist length filled in
+func (d PlaylistNameData) WithDuration(totalMs int) PlaylistNameData {
+	d.Duration = formatClock(totalMs / 1000)
+	d.DurationMinutes = totalMs / 60000
+	return d
+}
+
+// formatClock formats seconds as h:mm:ss, or m:ss below an hour
+func formatClock(totalSeconds int) string {
+	hours := totalSeconds / 3600
+	minutes := (totalSeconds % 3600) / 60
+	seconds := totalSeconds % 60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
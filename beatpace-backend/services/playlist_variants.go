@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// Variant strategies
+const (
+	VariantFamiliar   = "familiar"
+	VariantDiscovery  = "discovery"
+	VariantHighEnergy = "high-energy"
+	VariantRandom     = "random"
+)
+
+const (
+	// familiarVariantRatio is the known-track fraction used by the familiar strategy
+	familiarVariantRatio = 0.8
+	// variantDraftTTL is how long unpublished variants can still be published
+	variantDraftTTL = time.Hour
+	// maxVariantDraftsPerUser bounds the drafts kept for one user; generating more
+	// drops the oldest
+	maxVariantDraftsPerUser = 5
+)
+
+// VariantSetResponse is the shape returned when variants are generated or published
+type VariantSetResponse struct {
+	ID        string            `json:"id"`
+	TargetBPM int               `json:"targetBpm"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	Variants  []PlaylistVariant `json:"variants"`
+	Overlap   []VariantOverlap  `json:"overlap"`
+
+	// CadenceProgramStage is set when the target came from the user's cadence program
+	CadenceProgramStage *int `json:"cadenceProgramStage,omitempty"`
+}
+
+// PlaylistVariant is one candidate playlist; URL is set once it has been published
+type PlaylistVariant struct {
+	ID            string          `json:"id"`
+	Strategy      string          `json:"strategy"`
+	Seed          int64           `json:"seed"`
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	KnownCount    int             `json:"knownCount"`
+	NewCount      int             `json:"newCount"`
+	AvgPopularity float64         `json:"avgPopularity"`
+	Tracks        []PlaylistTrack `json:"tracks"`
+	Published     bool            `json:"published"`
+	URL           string          `json:"url,omitempty"`
+	HistoryID     string          `json:"historyId,omitempty"` // Set once published and recorded
+}
+
+// VariantOverlap compares the track lists of two variants
+type VariantOverlap struct {
+	A       string  `json:"a"`
+	B       string  `json:"b"`
+	Shared  int     `json:"shared"`
+	Jaccard float64 `json:"jaccard"` // Shared tracks over the union of both lists
+}
+
+// variantSpec is a validated VariantSpec with its seed resolved
+type variantSpec struct {
+	ID       string
+	Strategy string
+	Seed     int64
+}
+
+// variantDraft holds generated variants until the user publishes or they expire
+type variantDraft struct {
+	mu        sync.Mutex // Serialises publishing so a variant is never created twice
+	userID    string
+	response  *VariantSetResponse
+	tracks    map[string][]TrackInfo
+	record    playlistRecord              // How the variants were generated, for the history
+	effects   map[string][]feedbackEffect // What feedback did to each variant
+	expiresAt time.Time
+}
+
+// variantDraftStore keeps unpublished variants in memory, keyed by variant set ID.
+// Drafts live in this process only, so publishing must reach the instance that
+// generated them.
+type variantDraftStore struct {
+	mu     sync.Mutex
+	drafts map[string]*variantDraft
+}
+
+func newVariantDraftStore() *variantDraftStore {
+	return &variantDraftStore{drafts: make(map[string]*variantDraft)}
+}
+
+func (st *variantDraftStore) put(id string, draft *variantDraft) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.purgeExpired()
+
+	var userDrafts []string
+	for existingID, existing := range st.drafts {
+		if existing.userID == draft.userID {
+			userDrafts = append(userDrafts, existingID)
+		}
+	}
+	if len(userDrafts) >= maxVariantDraftsPerUser {
+		sort.Slice(userDrafts, func(i, j int) bool {
+			return st.drafts[userDrafts[i]].expiresAt.Before(st.drafts[userDrafts[j]].expiresAt)
+		})
+		for _, oldID := range userDrafts[:len(userDrafts)-maxVariantDraftsPerUser+1] {
+			delete(st.drafts, oldID)
+		}
+	}
+	st.drafts[id] = draft
+}
+
+// get returns the draft only if it exists, has not expired and belongs to userID
+func (st *variantDraftStore) get(id string, userID string) (*variantDraft, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.purgeExpired()
+	draft, ok := st.drafts[id]
+	if !ok || draft.userID != userID {
+		return nil, false
+	}
+	return draft, true
+}
+
+func (st *variantDraftStore) purgeExpired() {
+	now := time.Now()
+	for id, draft := range st.drafts {
+		if now.After(draft.expiresAt) {
+			delete(st.drafts, id)
+		}
+	}
+}
+
+// GeneratePlaylistVariants builds several track lists at one target tempo from a shared
+// pool. Nothing is published to Spotify until PublishPlaylistVariants is called.
+func (s *SpotifyServiceImpl) GeneratePlaylistVariants(ctx context.Context, internalUserID string, req types.PlaylistVariantsRequest) (*VariantSetResponse, error) {
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	targetBPM := targetBPMForPace(req.PaceInSeconds, paceUnit)
+
+	// An active cadence program sets the target, as for single playlists
+	var programStage *int
+	if !req.IgnoreCadenceProgram {
+		programCadence, stage, ok, err := currentProgramCadence(ctx, s.programRepo, internalUserID)
+		if err != nil {
+			fmt.Printf("Failed to load cadence program: %v\n", err)
+		} else if ok {
+			// Variants use the running model, one beat per step
+			targetBPM = programCadence
+			programStage = &stage
+		}
+	}
+
+	specs := make([]variantSpec, len(req.Variants))
+	for i, v := range req.Variants {
+		seed := time.Now().UnixNano() + int64(i)
+		if v.Seed != nil {
+			seed = *v.Seed
+		}
+		specs[i] = variantSpec{
+			ID:       fmt.Sprintf("%s-%d", v.Strategy, i+1),
+			Strategy: v.Strategy,
+			Seed:     seed,
+		}
+	}
+
+	templates := loadPlaylistTemplates(ctx, s.templateRepo, internalUserID)
+
+	generator := s.newGenerator(ctx, internalUserID)
+	selections, err := generator.GenerateVariants(ctx, internalUserID, targetBPM, specs, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate variants: %v", err)
+	}
+
+	response := &VariantSetResponse{
+		ID:                  uuid.New().String(),
+		TargetBPM:           targetBPM,
+		ExpiresAt:           time.Now().Add(variantDraftTTL),
+		CadenceProgramStage: programStage,
+	}
+	draft := &variantDraft{
+		userID:    internalUserID,
+		response:  response,
+		tracks:    make(map[string][]TrackInfo),
+		effects:   make(map[string][]feedbackEffect),
+		expiresAt: response.ExpiresAt,
+		record: playlistRecord{
+			Kind:        PlaylistKindVariant,
+			PaceSeconds: req.PaceInSeconds,
+			PaceUnit:    paceUnit,
+			TargetBPM:   targetBPM,
+			Filters:     req.TrackFilterParams,
+			Inputs:      req,
+		},
+	}
+
+	nameData := NewPlaylistNameData(req.PaceInSeconds, paceUnit, targetBPM, targetBPM, req.WorkoutName)
+	for i, spec := range specs {
+		tracks := selections[i]
+		name, description := RenderPlaylistName(templates, nameData.WithDuration(totalDurationMs(tracks)))
+		if len(specs) > 1 {
+			// Tell the variants apart in the user's Spotify library
+			name = truncateRunes(fmt.Sprintf("%s (%s)", name, spec.Strategy), maxPlaylistNameLength)
+		}
+
+		variant := PlaylistVariant{
+			ID:          spec.ID,
+			Strategy:    spec.Strategy,
+			Seed:        spec.Seed,
+			Name:        name,
+			Description: description,
+		}
+		popularity := 0
+		for _, track := range tracks {
+			if track.Known {
+				variant.KnownCount++
+			} else {
+				variant.NewCount++
+			}
+			popularity += track.Popularity
+			variant.Tracks = append(variant.Tracks, newPlaylistTrack(track))
+		}
+		if len(tracks) > 0 {
+			variant.AvgPopularity = math.Round(float64(popularity)/float64(len(tracks))*10) / 10
+		}
+
+		response.Variants = append(response.Variants, variant)
+		draft.tracks[spec.ID] = tracks
+		draft.effects[spec.ID] = generator.feedbackEffects(tracks)
+	}
+	response.Overlap = variantOverlap(specs, selections)
+
+	// The draft's response changes as variants are published, so the caller gets a copy
+	result := response.clone()
+	s.drafts.put(response.ID, draft)
+	return result, nil
+}
+
+// PublishPlaylistVariants publishes the chosen variants of a set to Spotify, publicly
+// when the request or the stored preferences ask for it. Variants that were already
+// published are left as they are.
+func (s *SpotifyServiceImpl) PublishPlaylistVariants(ctx context.Context, internalUserID string, setID string, req types.PublishVariantsRequest) (*VariantSetResponse, error) {
+	draft, ok := s.drafts.get(setID, internalUserID)
+	if !ok {
+		return nil, fmt.Errorf("variant set %s %w (drafts expire after %s)", setID, ErrNotFound, variantDraftTTL)
+	}
+
+	variantIDs := req.Variants
+	for _, id := range variantIDs {
+		if _, ok := draft.tracks[id]; !ok {
+			return nil, fmt.Errorf("%w: unknown variant %q", ErrInvalidRequest, id)
+		}
+	}
+
+	public := false
+	if req.Public != nil {
+		public = *req.Public
+	} else if prefs, err := loadPreferences(ctx, s.prefsRepo, internalUserID); err != nil {
+		fmt.Printf("Failed to load preferences: %v\n", err)
+	} else if prefs != nil {
+		public = prefs.PlaylistPublic
+	}
+
+	draft.mu.Lock()
+	defer draft.mu.Unlock()
+
+	generator := NewPlaylistGenerator(s)
+	for _, id := range variantIDs {
+		for i := range draft.response.Variants {
+			variant := &draft.response.Variants[i]
+			if variant.ID != id || variant.Published {
+				continue
+			}
+			if len(draft.tracks[id]) == 0 {
+				return nil, fmt.Errorf("%w: variant %q has no tracks", ErrInvalidRequest, id)
+			}
+
+			published, err := generator.Publish(ctx, internalUserID, variant.Name, variant.Description, draft.tracks[id], public)
+			if err != nil {
+				return nil, fmt.Errorf("failed to publish variant %s: %v", id, err)
+			}
+			variant.Published = true
+			variant.URL = fmt.Sprintf("https://open.spotify.com/playlist/%s", published.Playlist.ID)
+
+			record := draft.record
+			record.Feedback = draft.effects[id]
+			recorded := &PlaylistResponse{}
+			s.recordPlaylist(ctx, internalUserID, record, published, recorded)
+			variant.HistoryID = recorded.HistoryID
+		}
+	}
+
+	// Copied under the lock, so a concurrent publish cannot change it while it is
+	// being serialised
+	return draft.response.clone(), nil
+}
+
+// clone returns a deep copy of the response
+func (r *VariantSetResponse) clone() *VariantSetResponse {
+	copied := *r
+	copied.Variants = make([]PlaylistVariant, len(r.Variants))
+	for i, variant := range r.Variants {
+		variant.Tracks = append([]PlaylistTrack(nil), variant.Tracks...)
+		copied.Variants[i] = variant
+	}
+	copied.Overlap = append([]VariantOverlap(nil), r.Overlap...)
+	return &copied
+}
+
+// GenerateVariants collects and enriches one pool at the target tempo, labels known
+// tracks once, and selects a track list per spec without publishing anything
+func (s *PlaylistGenerator) GenerateVariants(ctx context.Context, userID string, targetBPM int, specs []variantSpec, filters TrackFilters) ([][]TrackInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client := s.spotifyService.GetClient(ctx, userID)
+	if client == nil {
+		fmt.Printf("PlaylistGenerator: Failed to get Spotify client\n")
+		return nil, fmt.Errorf("failed to get spotify client")
+	}
+
+	candidates, trackMap, err := s.tempoPool(ctx, client, targetBPM, PlaylistOptions{Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+
+	// The familiar strategy needs known tracks to draw on
+	var library []TrackInfo
+	for _, spec := range specs {
+		if spec.Strategy == VariantFamiliar {
+			library = s.libraryPool(ctx, client, targetBPM, PlaylistOptions{Filters: filters}, trackMap)
+			break
+		}
+	}
+	s.labelKnownTracks(ctx, client, candidates, s.fetchListeningHistory(ctx, client))
+
+	selections := make([][]TrackInfo, len(specs))
+	for i, spec := range specs {
+		selections[i] = selectVariant(candidates, library, spec, maxPlaylistTracks)
+		fmt.Printf("PlaylistGenerator: Variant %s selected %d tracks\n", spec.ID, len(selections[i]))
+	}
+	return selections, nil
+}
+
+// Publish creates a playlist from tracks that have already been selected
+func (s *PlaylistGenerator) Publish(ctx context.Context, userID string, name string, description string, tracks []TrackInfo, public bool) (*GeneratedPlaylist, error) {
+	client := s.spotifyService.GetClient(ctx, userID)
+	if client == nil {
+		return nil, fmt.Errorf("failed to get spotify client")
+	}
+
+	playlist, err := s.publishPlaylist(ctx, client, userID, name, description, tracks, public)
+	if err != nil {
+		return nil, err
+	}
+	return &GeneratedPlaylist{Playlist: playlist, Tracks: tracks}, nil
+}
+
+// selectVariant orders a seeded shuffle of the pool by the spec's strategy, then by the
+// user's feedback, and takes up to limit tracks. Shuffling first means different seeds
+// break ties differently.
+func selectVariant(pool []TrackInfo, library []TrackInfo, spec variantSpec, limit int) []TrackInfo {
+	rng := rand.New(rand.NewSource(spec.Seed))
+	shuffled := func(tracks []TrackInfo) []TrackInfo {
+		out := append([]TrackInfo(nil), tracks...)
+		rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+		return out
+	}
+
+	var selected []TrackInfo
+	switch spec.Strategy {
+	case VariantFamiliar:
+		return selectByFamiliarity(rankByFeedback(shuffled(append(append([]TrackInfo(nil), pool...), library...))), familiarVariantRatio, limit)
+	case VariantDiscovery:
+		// New tracks only, deeper cuts first
+		for _, track := range shuffled(pool) {
+			if !track.Known {
+				selected = append(selected, track)
+			}
+		}
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].Popularity < selected[j].Popularity
+		})
+	case VariantHighEnergy:
+		// Energy is only known for tracks with audio features; popularity breaks ties
+		selected = shuffled(pool)
+		sort.SliceStable(selected, func(i, j int) bool {
+			if selected[i].Energy != selected[j].Energy {
+				return selected[i].Energy > selected[j].Energy
+			}
+			return selected[i].Popularity > selected[j].Popularity
+		})
+	default:
+		selected = shuffled(pool)
+	}
+	selected = rankByFeedback(selected)
+
+	if len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+// variantOverlap reports the shared tracks of every pair of variants
+func variantOverlap(specs []variantSpec, selections [][]TrackInfo) []VariantOverlap {
+	sets := make([]map[string]bool, len(selections))
+	for i, tracks := range selections {
+		sets[i] = make(map[string]bool, len(tracks))
+		for _, track := range tracks {
+			sets[i][track.Track.ID.String()] = true
+		}
+	}
+
+	var overlaps []VariantOverlap
+	for a := 0; a < len(specs); a++ {
+		for b := a + 1; b < len(specs); b++ {
+			shared := 0
+			for id := range sets[a] {
+				if sets[b][id] {
+					shared++
+				}
+			}
+			overlap := VariantOverlap{A: specs[a].ID, B: specs[b].ID, Shared: shared}
+			if union := len(sets[a]) + len(sets[b]) - shared; union > 0 {
+				overlap.Jaccard = math.Round(float64(shared)/float64(union)*1000) / 1000
+			}
+			overlaps = append(overlaps, overlap)
+		}
+	}
+	return overlaps
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// PreferencesResponse is a user's stored profile and generation defaults. Users who
+// have saved nothing get the defaults with no UpdatedAt.
+type PreferencesResponse struct {
+	Height          *float64   `json:"height,omitempty"`
+	HeightUnit      string     `json:"heightUnit"`
+	Gender          string     `json:"gender,omitempty"`
+	StrideLengthCm  *float64   `json:"strideLengthCm,omitempty"`
+	PaceUnit        string     `json:"paceUnit"`
+	TempoTolerance  int        `json:"tempoTolerance"`
+	ExplicitContent string     `json:"explicitContent"`
+	Genres          []string   `json:"genres"`
+	PlaylistPublic  bool       `json:"playlistPublic"`
+	UpdatedAt       *time.Time `json:"updatedAt,omitempty"`
+}
+
+type preferencesService struct {
+	prefsRepo repository.UserPreferencesRepository
+}
+
+func NewPreferencesService(prefsRepo repository.UserPreferencesRepository) PreferencesService {
+	return &preferencesService{
+		prefsRepo: prefsRepo,
+	}
+}
+
+// GetPreferences returns the user's stored preferences, or the defaults
+func (s *preferencesService) GetPreferences(ctx context.Context, userID string) (*PreferencesResponse, error) {
+	prefs, err := loadPreferences(ctx, s.prefsRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return &PreferencesResponse{
+			HeightUnit:      "cm",
+			PaceUnit:        "km",
+			TempoTolerance:  defaultTempoTolerance,
+			ExplicitContent: ExplicitAllow,
+			Genres:          []string{},
+		}, nil
+	}
+	return newPreferencesResponse(prefs)
+}
+
+// SavePreferences replaces the user's stored preferences
+func (s *preferencesService) SavePreferences(ctx context.Context, userID string, req types.PreferencesRequest) (*PreferencesResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	genres := req.Genres
+	if genres == nil {
+		genres = []string{}
+	}
+	encoded, err := json.Marshal(genres)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode genres: %v", err)
+	}
+
+	prefs := &model.UserPreferences{
+		UserID:          uid,
+		Height:          req.Height,
+		HeightUnit:      req.HeightUnit,
+		Gender:          req.Gender,
+		StrideLengthCm:  req.StrideLengthCm,
+		PaceUnit:        normalizePaceUnit(req.PaceUnit),
+		TempoTolerance:  defaultTempoTolerance,
+		ExplicitContent: req.ExplicitContent,
+		Genres:          string(encoded),
+		PlaylistPublic:  req.PlaylistPublic,
+		UpdatedAt:       time.Now(),
+	}
+	if prefs.HeightUnit == "" {
+		prefs.HeightUnit = "cm"
+	}
+	if req.TempoTolerance != nil {
+		prefs.TempoTolerance = *req.TempoTolerance
+	}
+	if prefs.ExplicitContent == "" {
+		prefs.ExplicitContent = ExplicitAllow
+	}
+
+	if err := s.prefsRepo.SavePreferences(ctx, prefs); err != nil {
+		return nil, fmt.Errorf("failed to save preferences: %v", err)
+	}
+	return newPreferencesResponse(prefs)
+}
+
+// loadPreferences returns the user's stored preferences, or nil if they have none
+func loadPreferences(ctx context.Context, prefsRepo repository.UserPreferencesRepository, userID string) (*model.UserPreferences, error) {
+	prefs, err := prefsRepo.GetPreferences(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// applyPreferences fills the fields a generation request leaves out from the stored
+// preferences and returns the names of the fields it filled
+func applyPreferences(req *types.GeneratePlaylistRequest, prefs *model.UserPreferences) ([]string, error) {
+	if prefs == nil {
+		return nil, nil
+	}
+
+	var applied []string
+	if req.Height == 0 && prefs.Height != nil {
+		req.Height = *prefs.Height
+		req.HeightUnit = prefs.HeightUnit
+		applied = append(applied, "height", "heightUnit")
+	}
+	if req.Gender == "" && prefs.Gender != "" {
+		req.Gender = prefs.Gender
+		applied = append(applied, "gender")
+	}
+	if req.StrideLengthCm == 0 && prefs.StrideLengthCm != nil {
+		req.StrideLengthCm = *prefs.StrideLengthCm
+		applied = append(applied, "strideLengthCm")
+	}
+	if req.PaceUnit == "" {
+		req.PaceUnit = prefs.PaceUnit
+		applied = append(applied, "paceUnit")
+	}
+	if req.TempoTolerance == nil {
+		tolerance := prefs.TempoTolerance
+		req.TempoTolerance = &tolerance
+		applied = append(applied, "tempoTolerance")
+	}
+	if req.ExplicitContent == "" {
+		req.ExplicitContent = prefs.ExplicitContent
+		applied = append(applied, "explicitContent")
+	}
+	if req.Genres == nil {
+		if err := json.Unmarshal([]byte(prefs.Genres), &req.Genres); err != nil {
+			return nil, fmt.Errorf("failed to decode genres: %v", err)
+		}
+		applied = append(applied, "genres")
+	}
+	if req.Public == nil {
+		public := prefs.PlaylistPublic
+		req.Public = &public
+		applied = append(applied, "public")
+	}
+	return applied, nil
+}
+
+// applyCadencePreferences fills the body settings a workout request leaves out from
+// the stored preferences and returns the names of the fields it filled
+func applyCadencePreferences(settings *types.CadenceSettings, prefs *model.UserPreferences) []string {
+	if prefs == nil {
+		return nil
+	}
+
+	var applied []string
+	if settings.Height == 0 && prefs.Height != nil {
+		settings.Height = *prefs.Height
+		settings.HeightUnit = prefs.HeightUnit
+		applied = append(applied, "height", "heightUnit")
+	}
+	if settings.Gender == "" && prefs.Gender != "" {
+		settings.Gender = prefs.Gender
+		applied = append(applied, "gender")
+	}
+	if settings.StrideLengthCm == 0 && prefs.StrideLengthCm != nil {
+		settings.StrideLengthCm = *prefs.StrideLengthCm
+		applied = append(applied, "strideLengthCm")
+	}
+	return applied
+}
+
+func newPreferencesResponse(prefs *model.UserPreferences) (*PreferencesResponse, error) {
+	response := &PreferencesResponse{
+		Height:          prefs.Height,
+		HeightUnit:      prefs.HeightUnit,
+		Gender:          prefs.Gender,
+		StrideLengthCm:  prefs.StrideLengthCm,
+		PaceUnit:        prefs.PaceUnit,
+		TempoTolerance:  prefs.TempoTolerance,
+		ExplicitContent: prefs.ExplicitContent,
+		PlaylistPublic:  prefs.PlaylistPublic,
+		UpdatedAt:       &prefs.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(prefs.Genres), &response.Genres); err != nil {
+		return nil, fmt.Errorf("failed to decode genres: %v", err)
+	}
+	if response.Genres == nil {
+		response.Genres = []string{}
+	}
+	return response, nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// Progression run shapes
+const (
+	ProgressionLinear   = "linear"
+	ProgressionStepwise = "stepwise"
+)
+
+const (
+	// defaultProgressionSteps is the number of paces in a stepwise run when not set
+	defaultProgressionSteps = 4
+	// progressionSampleMs is the spacing of points on a linear planned curve
+	progressionSampleMs = 60000
+)
+
+// ProgressionRunResponse is the shape returned by GenerateProgressionRun
+type ProgressionRunResponse struct {
+	Playlist     *PlaylistResponse  `json:"playlist"`
+	Shape        string             `json:"shape"`
+	PlannedMs    int                `json:"plannedMs"`
+	TracksMs     int                `json:"tracksMs"`
+	MeanBPMError float64            `json:"meanBpmError"` // Mean |tempo - target| over measured tracks
+	Planned      []ProgressionPoint `json:"planned"`
+	Actual       []ProgressionTrack `json:"actual"`
+}
+
+// ProgressionPoint is a point on the planned pace curve
+type ProgressionPoint struct {
+	OffsetMs    int    `json:"offsetMs"`
+	PaceSeconds int    `json:"paceSeconds"`
+	Pace        string `json:"pace"`
+	TargetBPM   int    `json:"targetBpm"`
+}
+
+// ProgressionTrack is one track of the curve the playlist actually produces.
+// EffectiveBPM is the track tempo after half- or double-time matching.
+type ProgressionTrack struct {
+	PlaylistTrack
+	Position     int     `json:"position"`
+	StartMs      int     `json:"startMs"`
+	EndMs        int     `json:"endMs"`
+	TargetBPM    int     `json:"targetBpm"`
+	EffectiveBPM float64 `json:"effectiveBpm"`
+	ErrorBPM     float64 `json:"errorBpm"`
+}
+
+// progressionCurve gives the planned pace at any offset into the run
+type progressionCurve struct {
+	shape      string
+	startPace  int
+	endPace    int
+	durationMs int
+	steps      int
+	paceUnit   string
+}
+
+// paceAt returns the planned pace in seconds per unit at offsetMs
+func (c progressionCurve) paceAt(offsetMs int) int {
+	progress := math.Min(math.Max(float64(offsetMs)/float64(c.durationMs), 0), 1)
+	if c.shape == ProgressionStepwise {
+		// Each step holds its pace for an equal share of the run; the last step
+		// lands on the end pace
+		step := min(int(progress*float64(c.steps)), c.steps-1)
+		progress = float64(step) / float64(c.steps-1)
+	}
+	return int(math.Round(float64(c.startPace) + float64(c.endPace-c.startPace)*progress))
+}
+
+// bpmAt returns the planned target BPM at offsetMs
+func (c progressionCurve) bpmAt(offsetMs int) int {
+	return targetBPMForPace(c.paceAt(offsetMs), c.paceUnit)
+}
+
+// direction is +1 when the tempo rises over the run, -1 when it falls and 0 when flat
+func (c progressionCurve) direction() int {
+	start, end := c.bpmAt(0), c.bpmAt(c.durationMs)
+	switch {
+	case end > start:
+		return 1
+	case end < start:
+		return -1
+	}
+	return 0
+}
+
+// plannedPoints samples the curve: every step change for stepwise runs, every minute
+// for linear runs, and always the start and end
+func (c progressionCurve) plannedPoints() []ProgressionPoint {
+	var offsets []int
+	if c.shape == ProgressionStepwise {
+		for i := 0; i < c.steps; i++ {
+			offsets = append(offsets, c.durationMs*i/c.steps)
+		}
+	} else {
+		for offset := 0; offset < c.durationMs; offset += progressionSampleMs {
+			offsets = append(offsets, offset)
+		}
+	}
+	offsets = append(offsets, c.durationMs)
+
+	points := make([]ProgressionPoint, len(offsets))
+	for i, offset := range offsets {
+		pace := c.paceAt(offset)
+		points[i] = ProgressionPoint{
+			OffsetMs:    offset,
+			PaceSeconds: pace,
+			Pace:        formatClock(pace),
+			TargetBPM:   targetBPMForPace(pace, c.paceUnit),
+		}
+	}
+	return points
+}
+
+// GenerateProgressionRun builds a playlist whose tempo follows a pace that changes from a
+// start pace to an end pace, linearly or in equal steps
+func (s *SpotifyServiceImpl) GenerateProgressionRun(ctx context.Context, internalUserID string, req types.ProgressionRunRequest) (*ProgressionRunResponse, error) {
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	curve := progressionCurve{
+		shape:      req.Shape,
+		startPace:  req.StartPaceSeconds,
+		endPace:    req.EndPaceSeconds,
+		durationMs: req.DurationSeconds * 1000,
+		steps:      req.Steps,
+		paceUnit:   normalizePaceUnit(req.PaceUnit),
+	}
+	if curve.shape == "" {
+		curve.shape = ProgressionLinear
+	}
+	if curve.steps == 0 {
+		curve.steps = defaultProgressionSteps
+	}
+
+	templates := loadPlaylistTemplates(ctx, s.templateRepo, internalUserID)
+
+	// Name the playlist after the faster end of the run
+	namePace := min(curve.startPace, curve.endPace)
+	nameBPM := targetBPMForPace(namePace, curve.paceUnit)
+
+	generator := s.newGenerator(ctx, internalUserID)
+	generated, placed, err := generator.GenerateProgression(ctx, internalUserID, curve, PlaylistOptions{
+		Templates: templates,
+		NameData:  NewPlaylistNameData(namePace, curve.paceUnit, nameBPM, nameBPM, req.WorkoutName),
+		Filters:   filters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate progression run: %v", err)
+	}
+
+	response := &ProgressionRunResponse{
+		Playlist:  newPlaylistResponse(generated),
+		Shape:     curve.shape,
+		PlannedMs: curve.durationMs,
+		Planned:   curve.plannedPoints(),
+	}
+	response.Playlist.CadenceProgramIgnoredStage = ignoredProgramStage(ctx, s.programRepo, internalUserID)
+	s.recordPlaylist(ctx, internalUserID, playlistRecord{
+		Kind:        PlaylistKindProgression,
+		PaceSeconds: namePace,
+		PaceUnit:    curve.paceUnit,
+		TargetBPM:   nameBPM,
+		Filters:     req.TrackFilterParams,
+		Inputs:      req,
+		Feedback:    generator.feedbackEffects(generated.Tracks),
+	}, generated, response.Playlist)
+
+	measured := 0
+	totalError := 0.0
+	for position, p := range placed {
+		target := curve.bpmAt(p.StartMs)
+		effective := effectiveTempo(p.BPM, float64(target))
+		point := ProgressionTrack{
+			PlaylistTrack: newPlaylistTrack(p.TrackInfo),
+			Position:      position,
+			StartMs:       p.StartMs,
+			EndMs:         p.StartMs + int(p.Track.Duration),
+			TargetBPM:     target,
+			EffectiveBPM:  math.Round(effective*10) / 10,
+			ErrorBPM:      math.Round((effective-float64(target))*10) / 10,
+		}
+		if p.TempoMeasured {
+			measured++
+			totalError += math.Abs(effective - float64(target))
+		}
+		response.TracksMs = point.EndMs
+		response.Actual = append(response.Actual, point)
+	}
+	if measured > 0 {
+		response.MeanBPMError = math.Round(totalError/float64(measured)*10) / 10
+	}
+	return response, nil
+}
+
+// GenerateProgression collects one pool seeded at the middle of the curve and places
+// tracks so each starts close to the curve's tempo at that moment
+func (s *PlaylistGenerator) GenerateProgression(ctx context.Context, userID string, curve progressionCurve, opts PlaylistOptions) (*GeneratedPlaylist, []placedTrack, error) {
+	if curve.durationMs <= 0 {
+		return nil, nil, fmt.Errorf("progression run has no duration")
+	}
+
+	return s.generateTimedPlaylist(ctx, userID, curve.bpmAt(curve.durationMs/2), opts, func(pool []TrackInfo) ([]placedTrack, error) {
+		return fitTracksToCurve(pool, curve), nil
+	})
+}
+
+// fitTracksToCurve places tracks greedily along the curve. Each pick minimises the error
+// against the target at the track's start time, plus a penalty for moving against the
+// direction of the run so the tempo keeps climbing (or falling). The last track is
+// weighed against stopping early, as for workout segments.
+func fitTracksToCurve(pool []TrackInfo, curve progressionCurve) []placedTrack {
+	direction := curve.direction()
+	used := make(map[int]bool)
+	var placed []placedTrack
+	previous := math.NaN()
+	cursor := 0
+
+	for cursor < curve.durationMs {
+		remaining := curve.durationMs - cursor
+		target := curve.bpmAt(cursor)
+
+		best := -1
+		bestCost := math.Inf(1)
+		for i, track := range pool {
+			if used[i] {
+				continue
+			}
+			cost, ok := segmentTempoCost(track, target)
+			if !ok {
+				continue
+			}
+			if track.TempoMeasured && !math.IsNaN(previous) {
+				if step := effectiveTempo(track.BPM, float64(target)) - previous; step*float64(direction) < 0 {
+					cost += math.Abs(step)
+				}
+			}
+			if gap := remaining - int(track.Track.Duration); gap < minTrackMs {
+				cost += math.Abs(float64(gap)) / 1000 * boundarySecondCost
+			}
+			if cost < bestCost {
+				best, bestCost = i, cost
+			}
+		}
+
+		if best == -1 {
+			fmt.Printf("PlaylistGenerator: Ran out of tracks at %s (%d BPM)\n", formatClock(cursor/1000), target)
+			break
+		}
+		if remaining < minTrackMs && float64(remaining)/1000*boundarySecondCost <= bestCost {
+			break
+		}
+
+		track := pool[best]
+		if !track.TempoMeasured {
+			track.BPM = float32(target)
+		}
+		used[best] = true
+		previous = effectiveTempo(track.BPM, float64(target))
+		placed = append(placed, placedTrack{TrackInfo: track, StartMs: cursor})
+		cursor += int(track.Track.Duration)
+	}
+	return placed
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// Pacing strategies for race plans
+const (
+	StrategyEven          = "even"
+	StrategyNegativeSplit = "negative-split"
+	StrategyCustom        = "custom"
+)
+
+// Timeline segment types used by race playlists
+const (
+	SegmentRace   = "race"
+	SegmentBuffer = "buffer"
+)
+
+const (
+	// defaultRaceBufferSeconds is the music added after the goal time when not set
+	defaultRaceBufferSeconds = 300
+	// defaultNegativeSplitRatio sets the gap between the halves of a negative split,
+	// as a fraction of the average pace, when the request does not set one
+	defaultNegativeSplitRatio = 0.02
+	// minRaceSplitPaceSeconds is the fastest split pace per km a plan may ask for,
+	// well beyond any world record pace
+	minRaceSplitPaceSeconds = 120
+)
+
+// RaceDistanceCustom selects the distance given in meters instead of a standard one
+const RaceDistanceCustom = "custom"
+
+// raceDistances are the standard race distances in meters
+var raceDistances = map[string]float64{
+	"5k":       5000,
+	"10k":      10000,
+	"half":     21097.5,
+	"marathon": 42195,
+}
+
+// RacePlanResponse is the shape returned by GenerateRacePlan
+type RacePlanResponse struct {
+	DistanceMeters  float64           `json:"distanceMeters"`
+	GoalTimeSeconds int               `json:"goalTimeSeconds"`
+	GoalTime        string            `json:"goalTime"`
+	AveragePace     string            `json:"averagePace"` // Per km
+	Strategy        string            `json:"strategy"`
+	BufferSeconds   int               `json:"bufferSeconds"`
+	Splits          []RaceSplit       `json:"splits"`
+	Playlist        *PlaylistResponse `json:"playlist"`
+	Timeline        WorkoutTimeline   `json:"timeline"`
+}
+
+// RaceSplit is one kilometre of the plan; the last split may be shorter
+type RaceSplit struct {
+	Index          int     `json:"index"`
+	DistanceMeters float64 `json:"distanceMeters"` // Length of this split
+	PaceSeconds    int     `json:"paceSeconds"`    // Per km, rounded
+	Pace           string  `json:"pace"`
+	SplitSeconds   int     `json:"splitSeconds"`
+	ElapsedSeconds int     `json:"elapsedSeconds"` // Planned time at the end of this split
+	Elapsed        string  `json:"elapsed"`
+	TargetBPM      int     `json:"targetBpm"`
+}
+
+// raceSplit is a planned split before rounding
+type raceSplit struct {
+	meters      float64
+	paceSeconds float64 // Per km
+}
+
+// GenerateRacePlan computes a per-km pace plan for a goal time and builds a playlist
+// that follows it through the finish, plus a buffer
+func (s *SpotifyServiceImpl) GenerateRacePlan(ctx context.Context, internalUserID string, req types.RacePlanRequest) (*RacePlanResponse, error) {
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	distance, err := raceDistance(req.Distance, req.DistanceMeters)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = StrategyEven
+	}
+	splits, err := planRaceSplits(distance, float64(req.GoalTimeSeconds), strategy, req.SplitDifferenceSeconds, req.Splits)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := defaultRaceBufferSeconds
+	if req.BufferSeconds != nil {
+		buffer = *req.BufferSeconds
+	}
+
+	plan := raceSegments(splits, buffer)
+
+	templates := loadPlaylistTemplates(ctx, s.templateRepo, internalUserID)
+
+	averagePace := int(math.Round(float64(req.GoalTimeSeconds) / (distance / 1000)))
+	averageBPM := targetBPMForPace(averagePace, "km")
+
+	generator := s.newGenerator(ctx, internalUserID)
+	generated, placed, err := generator.GenerateWorkout(ctx, internalUserID, plan, PlaylistOptions{
+		Templates: templates,
+		NameData:  NewPlaylistNameData(averagePace, "km", averageBPM, averageBPM, req.WorkoutName),
+		Filters:   filters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate race playlist: %v", err)
+	}
+
+	response := &RacePlanResponse{
+		DistanceMeters:  distance,
+		GoalTimeSeconds: req.GoalTimeSeconds,
+		GoalTime:        formatClock(req.GoalTimeSeconds),
+		AveragePace:     formatClock(averagePace),
+		Strategy:        strategy,
+		BufferSeconds:   buffer,
+		Playlist:        newPlaylistResponse(generated),
+		Timeline:        buildWorkoutTimeline(plan, placed),
+	}
+	response.Playlist.CadenceProgramIgnoredStage = ignoredProgramStage(ctx, s.programRepo, internalUserID)
+	s.recordPlaylist(ctx, internalUserID, playlistRecord{
+		Kind:        PlaylistKindRace,
+		PaceSeconds: averagePace,
+		PaceUnit:    "km",
+		TargetBPM:   averageBPM,
+		Filters:     req.TrackFilterParams,
+		Inputs:      req,
+		Feedback:    generator.feedbackEffects(generated.Tracks),
+	}, generated, response.Playlist)
+
+	elapsed := 0.0
+	for i, split := range splits {
+		seconds := split.paceSeconds * split.meters / 1000
+		elapsed += seconds
+		pace := int(math.Round(split.paceSeconds))
+		response.Splits = append(response.Splits, RaceSplit{
+			Index:          i,
+			DistanceMeters: split.meters,
+			PaceSeconds:    pace,
+			Pace:           formatClock(pace),
+			SplitSeconds:   int(math.Round(seconds)),
+			ElapsedSeconds: int(math.Round(elapsed)),
+			Elapsed:        formatClock(int(math.Round(elapsed))),
+			TargetBPM:      targetBPMForPace(pace, "km"),
+		})
+	}
+	return response, nil
+}
+
+// raceDistance resolves a named race distance, or the custom distance in meters
+func raceDistance(name string, customMeters float64) (float64, error) {
+	if name == RaceDistanceCustom {
+		if customMeters <= 0 {
+			return 0, fmt.Errorf("%w: distanceMeters is required for a custom distance", ErrInvalidRequest)
+		}
+		return customMeters, nil
+	}
+	distance, ok := raceDistances[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown race distance %q", ErrInvalidRequest, name)
+	}
+	return distance, nil
+}
+
+// planRaceSplits divides the distance into kilometre splits and assigns each a pace so
+// the splits add up to the goal time exactly
+func planRaceSplits(distance float64, goalSeconds float64, strategy string, splitDifference *int, customSplits []int) ([]raceSplit, error) {
+	var splits []raceSplit
+	for covered := 0.0; covered < distance; covered += 1000 {
+		splits = append(splits, raceSplit{meters: math.Min(1000, distance-covered)})
+	}
+
+	average := goalSeconds / (distance / 1000)
+	switch strategy {
+	case StrategyEven:
+		for i := range splits {
+			splits[i].paceSeconds = average
+		}
+	case StrategyNegativeSplit:
+		difference := average * defaultNegativeSplitRatio
+		if splitDifference != nil {
+			difference = float64(*splitDifference)
+		}
+		if difference >= 2*average {
+			return nil, fmt.Errorf("%w: a split difference of %ds leaves no time for the second half at a %s/km average pace", ErrInvalidRequest, int(difference), formatClock(roundSeconds(average)))
+		}
+		// Splits are assigned to the half holding their midpoint
+		covered := 0.0
+		for i := range splits {
+			if covered+splits[i].meters/2 < distance/2 {
+				splits[i].paceSeconds = average + difference/2
+			} else {
+				splits[i].paceSeconds = average - difference/2
+			}
+			covered += splits[i].meters
+		}
+	case StrategyCustom:
+		if len(customSplits) != len(splits) {
+			return nil, fmt.Errorf("%w: custom strategy needs %d splits, got %d", ErrInvalidRequest, len(splits), len(customSplits))
+		}
+		for i := range splits {
+			splits[i].paceSeconds = float64(customSplits[i])
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown pacing strategy %q", ErrInvalidRequest, strategy)
+	}
+
+	// Scale so the plan finishes exactly on the goal time; for custom splits this keeps
+	// the shape of the race while correcting the total
+	total := 0.0
+	for _, split := range splits {
+		total += split.paceSeconds * split.meters / 1000
+	}
+	for i := range splits {
+		splits[i].paceSeconds *= goalSeconds / total
+		if splits[i].paceSeconds < minRaceSplitPaceSeconds {
+			return nil, fmt.Errorf("%w: split %d would need a %s/km pace, faster than %s/km", ErrInvalidRequest, i+1, formatClock(roundSeconds(splits[i].paceSeconds)), formatClock(minRaceSplitPaceSeconds))
+		}
+	}
+	return splits, nil
+}
+
+// raceSegments turns the splits into timeline segments, merging consecutive splits at
+// the same pace, and appends the buffer at the final pace
+func raceSegments(splits []raceSplit, bufferSeconds int) []plannedSegment {
+	var plan []plannedSegment
+	cursor := 0.0
+	for _, split := range splits {
+		pace := int(math.Round(split.paceSeconds))
+		cursor += split.paceSeconds * split.meters
+		end := int(math.Round(cursor))
+
+		if last := len(plan) - 1; last >= 0 && plan[last].PaceSeconds == pace {
+			plan[last].EndMs = end
+			continue
+		}
+		start := 0
+		if len(plan) > 0 {
+			start = plan[len(plan)-1].EndMs
+		}
+		plan = append(plan, plannedSegment{
+			Type:        SegmentRace,
+			PaceSeconds: pace,
+			TargetBPM:   targetBPMForPace(pace, "km"),
+			StartMs:     start,
+			EndMs:       end,
+		})
+	}
+
+	if bufferSeconds > 0 && len(plan) > 0 {
+		final := plan[len(plan)-1]
+		plan = append(plan, plannedSegment{
+			Type:        SegmentBuffer,
+			PaceSeconds: final.PaceSeconds,
+			TargetBPM:   final.TargetBPM,
+			StartMs:     final.EndMs,
+			EndMs:       final.EndMs + bufferSeconds*1000,
+		})
+	}
+	return plan
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/workoutfile"
+)
+
+const (
+	// replaySampleSeconds is the spacing of the resampled pace and cadence profile
+	replaySampleSeconds = 10
+	// defaultReplayWindowSeconds is the rolling window when the request does not set one
+	defaultReplayWindowSeconds = 60
+	// replayMedianSamples is the width of the median filter that removes GPS spikes
+	replayMedianSamples = 5
+	// replayPaceTolerance is how far, in seconds per km, a sample may stray from its
+	// segment's pace before a new segment starts
+	replayPaceTolerance = 15
+	// minReplaySegmentSeconds is the shortest segment kept; shorter ones are merged
+	minReplaySegmentSeconds = 60
+	// stoppedSpeed is the speed in m/s below which the runner is treated as stopped
+	stoppedSpeed = 0.5
+	// maxReplayDuration caps the length of an uploaded run
+	maxReplayDuration = 12 * time.Hour
+)
+
+// ReplayRunResponse is the shape returned by ReplayRun
+type ReplayRunResponse struct {
+	Format          string                   `json:"format"`
+	Name            string                   `json:"name"`
+	Sport           string                   `json:"sport"`
+	PaceUnit        string                   `json:"paceUnit"`
+	DistanceMeters  float64                  `json:"distanceMeters"`
+	DurationSeconds int                      `json:"durationSeconds"`
+	AveragePace     string                   `json:"averagePace"`
+	CadenceSource   string                   `json:"cadenceSource"` // "recorded" or "pace"
+	Profile         []ReplaySample           `json:"profile"`
+	Segments        []ReplaySegment          `json:"segments"`
+	Workout         *WorkoutPlaylistResponse `json:"workout,omitempty"` // Omitted for previews
+}
+
+// ReplaySample is one point of the smoothed pace and cadence profile
+type ReplaySample struct {
+	OffsetSeconds  int     `json:"offsetSeconds"`
+	DistanceMeters float64 `json:"distanceMeters"`
+	PaceSeconds    int     `json:"paceSeconds"` // Zero while stopped
+	Cadence        int     `json:"cadence"`     // Zero when not recorded
+}
+
+// ReplaySegment is a stretch of the run at a steady pace
+type ReplaySegment struct {
+	Type           string  `json:"type"`
+	StartSeconds   int     `json:"startSeconds"`
+	EndSeconds     int     `json:"endSeconds"`
+	DistanceMeters float64 `json:"distanceMeters"`
+	PaceSeconds    int     `json:"paceSeconds"`
+	Pace           string  `json:"pace"`
+	Cadence        int     `json:"cadence"` // Mean recorded cadence; zero when not recorded
+	TargetBPM      int     `json:"targetBpm"`
+}
+
+// replaySample is a profile point before rounding; speed is in m/s
+type replaySample struct {
+	offset   int
+	distance float64
+	speed    float64
+	cadence  float64
+}
+
+// ReplayRun analyses a recorded GPX or TCX run into a smoothed pace and cadence
+// profile, splits it into steady segments and, unless only a preview is requested,
+// generates a playlist that follows the run
+func (s *SpotifyServiceImpl) ReplayRun(ctx context.Context, internalUserID string, data []byte, form types.ReplayRunForm) (*ReplayRunResponse, error) {
+	activity, err := workoutfile.ParseActivity(data)
+	if err != nil {
+		if errors.Is(err, workoutfile.ErrUnknownFormat) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		return nil, fmt.Errorf("%w: invalid activity file: %v", ErrInvalidRequest, err)
+	}
+
+	points := activity.Points
+	duration := points[len(points)-1].Time.Sub(points[0].Time)
+	if duration < time.Minute {
+		return nil, fmt.Errorf("%w: run is shorter than a minute", ErrInvalidRequest)
+	}
+	if duration > maxReplayDuration {
+		return nil, fmt.Errorf("%w: run is longer than %v", ErrInvalidRequest, maxReplayDuration)
+	}
+
+	window := form.WindowSeconds
+	if window == 0 {
+		window = defaultReplayWindowSeconds
+	}
+	samples := resampleActivity(points, window)
+	segments := segmentReplay(samples)
+
+	paceUnit := normalizePaceUnit(form.PaceUnit)
+	meters := unitMeters(paceUnit)
+	distance := points[len(points)-1].DistanceMeters
+
+	response := &ReplayRunResponse{
+		Format:          activity.Format,
+		Name:            activity.Name,
+		Sport:           activity.Sport,
+		PaceUnit:        paceUnit,
+		DistanceMeters:  math.Round(distance),
+		DurationSeconds: int(duration.Seconds()),
+		AveragePace:     formatClock(int(math.Round(duration.Seconds() / distance * meters))),
+		CadenceSource:   "pace",
+	}
+	if activity.HasCadence() {
+		response.CadenceSource = "recorded"
+	}
+
+	for _, sample := range samples {
+		point := ReplaySample{
+			OffsetSeconds:  sample.offset,
+			DistanceMeters: math.Round(sample.distance),
+			Cadence:        int(math.Round(sample.cadence)),
+		}
+		if sample.speed >= stoppedSpeed {
+			point.PaceSeconds = int(math.Round(meters / sample.speed))
+		}
+		response.Profile = append(response.Profile, point)
+	}
+
+	var plan []plannedSegment
+	for _, segment := range segments {
+		// Stops are planned as very slow running, which lands on the lowest tempo
+		pace := int(math.Round(meters / math.Max(segment.speed, stoppedSpeed)))
+		targetBPM := targetBPMForPace(pace, paceUnit)
+		if segment.cadence > 0 {
+			// A recorded cadence is what the runner actually did; match it directly
+			targetBPM = clampBPM(int(math.Round(segment.cadence)))
+		}
+
+		response.Segments = append(response.Segments, ReplaySegment{
+			Type:           segment.kind,
+			StartSeconds:   segment.start,
+			EndSeconds:     segment.end,
+			DistanceMeters: math.Round(segment.distance),
+			PaceSeconds:    pace,
+			Pace:           formatClock(pace),
+			Cadence:        int(math.Round(segment.cadence)),
+			TargetBPM:      targetBPM,
+		})
+		plan = append(plan, plannedSegment{
+			Type:        segment.kind,
+			PaceSeconds: pace,
+			TargetBPM:   targetBPM,
+			StartMs:     segment.start * 1000,
+			EndMs:       segment.end * 1000,
+		})
+	}
+
+	if form.Preview {
+		return response, nil
+	}
+
+	name := form.WorkoutName
+	if name == "" {
+		name = activity.Name
+	}
+	workout, err := s.generateFromPlan(ctx, internalUserID, plan, paceUnit, name, TrackFilters{}, playlistRecord{
+		Kind:   PlaylistKindReplay,
+		Inputs: form,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate replay playlist: %v", err)
+	}
+	response.Workout = workout
+	return response, nil
+}
+
+// resampleActivity samples the run every replaySampleSeconds. Speed is the distance
+// covered over a centred rolling window; cadence is the mean recorded cadence in the
+// same window. A median filter then removes single-sample GPS spikes.
+func resampleActivity(points []workoutfile.TrackPoint, windowSeconds int) []replaySample {
+	start := points[0].Time
+	total := int(points[len(points)-1].Time.Sub(start).Seconds())
+	half := windowSeconds / 2
+
+	var samples []replaySample
+	for offset := 0; offset <= total; offset += replaySampleSeconds {
+		from := max(0, offset-half)
+		to := min(total, offset+half)
+
+		sample := replaySample{
+			offset:   offset,
+			distance: distanceAt(points, start, offset),
+		}
+		if to > from {
+			sample.speed = (distanceAt(points, start, to) - distanceAt(points, start, from)) / float64(to-from)
+		}
+		sample.cadence = meanCadence(points, start.Add(time.Duration(from)*time.Second), start.Add(time.Duration(to)*time.Second))
+		samples = append(samples, sample)
+	}
+
+	return medianFilterSpeed(samples, replayMedianSamples)
+}
+
+// distanceAt interpolates the cumulative distance offset seconds into the run
+func distanceAt(points []workoutfile.TrackPoint, start time.Time, offset int) float64 {
+	at := start.Add(time.Duration(offset) * time.Second)
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(at)
+	})
+	if i == 0 {
+		return points[0].DistanceMeters
+	}
+	if i == len(points) {
+		return points[len(points)-1].DistanceMeters
+	}
+
+	before, after := points[i-1], points[i]
+	span := after.Time.Sub(before.Time).Seconds()
+	if span <= 0 {
+		return after.DistanceMeters
+	}
+	fraction := at.Sub(before.Time).Seconds() / span
+	return before.DistanceMeters + (after.DistanceMeters-before.DistanceMeters)*fraction
+}
+
+// meanCadence averages the recorded cadence of the points between from and to
+func meanCadence(points []workoutfile.TrackPoint, from time.Time, to time.Time) float64 {
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(from)
+	})
+	total, count := 0.0, 0
+	for ; i < len(points) && !points[i].Time.After(to); i++ {
+		if points[i].Cadence > 0 {
+			total += points[i].Cadence
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// medianFilterSpeed replaces each sample's speed with the median of its neighbourhood
+func medianFilterSpeed(samples []replaySample, width int) []replaySample {
+	filtered := make([]replaySample, len(samples))
+	copy(filtered, samples)
+	half := width / 2
+	for i := range samples {
+		from, to := max(0, i-half), min(len(samples), i+half+1)
+		speeds := make([]float64, 0, to-from)
+		for _, neighbour := range samples[from:to] {
+			speeds = append(speeds, neighbour.speed)
+		}
+		sort.Float64s(speeds)
+		filtered[i].speed = speeds[len(speeds)/2]
+	}
+	return filtered
+}
+
+// replaySpan is a run segment under construction
+type replaySpan struct {
+	kind         string
+	start, end   int
+	distance     float64
+	speed        float64 // Mean speed in m/s over the span
+	cadence      float64
+	cadenceCount int
+}
+
+// segmentReplay splits the samples into steady-pace segments. A new segment starts when
+// a sample's pace strays more than replayPaceTolerance from the current segment; short
+// segments are then merged into the neighbour closest in pace.
+func segmentReplay(samples []replaySample) []replaySpan {
+	var spans []replaySpan
+	for i, sample := range samples {
+		end := sample.offset + replaySampleSeconds
+		if i == len(samples)-1 {
+			end = sample.offset
+		}
+		if end <= sample.offset {
+			continue
+		}
+
+		if n := len(spans); n > 0 {
+			current := &spans[n-1]
+			if paceDifference(current.speed, sample.speed) <= replayPaceTolerance {
+				current.extend(sample, end)
+				continue
+			}
+		}
+		span := replaySpan{start: sample.offset, end: sample.offset}
+		span.extend(sample, end)
+		spans = append(spans, span)
+	}
+
+	spans = mergeShortSpans(spans)
+	for len(spans) > maxWorkoutSegments {
+		spans = mergeClosestSpans(spans)
+	}
+	classifySpans(spans)
+	return spans
+}
+
+// extend adds a sample covering [sample.offset, end) to the span
+func (s *replaySpan) extend(sample replaySample, end int) {
+	seconds := float64(end - sample.offset)
+	s.distance += sample.speed * seconds
+	s.end = end
+	s.speed = s.distance / float64(s.end-s.start)
+	if sample.cadence > 0 {
+		s.cadence = (s.cadence*float64(s.cadenceCount) + sample.cadence) / float64(s.cadenceCount+1)
+		s.cadenceCount++
+	}
+}
+
+// merge folds other, which directly follows s, into s
+func (s *replaySpan) merge(other replaySpan) {
+	s.distance += other.distance
+	s.end = other.end
+	s.speed = s.distance / float64(s.end-s.start)
+	if total := s.cadenceCount + other.cadenceCount; total > 0 {
+		s.cadence = (s.cadence*float64(s.cadenceCount) + other.cadence*float64(other.cadenceCount)) / float64(total)
+		s.cadenceCount = total
+	}
+}
+
+// paceDifference returns the difference between two speeds as seconds per km
+func paceDifference(a float64, b float64) float64 {
+	if a < stoppedSpeed || b < stoppedSpeed {
+		if a < stoppedSpeed && b < stoppedSpeed {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return math.Abs(1000/a - 1000/b)
+}
+
+// mergeShortSpans merges spans shorter than minReplaySegmentSeconds into the neighbour
+// closest in pace, shortest first so transitions fold into the steady stretches
+func mergeShortSpans(spans []replaySpan) []replaySpan {
+	for len(spans) > 1 {
+		short := -1
+		for i, span := range spans {
+			length := span.end - span.start
+			if length < minReplaySegmentSeconds && (short == -1 || length < spans[short].end-spans[short].start) {
+				short = i
+			}
+		}
+		if short == -1 {
+			return spans
+		}
+		spans = mergeInto(spans, short)
+	}
+	return spans
+}
+
+// mergeClosestSpans merges the adjacent pair closest in pace
+func mergeClosestSpans(spans []replaySpan) []replaySpan {
+	best, bestDiff := 1, math.Inf(1)
+	for i := 1; i < len(spans); i++ {
+		if d := paceDifference(spans[i-1].speed, spans[i].speed); d < bestDiff {
+			best, bestDiff = i, d
+		}
+	}
+	merged := spans[best-1]
+	merged.merge(spans[best])
+	spans[best-1] = merged
+	return append(spans[:best], spans[best+1:]...)
+}
+
+// mergeInto merges span i into whichever neighbour is closer in pace
+func mergeInto(spans []replaySpan, i int) []replaySpan {
+	left := i > 0
+	if left && i+1 < len(spans) {
+		left = paceDifference(spans[i-1].speed, spans[i].speed) <= paceDifference(spans[i+1].speed, spans[i].speed)
+	}
+	if !left {
+		i++
+	}
+	merged := spans[i-1]
+	merged.merge(spans[i])
+	spans[i-1] = merged
+	return append(spans[:i], spans[i+1:]...)
+}
+
+// classifySpans labels each span against the midpoint between the fastest and slowest
+// paces of the run: faster stretches are work, slower ones recovery, and slow stretches
+// at the very start or end are the warmup and cooldown. Runs without a clear spread
+// of paces are all work.
+func classifySpans(spans []replaySpan) {
+	fastest, slowest := spans[0].speed, spans[0].speed
+	for _, span := range spans {
+		fastest = math.Max(fastest, span.speed)
+		slowest = math.Min(slowest, span.speed)
+	}
+	steady := paceDifference(fastest, slowest) <= 2*replayPaceTolerance
+	midpoint := (fastest + slowest) / 2
+
+	for i := range spans {
+		slower := !steady && spans[i].speed < midpoint
+		switch {
+		case slower && i == 0:
+			spans[i].kind = SegmentWarmup
+		case slower && i == len(spans)-1:
+			spans[i].kind = SegmentCooldown
+		case slower:
+			spans[i].kind = SegmentRecovery
+		default:
+			spans[i].kind = SegmentWork
+		}
+	}
+}
@@ -20,21 +20,67 @@ import (
 
 	"github.com/yimango/beatpace-backend/model"
 	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/utils"
 )
 
 // PlaylistResponse is the shape returned by GeneratePlaylistForPace.
 type PlaylistResponse struct {
-	URL    string   `json:"url"`
-	Tracks []string `json:"tracks"`
+	URL         string          `json:"url"`
+	Tracks      []string        `json:"tracks"`
+	Items       []PlaylistTrack `json:"items"`
+	Activity    string          `json:"activity,omitempty"`
+	Cadence     int             `json:"cadence,omitempty"` // Steps per minute, or pedal RPM for cycling
+	TargetBPM   int             `json:"targetBpm,omitempty"`
+	Zone        string          `json:"zone,omitempty"`        // Training zone the pace was resolved from
+	PaceSeconds int             `json:"paceSeconds,omitempty"` // Pace the tempo is based on, in seconds per unit
+
+	// CadenceProgramStage is set when the cadence came from the user's cadence program
+	CadenceProgramStage *int `json:"cadenceProgramStage,omitempty"`
+
+	// CadenceProgramIgnoredStage is set when the user has a cadence program but the
+	// playlist follows its own pace targets: workouts, imports, treadmill programs,
+	// run replays, race plans, progression runs and pace ladders
+	CadenceProgramIgnoredStage *int `json:"cadenceProgramIgnoredStage,omitempty"`
+
+	// Environment is set when heat or altitude adjusted the pace
+	Environment *EnvironmentAdjustment `json:"environment,omitempty"`
+
+	// HistoryID identifies the playlist in the user's history; absent if recording failed
+	HistoryID string `json:"historyId,omitempty"`
+
+	// PreferencesApplied lists the request fields filled in from stored preferences
+	PreferencesApplied []string `json:"preferencesApplied,omitempty"`
+}
+
+// PlaylistTrack describes one track of a generated playlist
+type PlaylistTrack struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Artists     []string `json:"artists"`
+	URL         string   `json:"url"`
+	BPM         float32  `json:"bpm"`
+	Measured    bool     `json:"tempoMeasured"` // false when BPM is the search target estimate
+	DurationMs  int      `json:"durationMs"`
+	Familiarity string   `json:"familiarity"` // "known" or "new"
+	Popularity  int      `json:"popularity"`
+	ReleaseDate string   `json:"releaseDate"`
 }
 
 type SpotifyServiceImpl struct {
-	userRepo      repository.UserRepository
-	tokenRepo     repository.TokenRepository
-	clientID      string
-	clientSecret  string
-	redirectURI   string
-	auth          *spotifyauth.Authenticator
+	userRepo     repository.UserRepository
+	tokenRepo    repository.TokenRepository
+	templateRepo repository.PlaylistTemplateRepository
+	zoneRepo     repository.TrainingZoneRepository
+	programRepo  repository.CadenceProgramRepository
+	playlistRepo repository.PlaylistRepository
+	feedbackRepo repository.TrackFeedbackRepository
+	prefsRepo    repository.UserPreferencesRepository
+	drafts       *variantDraftStore
+	clientID     string
+	clientSecret string
+	redirectURI  string
+	auth         *spotifyauth.Authenticator
 }
 
 // SpotifyTokenResponse represents the response from Spotify's token endpoint
@@ -52,11 +98,16 @@ type SpotifyUserProfile struct {
 	Email *string `json:"email"`
 }
 
-
 // NewSpotifyService stays the same...
 func NewSpotifyService(
 	userRepo repository.UserRepository,
 	tokenRepo repository.TokenRepository,
+	templateRepo repository.PlaylistTemplateRepository,
+	zoneRepo repository.TrainingZoneRepository,
+	programRepo repository.CadenceProgramRepository,
+	playlistRepo repository.PlaylistRepository,
+	feedbackRepo repository.TrackFeedbackRepository,
+	prefsRepo repository.UserPreferencesRepository,
 ) *SpotifyServiceImpl {
 	clientID := os.Getenv("CLIENT_ID")
 	clientSecret := os.Getenv("CLIENT_SECRET")
@@ -72,15 +123,24 @@ func NewSpotifyService(
 			spotifyauth.ScopePlaylistModifyPublic,
 			spotifyauth.ScopePlaylistModifyPrivate,
 			spotifyauth.ScopeUserTopRead,
+			spotifyauth.ScopeUserLibraryRead,
+			spotifyauth.ScopeUserReadRecentlyPlayed,
 		),
 	)
 
 	return &SpotifyServiceImpl{
-		userRepo:      userRepo,
-		tokenRepo:     tokenRepo,
-		clientID:      clientID,
-		clientSecret:  clientSecret,
-		redirectURI:   redirectURI,
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		templateRepo: templateRepo,
+		zoneRepo:     zoneRepo,
+		programRepo:  programRepo,
+		playlistRepo: playlistRepo,
+		feedbackRepo: feedbackRepo,
+		prefsRepo:    prefsRepo,
+		drafts:       newVariantDraftStore(),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURI:  redirectURI,
 		auth:         auth,
 	}
 }
@@ -93,6 +153,8 @@ func (s *SpotifyServiceImpl) GetAuthURL() string {
 		"playlist-modify-public",
 		"playlist-modify-private",
 		"user-top-read",
+		"user-library-read",
+		"user-read-recently-played",
 	}
 	
 	params := url.Values{}
@@ -272,72 +334,203 @@ func (s *SpotifyServiceImpl) GetValidAccessToken(ctx context.Context, internalUs
 func (s *SpotifyServiceImpl) GeneratePlaylistForPace(
 	ctx context.Context,
 	internalUserID string,
-	paceSeconds int,
-	gender string,
-	height int,
+	req types.GeneratePlaylistRequest,
 ) (*PlaylistResponse, error) {
-	fmt.Printf("Generating playlist for user %s with pace %d seconds\n", internalUserID, paceSeconds)
-
-	// Calculate target BPM based on pace
-	// Running cadence is typically between 150-180 BPM
-	// Faster pace = higher BPM
-	// For a 5:00 min/km pace (300 seconds), we want ~180 BPM
-	// For a 6:00 min/km pace (360 seconds), we want ~150 BPM
-	// Linear interpolation: BPM = 270 - (paceSeconds/3)
-	targetBPM := int(270 - (float64(paceSeconds) / 3.0))
-	
-	// Clamp BPM to reasonable range
-	if targetBPM < 140 {
-		targetBPM = 140
+	received := req
+	prefs, err := loadPreferences(ctx, s.prefsRepo, internalUserID)
+	if err != nil {
+		// Generate from the request alone rather than failing it
+		fmt.Printf("Failed to load preferences: %v\n", err)
 	}
-	if targetBPM > 180 {
-		targetBPM = 180
+	applied, err := applyPreferences(&req, prefs)
+	if err != nil {
+		fmt.Printf("Failed to apply preferences: %v\n", err)
 	}
-	
-	fmt.Printf("Calculated target BPM: %d\n", targetBPM)
+	fmt.Printf("Generating playlist for user %s with pace %d seconds per %s\n", internalUserID, req.PaceInSeconds, req.PaceUnit)
 
-	// Create playlist generator
-	generator := NewPlaylistGenerator(s)
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
 
-	// Generate playlist
-	playlist, err := generator.GeneratePlaylist(ctx, internalUserID, targetBPM)
+	activity, err := utils.NewActivity(req.Activity, req.CyclingMapping)
 	if err != nil {
-		fmt.Printf("Failed to generate playlist: %v\n", err)
-		return nil, fmt.Errorf("failed to generate playlist: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
 	}
-	fmt.Printf("Generated playlist with ID: %s\n", playlist.ID)
 
-	// Get the tracks in the playlist
-	client := s.GetClient(ctx, internalUserID)
-	if client == nil {
-		fmt.Printf("Failed to get Spotify client\n")
-		return nil, fmt.Errorf("failed to get spotify client")
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	paceInSeconds := req.PaceInSeconds
+	if req.Zone != "" {
+		if activity.Name() != utils.ActivityRunning {
+			return nil, fmt.Errorf("%w: training zones are running paces and cannot be used for %s", ErrInvalidRequest, activity.Name())
+		}
+		paceInSeconds, err = resolveZonePace(ctx, s.zoneRepo, internalUserID, req.Zone, paceUnit)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Resolved %s zone to %d seconds per %s\n", req.Zone, paceInSeconds, paceUnit)
+	} else if paceInSeconds <= 0 && activity.Name() != utils.ActivityCycling {
+		return nil, fmt.Errorf("%w: paceInSeconds or zone is required", ErrInvalidRequest)
 	}
-	fmt.Printf("Got Spotify client\n")
 
-	tracks, err := client.GetPlaylistTracks(ctx, playlist.ID)
+	// Heat and altitude slow the pace run at the same effort, so the tempo follows the
+	// adjusted pace
+	conditions, hasConditions, err := environmentConditions(req.EnvironmentParams)
 	if err != nil {
-		fmt.Printf("Failed to get playlist tracks: %v\n", err)
-		return nil, fmt.Errorf("failed to get playlist tracks: %v", err)
+		return nil, err
 	}
-	fmt.Printf("Got %d tracks from playlist\n", len(tracks.Tracks))
+	hasConditions = hasConditions && activity.Name() != utils.ActivityCycling
+	nominalPace := paceInSeconds
+	var adjustment utils.PaceAdjustment
+	if hasConditions {
+		adjustment = utils.AdjustPace(conditions)
+		paceInSeconds = roundSeconds(float64(paceInSeconds) * adjustment.Factor())
+		fmt.Printf("Adjusted pace for conditions by %.1f%%: %d -> %d seconds per %s\n", adjustment.TotalPercent, nominalPace, paceInSeconds, paceUnit)
+	}
+
+	input := utils.ActivityInput{
+		PaceInSeconds: paceInSeconds,
+		PaceUnit:      paceUnit,
+		GradePercent:  req.GradePercent,
+		CadenceRPM:    float64(req.CadenceRPM),
+	}
+	switch {
+	case req.StrideLengthCm > 0:
+		input.StepLength = req.StrideLengthCm / 100
+	case req.Height > 0:
+		input.StepLength = utils.CalculateStrideLength(req.Height, req.HeightUnit, req.Gender)
+	}
+	cadence := int(activity.Cadence(input))
+	targetBPM := utils.TargetBPM(activity, input)
+
+	// An active cadence program overrides the pace-derived cadence for running
+	var programStage *int
+	if activity.Name() == utils.ActivityRunning && !req.IgnoreCadenceProgram {
+		programCadence, stage, ok, err := currentProgramCadence(ctx, s.programRepo, internalUserID)
+		if err != nil {
+			// Fall back to the pace-derived cadence rather than failing the request
+			fmt.Printf("Failed to load cadence program: %v\n", err)
+		} else if ok {
+			// The program's range is validated when it is saved and may leave the
+			// running tempo bounds on purpose, so its cadence is not clamped
+			cadence = programCadence
+			targetBPM = int(activity.BPM(float64(cadence)))
+			programStage = &stage
+			fmt.Printf("Using cadence program stage %d: %d steps per minute\n", stage, cadence)
+		}
+	}
+	fmt.Printf("Calculated target BPM: %d (%s, cadence %d)\n", targetBPM, activity.Name(), cadence)
 
-	// Extract track URLs
-	var trackURLs []string
-	for _, item := range tracks.Tracks {
-		trackURLs = append(trackURLs, fmt.Sprintf("https://open.spotify.com/track/%s", item.Track.ID))
+	var environment *EnvironmentAdjustment
+	if hasConditions {
+		nominalBPM := targetBPM
+		if programStage == nil {
+			nominalInput := input
+			nominalInput.PaceInSeconds = nominalPace
+			nominalBPM = utils.TargetBPM(activity, nominalInput)
+		}
+		environment = newEnvironmentAdjustment(adjustment, nominalPace, paceInSeconds, nominalBPM, targetBPM)
 	}
-	fmt.Printf("Extracted %d track URLs\n", len(trackURLs))
 
-	response := &PlaylistResponse{
-		URL:    fmt.Sprintf("https://open.spotify.com/playlist/%s", playlist.ID),
-		Tracks: trackURLs,
+	templates := loadPlaylistTemplates(ctx, s.templateRepo, internalUserID)
+
+	// Create playlist generator
+	generator := s.newGenerator(ctx, internalUserID)
+
+	// Generate playlist
+	generated, err := generator.GeneratePlaylist(ctx, internalUserID, targetBPM, PlaylistOptions{
+		Templates:      templates,
+		NameData:       NewPlaylistNameData(paceInSeconds, paceUnit, cadence, targetBPM, req.WorkoutName),
+		Familiarity:    req.Familiarity,
+		Filters:        filters,
+		TempoTolerance: req.TempoTolerance,
+		Genres:         req.Genres,
+		Public:         req.Public != nil && *req.Public,
+	})
+	if err != nil {
+		fmt.Printf("Failed to generate playlist: %v\n", err)
+		return nil, fmt.Errorf("failed to generate playlist: %v", err)
 	}
+	fmt.Printf("Generated playlist with ID: %s\n", generated.Playlist.ID)
+
+	response := newPlaylistResponse(generated)
+	response.Activity = activity.Name()
+	response.Cadence = cadence
+	response.TargetBPM = targetBPM
+	response.Zone = req.Zone
+	response.PaceSeconds = paceInSeconds
+	response.CadenceProgramStage = programStage
+	response.Environment = environment
+	response.PreferencesApplied = applied
+	s.recordPlaylist(ctx, internalUserID, playlistRecord{
+		Kind:        PlaylistKindPace,
+		PaceSeconds: paceInSeconds,
+		PaceUnit:    paceUnit,
+		Activity:    activity.Name(),
+		Cadence:     cadence,
+		TargetBPM:   targetBPM,
+		Filters:     req.TrackFilterParams,
+		Inputs:      received,
+		Feedback:    generator.feedbackEffects(generated.Tracks),
+	}, generated, response)
 	fmt.Printf("Returning response with URL: %s and %d tracks\n", response.URL, len(response.Tracks))
 
 	return response, nil
 }
 
+// normalizePaceUnit returns "mile" or "km", defaulting to km
+func normalizePaceUnit(unit string) string {
+	if unit == "mile" {
+		return "mile"
+	}
+	return "km"
+}
+
+// targetBPMForPace maps a running pace in seconds per unit to the target music BPM
+func targetBPMForPace(paceInSeconds int, paceUnit string) int {
+	return utils.TargetBPM(utils.Running{}, utils.ActivityInput{PaceInSeconds: paceInSeconds, PaceUnit: paceUnit})
+}
+
+// clampBPM keeps a target BPM within the running tempo bounds
+func clampBPM(bpm int) int {
+	low, high := utils.Running{}.TempoBounds()
+	return utils.ClampBPM(bpm, low, high)
+}
+
+// newPlaylistResponse builds the API response for a generated playlist
+func newPlaylistResponse(generated *GeneratedPlaylist) *PlaylistResponse {
+	response := &PlaylistResponse{
+		URL: fmt.Sprintf("https://open.spotify.com/playlist/%s", generated.Playlist.ID),
+	}
+	for _, track := range generated.Tracks {
+		item := newPlaylistTrack(track)
+		response.Tracks = append(response.Tracks, item.URL)
+		response.Items = append(response.Items, item)
+	}
+	return response
+}
+
+// newPlaylistTrack describes one selected track for API responses
+func newPlaylistTrack(track TrackInfo) PlaylistTrack {
+	var artists []string
+	for _, artist := range track.Track.Artists {
+		artists = append(artists, artist.Name)
+	}
+
+	return PlaylistTrack{
+		ID:          track.Track.ID.String(),
+		Name:        track.Track.Name,
+		Artists:     artists,
+		URL:         fmt.Sprintf("https://open.spotify.com/track/%s", track.Track.ID),
+		BPM:         track.BPM,
+		Measured:    track.TempoMeasured,
+		DurationMs:  int(track.Track.Duration),
+		Familiarity: FamiliarityLabel(track),
+		Popularity:  track.Popularity,
+		ReleaseDate: track.Track.Album.ReleaseDate,
+	}
+}
+
 func (s *SpotifyServiceImpl) GetUserProfile(ctx context.Context, userID string) (*model.SpotifyToken, error) {
 	token, err := s.tokenRepo.GetSpotifyToken(ctx, userID)
 	if err != nil {
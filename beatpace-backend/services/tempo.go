@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Spotify's audio features endpoint accepts at most 100 IDs per call
+const audioFeaturesBatchSize = 100
+
+// enrichTempos replaces the estimated BPM of each track with Spotify's measured tempo,
+// and records its energy, where the audio features endpoint provides one. Apps
+// without access to that endpoint keep the estimates, so failures are logged rather
+// than returned.
+func (s *PlaylistGenerator) enrichTempos(ctx context.Context, client *spotify.Client, tracks []TrackInfo) {
+	measured := 0
+	for start := 0; start < len(tracks); start += audioFeaturesBatchSize {
+		end := min(start+audioFeaturesBatchSize, len(tracks))
+
+		ids := make([]spotify.ID, 0, end-start)
+		for _, track := range tracks[start:end] {
+			ids = append(ids, track.Track.ID)
+		}
+
+		features, err := client.GetAudioFeatures(ctx, ids...)
+		if err != nil {
+			fmt.Printf("PlaylistGenerator: Audio features unavailable, keeping estimated tempos: %v\n", err)
+			return
+		}
+
+		for i, feature := range features {
+			if feature == nil || feature.Tempo <= 0 {
+				continue
+			}
+			tracks[start+i].BPM = feature.Tempo
+			tracks[start+i].TempoMeasured = true
+			tracks[start+i].Energy = feature.Energy
+			measured++
+		}
+	}
+	fmt.Printf("PlaylistGenerator: Measured tempo for %d of %d tracks\n", measured, len(tracks))
+}
+
+// effectiveTempo returns the track tempo as a runner would step to it: a 85 BPM track
+// works for a 170 cadence in double time, and a 180 BPM track in half time for 90.
+func effectiveTempo(bpm float32, target float64) float64 {
+	best := float64(bpm)
+	for _, candidate := range []float64{float64(bpm) * 2, float64(bpm) / 2} {
+		if math.Abs(candidate-target) < math.Abs(best-target) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// tempoDistance is the BPM gap between a track and the target, allowing half and double time
+func tempoDistance(bpm float32, target float64) float64 {
+	return math.Abs(effectiveTempo(bpm, target) - target)
+}
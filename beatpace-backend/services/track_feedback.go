@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// Feedback weights. Scores are in ranking points; one point is worth feedbackBPMWeight
+// BPM of tempo error when fitting tracks to a workout.
+const (
+	likeScore    = 2.0
+	dislikeScore = -3.0
+	// skipPenalty is charged per skip, up to maxCountedReports skips
+	skipPenalty = 0.5
+	// paceMismatchPenalty is charged per too slow or too fast report, up to maxCountedReports
+	paceMismatchPenalty = 0.25
+	maxCountedReports   = 4
+	// wrongTempoTrustLoss is how much each wrong tempo report lowers trust in the tempo
+	// data; two reports drop the track from future pools
+	wrongTempoTrustLoss = 0.5
+	// paceMismatchTrustLoss is the smaller trust loss of a too slow or too fast report
+	paceMismatchTrustLoss = 0.1
+	// tempoDistrustWeight converts lost tempo trust into ranking points
+	tempoDistrustWeight = 2.0
+	feedbackBPMWeight   = 1.0
+	// defaultImpactPlaylists is the number of recent playlists the impact report covers
+	defaultImpactPlaylists = 10
+)
+
+// TrackSignal is the ranking signal derived from a user's feedback on one track
+type TrackSignal struct {
+	Score      float64 // Added to the track's rank; includes the tempo distrust penalty
+	TempoTrust float64 // 1 trusts the tempo data fully, 0 drops the track
+}
+
+// TrackFeedbackView is the feedback a user has given on one track and the signal it
+// produces
+type TrackFeedbackView struct {
+	TrackID    string    `json:"trackId"`
+	Rating     string    `json:"rating,omitempty"` // "up", "down" or absent
+	WrongTempo int       `json:"wrongTempo"`
+	Skipped    int       `json:"skipped"`
+	TooSlow    int       `json:"tooSlow"`
+	TooFast    int       `json:"tooFast"`
+	Score      float64   `json:"score"`
+	TempoTrust float64   `json:"tempoTrust"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// FeedbackImpactResponse shows how a user's feedback shaped their recent playlists
+type FeedbackImpactResponse struct {
+	Signals   FeedbackSignalSummary    `json:"signals"`
+	Playlists []PlaylistFeedbackImpact `json:"playlists"`
+}
+
+// FeedbackSignalSummary counts the tracks the user's feedback currently affects
+type FeedbackSignalSummary struct {
+	Tracks          int `json:"tracks"`          // Tracks with any feedback
+	Boosted         int `json:"boosted"`         // Ranked higher
+	Penalized       int `json:"penalized"`       // Ranked lower
+	TempoDistrusted int `json:"tempoDistrusted"` // Tempo data trusted less
+	Excluded        int `json:"excluded"`        // Dropped from future playlists
+}
+
+// PlaylistFeedbackImpact lists the tracks of a generation's candidate pool that feedback
+// affected, by what happened to them
+type PlaylistFeedbackImpact struct {
+	PlaylistSummary
+	Boosted  []FeedbackTrackEffect `json:"boosted"`  // Boosted and included
+	Demoted  []FeedbackTrackEffect `json:"demoted"`  // Penalized but still included
+	LeftOut  []FeedbackTrackEffect `json:"leftOut"`  // In the pool with feedback but not chosen
+	Excluded []FeedbackTrackEffect `json:"excluded"` // Dropped for untrusted tempo
+}
+
+// FeedbackTrackEffect is one track's feedback signal at generation time
+type FeedbackTrackEffect struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Score      float64 `json:"score"`
+	TempoTrust float64 `json:"tempoTrust"`
+}
+
+// newTrackSignal turns stored feedback into a ranking signal. Thumbs set the base
+// score, skips and pace complaints lower it, and tempo complaints lower trust in the
+// tempo data, which costs ranking points in turn.
+func newTrackSignal(feedback model.TrackFeedback) TrackSignal {
+	score := 0.0
+	switch {
+	case feedback.Rating > 0:
+		score = likeScore
+	case feedback.Rating < 0:
+		score = dislikeScore
+	}
+	mismatches := min(feedback.TooSlow+feedback.TooFast, maxCountedReports)
+	score -= skipPenalty * float64(min(feedback.Skipped, maxCountedReports))
+	score -= paceMismatchPenalty * float64(mismatches)
+
+	trust := 1 - wrongTempoTrustLoss*float64(feedback.WrongTempo) - paceMismatchTrustLoss*float64(mismatches)
+	trust = math.Max(0, trust)
+	score -= (1 - trust) * tempoDistrustWeight
+
+	return TrackSignal{Score: roundTo(score, 2), TempoTrust: roundTo(trust, 2)}
+}
+
+// feedbackEffect is what a signal did to one pool track during a generation
+type feedbackEffect struct {
+	TrackID  string
+	Name     string
+	Signal   TrackSignal
+	Included bool
+	Excluded bool
+}
+
+// feedbackRanking applies a user's signals to candidate pools and remembers which
+// pool tracks they touched
+type feedbackRanking struct {
+	signals map[string]TrackSignal
+	seen    map[string]feedbackEffect
+}
+
+// newGenerator returns a playlist generator that ranks candidates by the user's track
+// feedback. Generation goes ahead unranked if the feedback cannot be loaded.
+func (s *SpotifyServiceImpl) newGenerator(ctx context.Context, internalUserID string) *PlaylistGenerator {
+	generator := NewPlaylistGenerator(s)
+	feedback, err := s.feedbackRepo.ListTrackFeedback(ctx, internalUserID)
+	if err != nil {
+		fmt.Printf("Failed to load track feedback: %v\n", err)
+		return generator
+	}
+	ranking := &feedbackRanking{
+		signals: make(map[string]TrackSignal, len(feedback)),
+		seen:    make(map[string]feedbackEffect),
+	}
+	for _, f := range feedback {
+		ranking.signals[f.SpotifyTrackID] = newTrackSignal(f)
+	}
+	generator.feedback = ranking
+	return generator
+}
+
+// applyFeedback sets each track's feedback score, drops tracks whose tempo is no longer
+// trusted and ranks the rest, best first. Tracks without feedback keep their order.
+func (s *PlaylistGenerator) applyFeedback(pool []TrackInfo) []TrackInfo {
+	if s.feedback == nil || len(s.feedback.signals) == 0 {
+		return pool
+	}
+
+	kept := make([]TrackInfo, 0, len(pool))
+	touched, excluded := 0, 0
+	for _, track := range pool {
+		id := track.Track.ID.String()
+		signal, ok := s.feedback.signals[id]
+		if !ok {
+			kept = append(kept, track)
+			continue
+		}
+		touched++
+		effect := feedbackEffect{TrackID: id, Name: track.Track.Name, Signal: signal}
+		if signal.TempoTrust <= 0 {
+			effect.Excluded = true
+			excluded++
+		} else {
+			track.Feedback = signal.Score
+			kept = append(kept, track)
+		}
+		s.feedback.seen[id] = effect
+	}
+	fmt.Printf("PlaylistGenerator: Applied feedback to %d tracks, excluded %d\n", touched, excluded)
+	return rankByFeedback(kept)
+}
+
+// feedbackEffects reports what feedback did to the pool tracks it touched, given the
+// tracks that made it into the playlist
+func (s *PlaylistGenerator) feedbackEffects(tracks []TrackInfo) []feedbackEffect {
+	if s.feedback == nil || len(s.feedback.seen) == 0 {
+		return nil
+	}
+	included := make(map[string]bool, len(tracks))
+	for _, track := range tracks {
+		included[track.Track.ID.String()] = true
+	}
+
+	effects := make([]feedbackEffect, 0, len(s.feedback.seen))
+	for id, effect := range s.feedback.seen {
+		effect.Included = included[id]
+		effects = append(effects, effect)
+	}
+	sort.Slice(effects, func(i, j int) bool {
+		return effects[i].TrackID < effects[j].TrackID
+	})
+	return effects
+}
+
+// rankByFeedback moves boosted tracks forward and penalized tracks back, keeping the
+// existing order among equals
+func rankByFeedback(tracks []TrackInfo) []TrackInfo {
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].Feedback > tracks[j].Feedback
+	})
+	return tracks
+}
+
+type trackFeedbackService struct {
+	feedbackRepo repository.TrackFeedbackRepository
+	playlistRepo repository.PlaylistRepository
+}
+
+func NewTrackFeedbackService(feedbackRepo repository.TrackFeedbackRepository, playlistRepo repository.PlaylistRepository) TrackFeedbackService {
+	return &trackFeedbackService{
+		feedbackRepo: feedbackRepo,
+		playlistRepo: playlistRepo,
+	}
+}
+
+// RateTrack records feedback on a track of one of the user's generated playlists
+func (s *trackFeedbackService) RateTrack(ctx context.Context, userID string, playlistID string, req types.TrackFeedbackRequest) (*TrackFeedbackView, error) {
+	if _, err := uuid.Parse(playlistID); err != nil {
+		return nil, fmt.Errorf("%w: playlist %s", ErrNotFound, playlistID)
+	}
+	_, tracks, err := s.playlistRepo.GetPlaylist(ctx, userID, playlistID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: playlist %s", ErrNotFound, playlistID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inPlaylist := false
+	for _, track := range tracks {
+		if track.SpotifyTrackID == req.TrackID {
+			inPlaylist = true
+			break
+		}
+	}
+	if !inPlaylist {
+		return nil, fmt.Errorf("%w: track %s is not in playlist %s", ErrInvalidRequest, req.TrackID, playlistID)
+	}
+
+	if err := s.feedbackRepo.AddTrackFeedback(ctx, userID, req.TrackID, req.Feedback, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to save track feedback: %v", err)
+	}
+	feedback, err := s.feedbackRepo.GetTrackFeedback(ctx, userID, req.TrackID)
+	if err != nil {
+		return nil, err
+	}
+	return newTrackFeedbackView(feedback), nil
+}
+
+// GetFeedbackImpact summarises the user's current signals and shows what feedback did
+// to their most recent playlists
+func (s *trackFeedbackService) GetFeedbackImpact(ctx context.Context, userID string, query types.FeedbackImpactQuery) (*FeedbackImpactResponse, error) {
+	feedback, err := s.feedbackRepo.ListTrackFeedback(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	response := &FeedbackImpactResponse{Playlists: []PlaylistFeedbackImpact{}}
+	response.Signals.Tracks = len(feedback)
+	for _, f := range feedback {
+		signal := newTrackSignal(f)
+		switch {
+		case signal.TempoTrust <= 0:
+			response.Signals.Excluded++
+		case signal.Score > 0:
+			response.Signals.Boosted++
+		case signal.Score < 0:
+			response.Signals.Penalized++
+		}
+		if signal.TempoTrust < 1 {
+			response.Signals.TempoDistrusted++
+		}
+	}
+
+	limit := query.Playlists
+	if limit == 0 {
+		limit = defaultImpactPlaylists
+	}
+	playlists, err := s.playlistRepo.ListPlaylists(ctx, userID, repository.PlaylistListFilter{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(playlists))
+	for i, playlist := range playlists {
+		ids[i] = playlist.ID
+	}
+	effects, err := s.playlistRepo.ListFeedbackEffects(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byPlaylist := make(map[uuid.UUID][]model.PlaylistFeedbackEffect)
+	for _, effect := range effects {
+		byPlaylist[effect.PlaylistID] = append(byPlaylist[effect.PlaylistID], effect)
+	}
+
+	for _, playlist := range playlists {
+		impact := PlaylistFeedbackImpact{
+			PlaylistSummary: newPlaylistSummary(&playlist),
+			Boosted:         []FeedbackTrackEffect{},
+			Demoted:         []FeedbackTrackEffect{},
+			LeftOut:         []FeedbackTrackEffect{},
+			Excluded:        []FeedbackTrackEffect{},
+		}
+		for _, effect := range byPlaylist[playlist.ID] {
+			view := FeedbackTrackEffect{
+				ID:         effect.SpotifyTrackID,
+				Name:       effect.Name,
+				Score:      effect.Score,
+				TempoTrust: effect.TempoTrust,
+			}
+			switch {
+			case effect.Excluded:
+				impact.Excluded = append(impact.Excluded, view)
+			case !effect.Included:
+				impact.LeftOut = append(impact.LeftOut, view)
+			case effect.Score > 0:
+				impact.Boosted = append(impact.Boosted, view)
+			case effect.Score < 0:
+				impact.Demoted = append(impact.Demoted, view)
+			}
+		}
+		response.Playlists = append(response.Playlists, impact)
+	}
+	return response, nil
+}
+
+func newTrackFeedbackView(feedback *model.TrackFeedback) *TrackFeedbackView {
+	signal := newTrackSignal(*feedback)
+	view := &TrackFeedbackView{
+		TrackID:    feedback.SpotifyTrackID,
+		WrongTempo: feedback.WrongTempo,
+		Skipped:    feedback.Skipped,
+		TooSlow:    feedback.TooSlow,
+		TooFast:    feedback.TooFast,
+		Score:      signal.Score,
+		TempoTrust: signal.TempoTrust,
+		UpdatedAt:  feedback.UpdatedAt,
+	}
+	switch {
+	case feedback.Rating > 0:
+		view.Rating = model.TrackFeedbackUp
+	case feedback.Rating < 0:
+		view.Rating = model.TrackFeedbackDown
+	}
+	return view
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Explicit content policies
+const (
+	ExplicitAllow   = "allow"
+	ExplicitExclude = "exclude"
+)
+
+// TrackFilters narrows candidate tracks by Spotify popularity, album release date and
+// explicit content. Nil popularity bounds and zero years mean "unbounded"; popularity
+// 0 is a real bound, so a maximum of 0 keeps only the least popular tracks.
+type TrackFilters struct {
+	MinPopularity   *int
+	MaxPopularity   *int
+	YearFrom        int
+	YearTo          int
+	ReleasedAfter   time.Time // Set for "last N years" requests
+	ExcludeExplicit bool
+}
+
+// NewTrackFilters validates the request's popularity and release-era fields
+func NewTrackFilters(req types.TrackFilterParams, now time.Time) (TrackFilters, error) {
+	var filters TrackFilters
+
+	filters.MinPopularity = req.MinPopularity
+	filters.MaxPopularity = req.MaxPopularity
+	if filters.MinPopularity != nil && filters.MaxPopularity != nil && *filters.MinPopularity > *filters.MaxPopularity {
+		return TrackFilters{}, fmt.Errorf("%w: minPopularity is greater than maxPopularity", ErrInvalidRequest)
+	}
+
+	if req.ReleaseYearFrom != nil {
+		filters.YearFrom = *req.ReleaseYearFrom
+	}
+	if req.ReleaseYearTo != nil {
+		filters.YearTo = *req.ReleaseYearTo
+	}
+	if req.ReleasedWithinYears != nil {
+		if req.ReleaseYearFrom != nil || req.ReleaseYearTo != nil {
+			return TrackFilters{}, fmt.Errorf("%w: releasedWithinYears cannot be combined with a release year range", ErrInvalidRequest)
+		}
+		filters.ReleasedAfter = now.AddDate(-*req.ReleasedWithinYears, 0, 0)
+		filters.YearFrom = filters.ReleasedAfter.Year()
+		filters.YearTo = now.Year()
+	}
+	if filters.YearFrom > 0 && filters.YearTo > 0 && filters.YearFrom > filters.YearTo {
+		return TrackFilters{}, fmt.Errorf("%w: releaseYearFrom is after releaseYearTo", ErrInvalidRequest)
+	}
+	filters.ExcludeExplicit = req.ExplicitContent == ExplicitExclude
+
+	return filters, nil
+}
+
+// yearQualifier returns a Spotify search `year:` qualifier for the release range, if any
+func (f TrackFilters) yearQualifier() string {
+	switch {
+	case f.YearFrom > 0 && f.YearTo > 0:
+		return fmt.Sprintf("year:%d-%d", f.YearFrom, f.YearTo)
+	case f.YearFrom > 0:
+		return fmt.Sprintf("year:%d-%d", f.YearFrom, time.Now().Year())
+	case f.YearTo > 0:
+		return fmt.Sprintf("year:1900-%d", f.YearTo)
+	}
+	return ""
+}
+
+// matches reports whether a track passes the popularity, release-date and explicit
+// content filters. Tracks with an unreadable release date are dropped when a year range
+// is set.
+func (f TrackFilters) matches(track spotify.FullTrack) bool {
+	if f.ExcludeExplicit && track.Explicit {
+		return false
+	}
+	popularity := int(track.Popularity)
+	if f.MinPopularity != nil && popularity < *f.MinPopularity {
+		return false
+	}
+	if f.MaxPopularity != nil && popularity > *f.MaxPopularity {
+		return false
+	}
+
+	if f.YearFrom == 0 && f.YearTo == 0 && f.ReleasedAfter.IsZero() {
+		return true
+	}
+
+	year := releaseYear(track.Album)
+	if year == 0 {
+		return false
+	}
+	if f.YearFrom > 0 && year < f.YearFrom {
+		return false
+	}
+	if f.YearTo > 0 && year > f.YearTo {
+		return false
+	}
+	if !f.ReleasedAfter.IsZero() && track.Album.ReleaseDatePrecision == "day" {
+		if track.Album.ReleaseDateTime().Before(f.ReleasedAfter) {
+			return false
+		}
+	}
+	return true
+}
+
+// releaseYear extracts the year from an album release date ("1981", "1981-12" or "1981-12-15")
+func releaseYear(album spotify.SimpleAlbum) int {
+	if len(album.ReleaseDate) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(album.ReleaseDate[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+const (
+	// defaultLeadHours is how long before a session its playlist is generated when the
+	// plan does not say
+	defaultLeadHours = 12
+	// maxSessionAttempts is how many times the scheduler tries to generate a session's
+	// playlist before marking it failed
+	maxSessionAttempts = 3
+	// sessionRetryDelay is multiplied by the attempt count to space out retries
+	sessionRetryDelay = 15 * time.Minute
+	// sessionMissedAfter is how long after a session starts its playlist is still worth
+	// generating
+	sessionMissedAfter = 2 * time.Hour
+	// staleClaimAfter is how long a session may stay generating before another worker
+	// assumes the claim was abandoned
+	staleClaimAfter = 15 * time.Minute
+	// sessionGenerateTimeout bounds one session's playlist generation
+	sessionGenerateTimeout = 2 * time.Minute
+	// calendarHistory is how far back the calendar feed lists sessions
+	calendarHistory = 30 * 24 * time.Hour
+	// defaultSessionDuration is the calendar event length when a workout has no
+	// planned duration
+	defaultSessionDuration = time.Hour
+)
+
+// TrainingPlanView is a training plan as returned by the API
+type TrainingPlanView struct {
+	ID        string                           `json:"id"`
+	Name      string                           `json:"name"`
+	LeadHours int                              `json:"leadHours"`
+	Settings  types.GenerateFromWorkoutRequest `json:"settings"`
+	CreatedAt time.Time                        `json:"createdAt"`
+	Sessions  []TrainingSessionView            `json:"sessions,omitempty"`
+}
+
+// TrainingSessionView is one session of a training plan as returned by the API
+type TrainingSessionView struct {
+	ID          string     `json:"id"`
+	WorkoutID   string     `json:"workoutId"`
+	Title       string     `json:"title"`
+	Notes       string     `json:"notes,omitempty"`
+	ScheduledAt time.Time  `json:"scheduledAt"`
+	GenerateAt  time.Time  `json:"generateAt"`
+	Status      string     `json:"status"`
+	PlaylistURL string     `json:"playlistUrl,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	GeneratedAt *time.Time `json:"generatedAt,omitempty"`
+}
+
+type trainingPlanService struct {
+	planRepo       repository.TrainingPlanRepository
+	feedRepo       repository.CalendarFeedRepository
+	workoutService WorkoutService
+}
+
+func NewTrainingPlanService(planRepo repository.TrainingPlanRepository, feedRepo repository.CalendarFeedRepository, workoutService WorkoutService) TrainingPlanService {
+	return &trainingPlanService{
+		planRepo:       planRepo,
+		feedRepo:       feedRepo,
+		workoutService: workoutService,
+	}
+}
+
+// CreatePlan checks every session's workout belongs to the user and stores the plan.
+// Sessions already in the past are stored as missed.
+func (s *trainingPlanService) CreatePlan(ctx context.Context, userID string, req types.TrainingPlanRequest) (*TrainingPlanView, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	settings, err := json.Marshal(types.GenerateFromWorkoutRequest{
+		CadenceSettings:   req.CadenceSettings,
+		TrackFilterParams: req.TrackFilterParams,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plan settings: %v", err)
+	}
+
+	leadHours := defaultLeadHours
+	if req.LeadHours != nil {
+		leadHours = *req.LeadHours
+	}
+
+	now := time.Now()
+	plan := &model.TrainingPlan{
+		ID:        uuid.New(),
+		UserID:    uid,
+		Name:      req.Name,
+		LeadHours: leadHours,
+		Settings:  string(settings),
+		CreatedAt: now,
+	}
+
+	workouts := make(map[string]*WorkoutTemplate)
+	sessions := make([]model.TrainingSession, 0, len(req.Sessions))
+	for i, sessionReq := range req.Sessions {
+		workout, ok := workouts[sessionReq.WorkoutID]
+		if !ok {
+			workout, err = s.workoutService.GetWorkout(ctx, userID, sessionReq.WorkoutID)
+			if errors.Is(err, ErrNotFound) {
+				return nil, fmt.Errorf("%w: session %d: workout %s not found", ErrInvalidRequest, i+1, sessionReq.WorkoutID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			workouts[sessionReq.WorkoutID] = workout
+		}
+
+		title := sessionReq.Title
+		if title == "" {
+			title = workout.Name
+		}
+		status := model.SessionScheduled
+		if now.After(sessionReq.ScheduledAt.Add(sessionMissedAfter)) {
+			status = model.SessionMissed
+		}
+		sessions = append(sessions, model.TrainingSession{
+			ID:          uuid.New(),
+			PlanID:      plan.ID,
+			UserID:      uid,
+			WorkoutID:   uuid.MustParse(workout.ID),
+			Title:       title,
+			Notes:       sessionReq.Notes,
+			ScheduledAt: sessionReq.ScheduledAt.UTC(),
+			GenerateAt:  sessionReq.ScheduledAt.UTC().Add(-time.Duration(leadHours) * time.Hour),
+			Status:      status,
+			UpdatedAt:   now,
+		})
+	}
+
+	if err := s.planRepo.CreatePlan(ctx, plan, sessions); err != nil {
+		return nil, fmt.Errorf("failed to create training plan: %v", err)
+	}
+	return newTrainingPlanView(plan, sessions)
+}
+
+// ListPlans returns the user's training plans without their sessions
+func (s *trainingPlanService) ListPlans(ctx context.Context, userID string) ([]TrainingPlanView, error) {
+	plans, err := s.planRepo.ListPlans(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]TrainingPlanView, 0, len(plans))
+	for i := range plans {
+		view, err := newTrainingPlanView(&plans[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, nil
+}
+
+// GetPlan returns a training plan with its sessions and their generation status
+func (s *trainingPlanService) GetPlan(ctx context.Context, userID string, id string) (*TrainingPlanView, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("%w: training plan %s", ErrNotFound, id)
+	}
+	plan, err := s.planRepo.GetPlan(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: training plan %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.planRepo.ListPlanSessions(ctx, plan.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	return newTrainingPlanView(plan, sessions)
+}
+
+// DeletePlan removes a training plan and its sessions
+func (s *trainingPlanService) DeletePlan(ctx context.Context, userID string, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%w: training plan %s", ErrNotFound, id)
+	}
+	err := s.planRepo.DeletePlan(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: training plan %s", ErrNotFound, id)
+	}
+	return err
+}
+
+// GetCalendarFeed returns the user's calendar feed, creating it on first use
+func (s *trainingPlanService) GetCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error) {
+	feed, err := s.feedRepo.GetCalendarFeed(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return s.RotateCalendarFeed(ctx, userID)
+	}
+	return feed, err
+}
+
+// RotateCalendarFeed gives the user's calendar feed a new token, so the old feed URL
+// stops working
+func (s *trainingPlanService) RotateCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %v", err)
+	}
+	feed := &model.CalendarFeed{
+		UserID:    uid,
+		Token:     strings.TrimRight(token, "="),
+		CreatedAt: time.Now(),
+	}
+	if err := s.feedRepo.SaveCalendarFeed(ctx, feed); err != nil {
+		return nil, fmt.Errorf("failed to save calendar feed: %v", err)
+	}
+	return feed, nil
+}
+
+// RenderCalendarFeed returns the iCalendar document for a feed token, listing the
+// user's sessions from the last 30 days onwards with their playlist links
+func (s *trainingPlanService) RenderCalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	feed, err := s.feedRepo.GetCalendarFeedByToken(ctx, token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: calendar feed", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userID := feed.UserID.String()
+	now := time.Now()
+	sessions, err := s.planRepo.ListUserSessions(ctx, userID, now.Add(-calendarHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	durations := make(map[uuid.UUID]time.Duration)
+	events := make([]calendarEvent, 0, len(sessions))
+	for _, session := range sessions {
+		duration, ok := durations[session.WorkoutID]
+		if !ok {
+			duration = defaultSessionDuration
+			if workout, err := s.workoutService.GetWorkout(ctx, userID, session.WorkoutID.String()); err == nil && workout.PlannedSeconds > 0 {
+				duration = time.Duration(workout.PlannedSeconds) * time.Second
+			}
+			durations[session.WorkoutID] = duration
+		}
+
+		event := calendarEvent{
+			UID:         session.ID.String() + "@beatpace",
+			Start:       session.ScheduledAt,
+			End:         session.ScheduledAt.Add(duration),
+			Summary:     session.Title,
+			Description: sessionDescription(&session),
+			Updated:     session.UpdatedAt,
+		}
+		if session.PlaylistURL != nil {
+			event.URL = *session.PlaylistURL
+		}
+		events = append(events, event)
+	}
+	return renderCalendar("BeatPace training", events, now), nil
+}
+
+// GenerateDueSessions claims sessions whose generation time has come and generates
+// their playlists. It returns how many sessions were claimed.
+func (s *trainingPlanService) GenerateDueSessions(ctx context.Context, limit int) (int, error) {
+	now := time.Now()
+	sessions, err := s.planRepo.ClaimDueSessions(ctx, now, now.Add(-staleClaimAfter), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range sessions {
+		if ctx.Err() != nil {
+			break
+		}
+		s.generateSession(ctx, &sessions[i])
+	}
+	return len(sessions), nil
+}
+
+// generateSession generates one claimed session's playlist and records the outcome.
+// Transient failures are retried later; a missing workout or invalid settings fail
+// the session at once.
+func (s *trainingPlanService) generateSession(ctx context.Context, session *model.TrainingSession) {
+	userID := session.UserID.String()
+	now := time.Now()
+	session.UpdatedAt = now
+
+	if now.After(session.ScheduledAt.Add(sessionMissedAfter)) {
+		session.Status = model.SessionMissed
+		s.saveSession(ctx, session)
+		return
+	}
+
+	response, err := s.generateSessionPlaylist(ctx, userID, session)
+	session.UpdatedAt = time.Now()
+	if err == nil {
+		url := response.Playlist.URL
+		session.Status = model.SessionReady
+		session.PlaylistURL = &url
+		session.LastError = nil
+		session.GeneratedAt = &session.UpdatedAt
+		fmt.Printf("PlanScheduler: generated session %s for user %s: %s\n", session.ID, userID, url)
+		s.saveSession(ctx, session)
+		return
+	}
+
+	message := err.Error()
+	session.LastError = &message
+	permanent := errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidRequest)
+	if permanent || session.Attempts >= maxSessionAttempts {
+		session.Status = model.SessionFailed
+	} else {
+		session.Status = model.SessionScheduled
+		session.GenerateAt = session.UpdatedAt.Add(time.Duration(session.Attempts) * sessionRetryDelay)
+	}
+	fmt.Printf("PlanScheduler: session %s for user %s failed (attempt %d, %s): %v\n",
+		session.ID, userID, session.Attempts, session.Status, err)
+	s.saveSession(ctx, session)
+}
+
+func (s *trainingPlanService) generateSessionPlaylist(ctx context.Context, userID string, session *model.TrainingSession) (*WorkoutPlaylistResponse, error) {
+	plan, err := s.planRepo.GetPlan(ctx, userID, session.PlanID.String())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: training plan %s", ErrNotFound, session.PlanID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var settings types.GenerateFromWorkoutRequest
+	if err := json.Unmarshal([]byte(plan.Settings), &settings); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode plan settings: %v", ErrInvalidRequest, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sessionGenerateTimeout)
+	defer cancel()
+	return s.workoutService.GenerateFromWorkout(ctx, userID, session.WorkoutID.String(), settings)
+}
+
+func (s *trainingPlanService) saveSession(ctx context.Context, session *model.TrainingSession) {
+	if err := s.planRepo.UpdateSession(ctx, session); err != nil {
+		fmt.Printf("PlanScheduler: failed to save session %s: %v\n", session.ID, err)
+	}
+}
+
+// sessionDescription is the calendar event description: the notes, then the playlist
+// link or when it will be ready
+func sessionDescription(session *model.TrainingSession) string {
+	var lines []string
+	if session.Notes != "" {
+		lines = append(lines, session.Notes)
+	}
+	switch session.Status {
+	case model.SessionReady:
+		if session.PlaylistURL != nil {
+			lines = append(lines, "Playlist: "+*session.PlaylistURL)
+		}
+	case model.SessionScheduled, model.SessionGenerating:
+		lines = append(lines, "Playlist will be ready by "+session.GenerateAt.UTC().Format("Mon 2 Jan 15:04 MST"))
+	case model.SessionFailed:
+		lines = append(lines, "Playlist generation failed")
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+func newTrainingPlanView(plan *model.TrainingPlan, sessions []model.TrainingSession) (*TrainingPlanView, error) {
+	view := &TrainingPlanView{
+		ID:        plan.ID.String(),
+		Name:      plan.Name,
+		LeadHours: plan.LeadHours,
+		CreatedAt: plan.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(plan.Settings), &view.Settings); err != nil {
+		return nil, fmt.Errorf("failed to decode training plan %s: %v", plan.ID, err)
+	}
+
+	for _, session := range sessions {
+		sessionView := TrainingSessionView{
+			ID:          session.ID.String(),
+			WorkoutID:   session.WorkoutID.String(),
+			Title:       session.Title,
+			Notes:       session.Notes,
+			ScheduledAt: session.ScheduledAt,
+			GenerateAt:  session.GenerateAt,
+			Status:      session.Status,
+			GeneratedAt: session.GeneratedAt,
+		}
+		if session.PlaylistURL != nil {
+			sessionView.PlaylistURL = *session.PlaylistURL
+		}
+		if session.LastError != nil {
+			sessionView.Error = *session.LastError
+		}
+		view.Sessions = append(view.Sessions, sessionView)
+	}
+	return view, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/utils"
+)
+
+// TrainingZonesResponse is a user's stored training zones
+type TrainingZonesResponse struct {
+	Method             string             `json:"method"`
+	RaceDistanceMeters float64            `json:"raceDistanceMeters"`
+	RaceTimeSeconds    int                `json:"raceTimeSeconds"`
+	RaceTime           string             `json:"raceTime"`
+	VDOT               *float64           `json:"vdot,omitempty"`
+	Zones              []TrainingZoneView `json:"zones"`
+	UpdatedAt          time.Time          `json:"updatedAt"`
+}
+
+// TrainingZoneView is one zone's pace in both units and the running tempo it maps to
+type TrainingZoneView struct {
+	Name            string `json:"name"`
+	PaceSecondsKm   int    `json:"paceSecondsKm"`
+	PaceKm          string `json:"paceKm"`
+	PaceSecondsMile int    `json:"paceSecondsMile"`
+	PaceMile        string `json:"paceMile"`
+	TargetBPM       int    `json:"targetBpm"`
+}
+
+type trainingZoneService struct {
+	zoneRepo repository.TrainingZoneRepository
+}
+
+func NewTrainingZoneService(zoneRepo repository.TrainingZoneRepository) TrainingZoneService {
+	return &trainingZoneService{
+		zoneRepo: zoneRepo,
+	}
+}
+
+// GetTrainingZones returns the user's stored training zones
+func (s *trainingZoneService) GetTrainingZones(ctx context.Context, userID string) (*TrainingZonesResponse, error) {
+	zones, err := s.zoneRepo.GetTrainingZones(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: no training zones saved", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newTrainingZonesResponse(zones), nil
+}
+
+// SaveTrainingZones derives training zones from a race result and stores them,
+// replacing any the user had
+func (s *trainingZoneService) SaveTrainingZones(ctx context.Context, userID string, req types.TrainingZonesRequest) (*TrainingZonesResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	distance, err := raceDistance(req.Distance, req.DistanceMeters)
+	if err != nil {
+		return nil, err
+	}
+	calculated, err := utils.CalculateTrainingZones(req.Method, distance, float64(req.TimeSeconds))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	zones := &model.TrainingZones{
+		UserID:             uid,
+		Method:             calculated.Method,
+		RaceDistanceMeters: distance,
+		RaceTimeSeconds:    req.TimeSeconds,
+		EasySeconds:        roundSeconds(calculated.Easy),
+		MarathonSeconds:    roundSeconds(calculated.Marathon),
+		ThresholdSeconds:   roundSeconds(calculated.Threshold),
+		IntervalSeconds:    roundSeconds(calculated.Interval),
+		RepetitionSeconds:  roundSeconds(calculated.Repetition),
+		UpdatedAt:          time.Now(),
+	}
+	if calculated.Method == utils.ZoneMethodVDOT {
+		vdot := math.Round(calculated.VDOT*10) / 10
+		zones.VDOT = &vdot
+	}
+
+	if err := s.zoneRepo.SaveTrainingZones(ctx, zones); err != nil {
+		return nil, fmt.Errorf("failed to save training zones: %v", err)
+	}
+	return newTrainingZonesResponse(zones), nil
+}
+
+// resolveZonePace looks up the user's stored pace for a zone in seconds per paceUnit
+func resolveZonePace(ctx context.Context, zoneRepo repository.TrainingZoneRepository, userID string, zone string, paceUnit string) (int, error) {
+	stored, err := zoneRepo.GetTrainingZones(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, fmt.Errorf("%w: no training zones saved, set them from a race result first", ErrInvalidRequest)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	pace, ok := storedZones(stored).Pace(zone)
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown zone %q", ErrInvalidRequest, zone)
+	}
+	return roundSeconds(pace * unitMeters(paceUnit) / 1000), nil
+}
+
+// storedZones converts stored zones back to the calculator's representation
+func storedZones(zones *model.TrainingZones) utils.TrainingZones {
+	return utils.TrainingZones{
+		Method:     zones.Method,
+		Easy:       float64(zones.EasySeconds),
+		Marathon:   float64(zones.MarathonSeconds),
+		Threshold:  float64(zones.ThresholdSeconds),
+		Interval:   float64(zones.IntervalSeconds),
+		Repetition: float64(zones.RepetitionSeconds),
+	}
+}
+
+func newTrainingZonesResponse(zones *model.TrainingZones) *TrainingZonesResponse {
+	response := &TrainingZonesResponse{
+		Method:             zones.Method,
+		RaceDistanceMeters: zones.RaceDistanceMeters,
+		RaceTimeSeconds:    zones.RaceTimeSeconds,
+		RaceTime:           formatClock(zones.RaceTimeSeconds),
+		VDOT:               zones.VDOT,
+		UpdatedAt:          zones.UpdatedAt,
+	}
+
+	calculated := storedZones(zones)
+	for _, name := range utils.ZoneNames {
+		pace, _ := calculated.Pace(name)
+		perKm := roundSeconds(pace)
+		perMile := roundSeconds(pace * unitMeters("mile") / 1000)
+		response.Zones = append(response.Zones, TrainingZoneView{
+			Name:            name,
+			PaceSecondsKm:   perKm,
+			PaceKm:          formatClock(perKm),
+			PaceSecondsMile: perMile,
+			PaceMile:        formatClock(perMile),
+			TargetBPM:       targetBPMForPace(perKm, "km"),
+		})
+	}
+	return response
+}
+
+func roundSeconds(seconds float64) int {
+	return int(math.Round(seconds))
+}
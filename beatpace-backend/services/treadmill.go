@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/utils"
+)
+
+const (
+	// metersPerMile converts mph to m/s together with the seconds in an hour
+	metersPerMile = 1609.34
+	// acsmGradeFactor is the ratio of the grade and horizontal terms of the ACSM running
+	// equation, VO2 = 0.2·v + 0.9·v·grade + 3.5 (v in m/min). Running at v up a grade
+	// costs the same as running v·(1 + 4.5·grade) on the flat.
+	acsmGradeFactor = 0.9 / 0.2
+	// treadmillRunSpeed is the belt speed in m/s (about 7 km/h) from which a step is
+	// taken to be run; slower steps are walked, as in incline walking programs
+	treadmillRunSpeed = 2.0
+)
+
+// TreadmillResponse is the shape returned by GenerateTreadmillPlaylist
+type TreadmillResponse struct {
+	SpeedUnit string                   `json:"speedUnit"`
+	PaceUnit  string                   `json:"paceUnit"`
+	Steps     []TreadmillStepPlan      `json:"steps"`
+	Workout   *WorkoutPlaylistResponse `json:"workout"`
+}
+
+// TreadmillStepPlan is one program step with its belt pace and the effective pace the
+// playlist tempo is based on
+type TreadmillStepPlan struct {
+	Index                int     `json:"index"`
+	Type                 string  `json:"type"`
+	Activity             string  `json:"activity"` // "running" or "walking", from the belt speed
+	DurationSeconds      int     `json:"durationSeconds"`
+	Speed                float64 `json:"speed"`
+	InclinePercent       float64 `json:"inclinePercent"`
+	PaceSeconds          int     `json:"paceSeconds"`
+	Pace                 string  `json:"pace"`
+	EffectivePaceSeconds int     `json:"effectivePaceSeconds"`
+	EffectivePace        string  `json:"effectivePace"`
+	TargetBPM            int     `json:"targetBpm"`
+}
+
+// GenerateTreadmillPlaylist converts a treadmill program of speed and incline steps
+// into effective paces and generates a playlist with a segment per step
+func (s *SpotifyServiceImpl) GenerateTreadmillPlaylist(ctx context.Context, internalUserID string, req types.TreadmillRequest) (*TreadmillResponse, error) {
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	repeat := max(req.Repeat, 1)
+	if len(req.Steps)*repeat > maxWorkoutSegments {
+		return nil, fmt.Errorf("%w: program expands to %d steps, the maximum is %d", ErrInvalidRequest, len(req.Steps)*repeat, maxWorkoutSegments)
+	}
+
+	speedUnit := req.SpeedUnit
+	if speedUnit == "" {
+		speedUnit = "kmh"
+	}
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	meters := unitMeters(paceUnit)
+
+	response := &TreadmillResponse{SpeedUnit: speedUnit, PaceUnit: paceUnit}
+	var plan []plannedSegment
+	cursor := 0
+	for r := 0; r < repeat; r++ {
+		for _, step := range req.Steps {
+			speed := treadmillSpeed(step.Speed, speedUnit)
+			activity, effective := treadmillEffort(speed, step.InclinePercent)
+			pace := int(math.Round(meters / speed))
+			effectivePace := int(math.Round(meters / effective))
+			targetBPM := utils.TargetBPM(activity, utils.ActivityInput{PaceInSeconds: effectivePace, PaceUnit: paceUnit})
+
+			segmentType := step.Type
+			if segmentType == "" {
+				segmentType = SegmentWork
+			}
+
+			response.Steps = append(response.Steps, TreadmillStepPlan{
+				Index:                len(response.Steps),
+				Type:                 segmentType,
+				Activity:             activity.Name(),
+				DurationSeconds:      step.DurationSeconds,
+				Speed:                step.Speed,
+				InclinePercent:       step.InclinePercent,
+				PaceSeconds:          pace,
+				Pace:                 formatClock(pace),
+				EffectivePaceSeconds: effectivePace,
+				EffectivePace:        formatClock(effectivePace),
+				TargetBPM:            targetBPM,
+			})
+			plan = append(plan, plannedSegment{
+				Type:        segmentType,
+				PaceSeconds: effectivePace,
+				TargetBPM:   targetBPM,
+				StartMs:     cursor,
+				EndMs:       cursor + step.DurationSeconds*1000,
+			})
+			cursor += step.DurationSeconds * 1000
+		}
+	}
+
+	workout, err := s.generateFromPlan(ctx, internalUserID, plan, paceUnit, req.WorkoutName, filters, playlistRecord{
+		Kind:    PlaylistKindTreadmill,
+		Filters: req.TrackFilterParams,
+		Inputs:  req,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate treadmill playlist: %v", err)
+	}
+	response.Workout = workout
+	return response, nil
+}
+
+// treadmillSpeed converts a belt speed in km/h or mph to m/s
+func treadmillSpeed(speed float64, speedUnit string) float64 {
+	if speedUnit == "mph" {
+		return speed * metersPerMile / 3600
+	}
+	return speed * 1000 / 3600
+}
+
+// treadmillEffort picks the cadence model for a belt speed and returns it with the
+// flat-ground speed that takes the same effort. Walking speeds use the walking model
+// with Tobler's hiking function for the incline; running speeds use the running model
+// with the ACSM running equation.
+func treadmillEffort(speed float64, inclinePercent float64) (utils.Activity, float64) {
+	if speed < treadmillRunSpeed {
+		return utils.Walking{}, utils.FlatWalkingSpeed(speed, inclinePercent)
+	}
+	return utils.Running{}, flatEquivalentSpeed(speed, inclinePercent)
+}
+
+// flatEquivalentSpeed returns the flat-ground speed with the same energy cost as
+// running at speed (m/s) up inclinePercent, from the ACSM running equation
+func flatEquivalentSpeed(speed float64, inclinePercent float64) float64 {
+	return speed * (1 + acsmGradeFactor*inclinePercent/100)
+}
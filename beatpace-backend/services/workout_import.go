@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/workoutfile"
+)
+
+const (
+	// defaultOpenStepSeconds is the length assumed for steps that end on the lap button
+	defaultOpenStepSeconds = 300
+	// maxWorkoutSegments matches the segment limit of a workout playlist request
+	maxWorkoutSegments = 100
+)
+
+// ImportedWorkoutResponse is the shape returned by ImportWorkout
+type ImportedWorkoutResponse struct {
+	Format   string                   `json:"format"`
+	Name     string                   `json:"name"`
+	Sport    string                   `json:"sport"`
+	PaceUnit string                   `json:"paceUnit"`
+	Segments []types.WorkoutSegment   `json:"segments"`
+	Warnings []string                 `json:"warnings"`
+	Workout  *WorkoutPlaylistResponse `json:"workout,omitempty"` // Omitted for previews
+}
+
+// ImportWorkout converts a TCX or FIT workout file into workout segments and, unless
+// only a preview is requested, generates the workout playlist
+func (s *SpotifyServiceImpl) ImportWorkout(ctx context.Context, internalUserID string, data []byte, form types.ImportWorkoutForm) (*ImportedWorkoutResponse, error) {
+	workout, err := workoutfile.Parse(data)
+	if err != nil {
+		if errors.Is(err, workoutfile.ErrUnknownFormat) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		return nil, fmt.Errorf("%w: invalid workout file: %v", ErrInvalidRequest, err)
+	}
+
+	paceUnit := normalizePaceUnit(form.PaceUnit)
+	openSeconds := form.OpenStepSeconds
+	if openSeconds == 0 {
+		openSeconds = defaultOpenStepSeconds
+	}
+
+	converter := &workoutConverter{
+		unitMeters:  unitMeters(paceUnit),
+		defaultPace: form.DefaultPaceSeconds,
+		openSeconds: openSeconds,
+	}
+	// Count before expanding: nested repeats multiply, so converting first would
+	// do the work the limit is meant to prevent
+	if _, err := expandedSegments(workout.Steps, ""); err != nil {
+		return nil, err
+	}
+	if err := converter.convert(workout.Steps, ""); err != nil {
+		return nil, err
+	}
+	if len(converter.segments) == 0 {
+		return nil, fmt.Errorf("%w: workout has no steps", ErrInvalidRequest)
+	}
+
+	response := &ImportedWorkoutResponse{
+		Format:   workout.Format,
+		Name:     workout.Name,
+		Sport:    workout.Sport,
+		PaceUnit: paceUnit,
+		Segments: converter.segments,
+		Warnings: converter.warnings,
+	}
+	if form.Preview {
+		return response, nil
+	}
+
+	generated, err := s.GenerateWorkoutPlaylist(ctx, internalUserID, types.WorkoutPlaylistRequest{
+		Name:     workout.Name,
+		PaceUnit: paceUnit,
+		Segments: converter.segments,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response.Workout = generated
+	return response, nil
+}
+
+// workoutConverter flattens file steps into segments, expanding repeats
+type workoutConverter struct {
+	unitMeters  float64
+	defaultPace int
+	openSeconds int
+	segments    []types.WorkoutSegment
+	warnings    []string
+}
+
+func (c *workoutConverter) convert(steps []workoutfile.Step, prefix string) error {
+	for i, step := range steps {
+		label := fmt.Sprintf("%s%d", prefix, i+1)
+		if step.Name != "" {
+			label = fmt.Sprintf("%s (%s)", label, step.Name)
+		}
+
+		if step.Repetitions > 0 {
+			for rep := 0; rep < step.Repetitions; rep++ {
+				if err := c.convert(step.Steps, fmt.Sprintf("%s%d.", prefix, i+1)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		segment := types.WorkoutSegment{Type: segmentTypeForIntensity(step.Intensity)}
+		switch step.DurationType {
+		case workoutfile.DurationTime:
+			segment.DurationSeconds = max(1, int(math.Round(step.DurationSeconds)))
+		case workoutfile.DurationDistance:
+			segment.DistanceMeters = step.DistanceMeters
+		default:
+			segment.DurationSeconds = c.openSeconds
+			c.warn(fmt.Sprintf("step %s has no fixed length; assumed %s", label, formatClock(c.openSeconds)))
+		}
+
+		switch {
+		case step.HasSpeedTarget():
+			segment.PaceSeconds = int(math.Round(c.unitMeters / step.TargetSpeed()))
+		case c.defaultPace > 0:
+			segment.PaceSeconds = c.defaultPace
+			c.warn(fmt.Sprintf("step %s has no pace target; used the default pace %s", label, formatClock(c.defaultPace)))
+		default:
+			return fmt.Errorf("%w: step %s has no pace target; set defaultPaceSeconds", ErrInvalidRequest, label)
+		}
+
+		c.segments = append(c.segments, segment)
+	}
+	return nil
+}
+
+// expandedSegments counts the segments steps expand to, failing as soon as the
+// count passes maxWorkoutSegments or a repeat has nothing to repeat
+func expandedSegments(steps []workoutfile.Step, prefix string) (int, error) {
+	total := 0
+	for i, step := range steps {
+		count := 1
+		if step.Repetitions > 0 {
+			inner, err := expandedSegments(step.Steps, fmt.Sprintf("%s%d.", prefix, i+1))
+			if err != nil {
+				return 0, err
+			}
+			if inner == 0 {
+				return 0, fmt.Errorf("%w: repeat step %s%d has nothing to repeat", ErrInvalidRequest, prefix, i+1)
+			}
+			if step.Repetitions > maxWorkoutSegments/inner {
+				return 0, fmt.Errorf("%w: workout expands to more than %d segments", ErrInvalidRequest, maxWorkoutSegments)
+			}
+			count = inner * step.Repetitions
+		}
+		total += count
+		if total > maxWorkoutSegments {
+			return 0, fmt.Errorf("%w: workout expands to more than %d segments", ErrInvalidRequest, maxWorkoutSegments)
+		}
+	}
+	return total, nil
+}
+
+// warn records a warning once, so repeated steps do not flood the response
+func (c *workoutConverter) warn(message string) {
+	for _, existing := range c.warnings {
+		if existing == message {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, message)
+}
+
+// segmentTypeForIntensity maps a file step intensity to a workout segment type
+func segmentTypeForIntensity(intensity string) string {
+	switch intensity {
+	case workoutfile.IntensityWarmup:
+		return SegmentWarmup
+	case workoutfile.IntensityCooldown:
+		return SegmentCooldown
+	case workoutfile.IntensityRest, workoutfile.IntensityRecovery:
+		return SegmentRecovery
+	}
+	return SegmentWork
+}
+
+// unitMeters returns the length of a pace unit in meters
+func unitMeters(paceUnit string) float64 {
+	if paceUnit == "mile" {
+		return 1609.34
+	}
+	return 1000
+}
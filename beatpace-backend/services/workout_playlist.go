@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yimango/beatpace-backend/types"
+	"github.com/yimango/beatpace-backend/utils"
+)
+
+// Workout segment types
+const (
+	SegmentWarmup   = "warmup"
+	SegmentWork     = "work"
+	SegmentRecovery = "recovery"
+	SegmentCooldown = "cooldown"
+)
+
+const (
+	// workoutTempoTolerance matches the ±10 BPM window used for single playlists
+	workoutTempoTolerance = 10
+	// estimatedTempoCost is the tempo penalty given to tracks without measured tempo,
+	// so close measured matches are preferred but unmeasured tracks can still fill
+	estimatedTempoCost = workoutTempoTolerance / 2
+	// boundarySecondCost converts seconds between a track change and a segment
+	// boundary into the same units as BPM error: 5 seconds off costs 1 BPM
+	boundarySecondCost = 0.2
+	// minTrackMs matches the shortest track the search accepts
+	minTrackMs = 60000
+)
+
+// WorkoutPlaylistResponse is the shape returned by GenerateWorkoutPlaylist
+type WorkoutPlaylistResponse struct {
+	Playlist *PlaylistResponse `json:"playlist"`
+	Timeline WorkoutTimeline   `json:"timeline"`
+}
+
+// WorkoutTimeline maps each workout segment to the tracks that cover it
+type WorkoutTimeline struct {
+	PlannedMs int               `json:"plannedMs"`
+	TracksMs  int               `json:"tracksMs"`
+	Segments  []TimelineSegment `json:"segments"`
+}
+
+// TimelineSegment is one planned segment and its tracks. BoundaryOffsetMs is the
+// nearest track change minus the planned segment end: positive means the music
+// changes after the boundary, negative before it.
+type TimelineSegment struct {
+	Index            int             `json:"index"`
+	Type             string          `json:"type"`
+	PaceSeconds      int             `json:"paceSeconds"`
+	Pace             string          `json:"pace"`
+	TargetBPM        int             `json:"targetBpm"`
+	StartMs          int             `json:"startMs"`
+	EndMs            int             `json:"endMs"`
+	BoundaryOffsetMs int             `json:"boundaryOffsetMs"`
+	Tracks           []TimelineTrack `json:"tracks"`
+}
+
+// TimelineTrack is a playlist track with its start time in the workout
+type TimelineTrack struct {
+	PlaylistTrack
+	Position int `json:"position"`
+	StartMs  int `json:"startMs"`
+}
+
+// plannedSegment is a workout segment with its timing and tempo resolved
+type plannedSegment struct {
+	Type        string
+	PaceSeconds int
+	TargetBPM   int
+	StartMs     int
+	EndMs       int
+}
+
+// placedTrack is a track placed on the workout timeline
+type placedTrack struct {
+	TrackInfo
+	Segment int
+	StartMs int
+}
+
+// GenerateWorkoutPlaylist builds one ordered playlist in which each segment of the
+// workout is covered by tracks at that segment's tempo
+func (s *SpotifyServiceImpl) GenerateWorkoutPlaylist(ctx context.Context, internalUserID string, req types.WorkoutPlaylistRequest) (*WorkoutPlaylistResponse, error) {
+	filters, err := NewTrackFilters(req.TrackFilterParams, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Saved templates and training plans generate later, so the body settings come
+	// from the user's preferences as they are now
+	received := req
+	prefs, err := loadPreferences(ctx, s.prefsRepo, internalUserID)
+	if err != nil {
+		fmt.Printf("Failed to load preferences: %v\n", err)
+	}
+	applied := applyCadencePreferences(&req.CadenceSettings, prefs)
+
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	tempo, err := paceTempo(req.CadenceSettings, paceUnit)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planWorkoutSegments(req.Segments, paceUnit, tempo)
+	if err != nil {
+		return nil, err
+	}
+
+	workout, err := s.generateFromPlan(ctx, internalUserID, plan, paceUnit, req.Name, filters, playlistRecord{
+		Kind:     PlaylistKindWorkout,
+		Activity: req.Activity,
+		Filters:  req.TrackFilterParams,
+		Inputs:   received,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate workout playlist: %v", err)
+	}
+	workout.Playlist.PreferencesApplied = applied
+	return workout, nil
+}
+
+// generateFromPlan generates the playlist for an already planned workout and records it
+// in the history under the fastest segment's pace and tempo
+func (s *SpotifyServiceImpl) generateFromPlan(ctx context.Context, internalUserID string, plan []plannedSegment, paceUnit string, workoutName string, filters TrackFilters, record playlistRecord) (*WorkoutPlaylistResponse, error) {
+	templates := loadPlaylistTemplates(ctx, s.templateRepo, internalUserID)
+
+	// Name the playlist after the hardest part of the workout
+	fastest := plan[0]
+	for _, segment := range plan {
+		if segment.TargetBPM > fastest.TargetBPM {
+			fastest = segment
+		}
+	}
+
+	generator := s.newGenerator(ctx, internalUserID)
+	generated, placed, err := generator.GenerateWorkout(ctx, internalUserID, plan, PlaylistOptions{
+		Templates: templates,
+		NameData:  NewPlaylistNameData(fastest.PaceSeconds, paceUnit, fastest.TargetBPM, fastest.TargetBPM, workoutName),
+		Filters:   filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &WorkoutPlaylistResponse{
+		Playlist: newPlaylistResponse(generated),
+		Timeline: buildWorkoutTimeline(plan, placed),
+	}
+	response.Playlist.CadenceProgramIgnoredStage = ignoredProgramStage(ctx, s.programRepo, internalUserID)
+	record.PaceSeconds = fastest.PaceSeconds
+	record.PaceUnit = paceUnit
+	record.TargetBPM = fastest.TargetBPM
+	record.Feedback = generator.feedbackEffects(generated.Tracks)
+	s.recordPlaylist(ctx, internalUserID, record, generated, response.Playlist)
+	return response, nil
+}
+
+// paceTempo returns a function mapping a pace in seconds per unit to target BPM under
+// the requested cadence model. Cycling has no pace targets, so it is rejected here.
+func paceTempo(settings types.CadenceSettings, paceUnit string) (func(int) int, error) {
+	activity, err := utils.NewActivity(settings.Activity, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if activity.Name() == utils.ActivityCycling {
+		return nil, fmt.Errorf("%w: cycling workouts have no pace targets", ErrInvalidRequest)
+	}
+
+	base := utils.ActivityInput{PaceUnit: paceUnit, GradePercent: settings.GradePercent}
+	switch {
+	case settings.StrideLengthCm > 0:
+		base.StepLength = settings.StrideLengthCm / 100
+	case settings.Height > 0:
+		base.StepLength = utils.CalculateStrideLength(settings.Height, settings.HeightUnit, settings.Gender)
+	}
+	return func(paceSeconds int) int {
+		input := base
+		input.PaceInSeconds = paceSeconds
+		return utils.TargetBPM(activity, input)
+	}, nil
+}
+
+// planWorkoutSegments resolves each segment's duration and target BPM and lays the
+// segments end to end
+func planWorkoutSegments(segments []types.WorkoutSegment, paceUnit string, tempo func(int) int) ([]plannedSegment, error) {
+	unitMeters := unitMeters(paceUnit)
+
+	plan := make([]plannedSegment, 0, len(segments))
+	cursor := 0
+	for i, segment := range segments {
+		var durationMs int
+		switch {
+		case segment.DurationSeconds > 0 && segment.DistanceMeters > 0:
+			return nil, fmt.Errorf("%w: segment %d sets both durationSeconds and distanceMeters", ErrInvalidRequest, i+1)
+		case segment.DurationSeconds > 0:
+			durationMs = segment.DurationSeconds * 1000
+		case segment.DistanceMeters > 0:
+			durationMs = int(segment.DistanceMeters / unitMeters * float64(segment.PaceSeconds) * 1000)
+		default:
+			return nil, fmt.Errorf("%w: segment %d needs durationSeconds or distanceMeters", ErrInvalidRequest, i+1)
+		}
+
+		plan = append(plan, plannedSegment{
+			Type:        segment.Type,
+			PaceSeconds: segment.PaceSeconds,
+			TargetBPM:   tempo(segment.PaceSeconds),
+			StartMs:     cursor,
+			EndMs:       cursor + durationMs,
+		})
+		cursor += durationMs
+	}
+	return plan, nil
+}
+
+// GenerateWorkout collects and enriches one pool, fits tracks to each planned segment
+// in order, and publishes the result as a single playlist
+func (s *PlaylistGenerator) GenerateWorkout(ctx context.Context, userID string, plan []plannedSegment, opts PlaylistOptions) (*GeneratedPlaylist, []placedTrack, error) {
+	if len(plan) == 0 {
+		return nil, nil, fmt.Errorf("workout has no segments")
+	}
+
+	// Seed the search at the median segment tempo; tempos come from enrichment
+	tempos := make([]int, len(plan))
+	for i, segment := range plan {
+		tempos[i] = segment.TargetBPM
+	}
+	sort.Ints(tempos)
+
+	return s.generateTimedPlaylist(ctx, userID, tempos[len(tempos)/2], opts, func(pool []TrackInfo) ([]placedTrack, error) {
+		return fitTracksToSegments(pool, plan)
+	})
+}
+
+// generateTimedPlaylist builds one candidate pool seeded at seedBPM, enriches its tempos,
+// lets fit place tracks on a timeline and publishes them in order
+func (s *PlaylistGenerator) generateTimedPlaylist(ctx context.Context, userID string, seedBPM int, opts PlaylistOptions, fit func([]TrackInfo) ([]placedTrack, error)) (*GeneratedPlaylist, []placedTrack, error) {
+	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
+	defer cancel()
+
+	client := s.spotifyService.GetClient(ctx, userID)
+	if client == nil {
+		fmt.Printf("PlaylistGenerator: Failed to get Spotify client\n")
+		return nil, nil, fmt.Errorf("failed to get spotify client")
+	}
+
+	pool, err := s.collectCandidates(ctx, client, seedBPM, opts.Filters, opts.Genres)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.enrichTempos(ctx, client, pool)
+	pool = s.applyFeedback(pool)
+
+	placed, err := fit(pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(placed) == 0 {
+		fmt.Printf("PlaylistGenerator: No suitable tracks found\n")
+		return nil, nil, fmt.Errorf("no suitable tracks found")
+	}
+
+	tracks := make([]TrackInfo, len(placed))
+	for i, p := range placed {
+		tracks[i] = p.TrackInfo
+	}
+
+	name, description := RenderPlaylistName(opts.Templates, opts.NameData.WithDuration(totalDurationMs(tracks)))
+	playlist, err := s.publishPlaylist(ctx, client, userID, name, description, tracks, opts.Public)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &GeneratedPlaylist{Playlist: playlist, Tracks: tracks}, placed, nil
+}
+
+// fitTracksToSegments walks the plan in order, filling each segment with unused tracks
+// close to its tempo. Near a boundary it weighs tempo error against how far the next
+// track change would land from the boundary, and may stop a segment early instead of
+// overrunning it. Timing drift does not accumulate: every segment aims at its planned end.
+// A segment that runs out of tracks fails the fit, since every later track would
+// start early and land in the wrong segment.
+func fitTracksToSegments(pool []TrackInfo, plan []plannedSegment) ([]placedTrack, error) {
+	used := make(map[int]bool)
+	var placed []placedTrack
+	cursor := 0
+
+	for index, segment := range plan {
+		for cursor < segment.EndMs {
+			remaining := segment.EndMs - cursor
+
+			best := -1
+			bestCost := math.Inf(1)
+			for i, track := range pool {
+				if used[i] {
+					continue
+				}
+				tempoCost, ok := segmentTempoCost(track, segment.TargetBPM)
+				if !ok {
+					continue
+				}
+				cost := tempoCost
+				if gap := remaining - int(track.Track.Duration); gap < minTrackMs {
+					// This track would be the last of the segment; charge for missing the boundary
+					cost += math.Abs(float64(gap)) / 1000 * boundarySecondCost
+				}
+				if cost < bestCost {
+					best, bestCost = i, cost
+				}
+			}
+
+			if best == -1 {
+				fmt.Printf("PlaylistGenerator: Ran out of tracks for segment %d (%d BPM)\n", index+1, segment.TargetBPM)
+				return nil, fmt.Errorf("not enough suitable tracks for segment %d (%d BPM)", index+1, segment.TargetBPM)
+			}
+
+			// Ending the segment here moves the change forward by the remaining time
+			if remaining < minTrackMs && float64(remaining)/1000*boundarySecondCost <= bestCost {
+				break
+			}
+
+			track := pool[best]
+			if !track.TempoMeasured {
+				track.BPM = float32(segment.TargetBPM)
+			}
+			used[best] = true
+			placed = append(placed, placedTrack{TrackInfo: track, Segment: index, StartMs: cursor})
+			cursor += int(track.Track.Duration)
+		}
+	}
+	return placed, nil
+}
+
+// segmentTempoCost is the BPM error of a track for a target, or false if it is out of
+// tolerance. Tracks without measured tempo get a flat penalty. Feedback shifts the cost,
+// so a liked track can beat a slightly closer one.
+func segmentTempoCost(track TrackInfo, targetBPM int) (float64, bool) {
+	feedback := track.Feedback * feedbackBPMWeight
+	if !track.TempoMeasured {
+		return math.Max(0, estimatedTempoCost-feedback), true
+	}
+	distance := tempoDistance(track.BPM, float64(targetBPM))
+	return math.Max(0, distance-feedback), distance <= workoutTempoTolerance
+}
+
+// buildWorkoutTimeline groups placed tracks by segment and measures how close the
+// nearest track change falls to each segment boundary
+func buildWorkoutTimeline(plan []plannedSegment, placed []placedTrack) WorkoutTimeline {
+	timeline := WorkoutTimeline{}
+	if len(plan) > 0 {
+		timeline.PlannedMs = plan[len(plan)-1].EndMs
+	}
+
+	// Every point where one track ends and the next starts, plus the playlist end
+	var changes []int
+	for i, p := range placed {
+		if i > 0 {
+			changes = append(changes, p.StartMs)
+		}
+		timeline.TracksMs = p.StartMs + int(p.Track.Duration)
+	}
+	changes = append(changes, timeline.TracksMs)
+
+	for index, segment := range plan {
+		entry := TimelineSegment{
+			Index:            index,
+			Type:             segment.Type,
+			PaceSeconds:      segment.PaceSeconds,
+			Pace:             formatClock(segment.PaceSeconds),
+			TargetBPM:        segment.TargetBPM,
+			StartMs:          segment.StartMs,
+			EndMs:            segment.EndMs,
+			BoundaryOffsetMs: nearestChangeOffset(changes, segment.EndMs),
+		}
+		for position, p := range placed {
+			if p.Segment == index {
+				entry.Tracks = append(entry.Tracks, TimelineTrack{
+					PlaylistTrack: newPlaylistTrack(p.TrackInfo),
+					Position:      position,
+					StartMs:       p.StartMs,
+				})
+			}
+		}
+		timeline.Segments = append(timeline.Segments, entry)
+	}
+	return timeline
+}
+
+// nearestChangeOffset returns the signed distance from boundary to the closest change
+func nearestChangeOffset(changes []int, boundary int) int {
+	best := 0
+	for i, change := range changes {
+		if i == 0 || abs(change-boundary) < abs(best) {
+			best = change - boundary
+		}
+	}
+	return best
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yimango/beatpace-backend/model"
+	"github.com/yimango/beatpace-backend/repository"
+	"github.com/yimango/beatpace-backend/types"
+)
+
+// WorkoutTemplate is a saved workout as returned by the API
+type WorkoutTemplate struct {
+	ID             string               `json:"id"`
+	Name           string               `json:"name"`
+	Description    string               `json:"description"`
+	PaceUnit       string               `json:"paceUnit"`
+	Blocks         []types.WorkoutBlock `json:"blocks"`
+	SegmentCount   int                  `json:"segmentCount"`   // Segments after expanding repeats
+	PlannedSeconds int                  `json:"plannedSeconds"` // Total planned duration
+	CreatedAt      time.Time            `json:"createdAt"`
+	UpdatedAt      time.Time            `json:"updatedAt"`
+}
+
+type workoutService struct {
+	workoutRepo    repository.WorkoutRepository
+	spotifyService SpotifyService
+}
+
+func NewWorkoutService(workoutRepo repository.WorkoutRepository, spotifyService SpotifyService) WorkoutService {
+	return &workoutService{
+		workoutRepo:    workoutRepo,
+		spotifyService: spotifyService,
+	}
+}
+
+// ListWorkouts returns the user's saved workouts, most recently updated first
+func (s *workoutService) ListWorkouts(ctx context.Context, userID string) ([]WorkoutTemplate, error) {
+	workouts, err := s.workoutRepo.ListWorkouts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]WorkoutTemplate, 0, len(workouts))
+	for i := range workouts {
+		template, err := newWorkoutTemplate(&workouts[i])
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	return templates, nil
+}
+
+// GetWorkout returns one of the user's saved workouts
+func (s *workoutService) GetWorkout(ctx context.Context, userID string, id string) (*WorkoutTemplate, error) {
+	workout, err := s.getWorkout(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return newWorkoutTemplate(workout)
+}
+
+// CreateWorkout validates and stores a new workout template
+func (s *workoutService) CreateWorkout(ctx context.Context, userID string, req types.WorkoutTemplateRequest) (*WorkoutTemplate, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	now := time.Now()
+	workout := &model.Workout{
+		ID:        uuid.New(),
+		UserID:    uid,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := applyWorkoutRequest(workout, req); err != nil {
+		return nil, err
+	}
+	if err := s.workoutRepo.CreateWorkout(ctx, workout); err != nil {
+		return nil, fmt.Errorf("failed to create workout: %v", err)
+	}
+	return newWorkoutTemplate(workout)
+}
+
+// UpdateWorkout replaces a workout template's name, description and blocks
+func (s *workoutService) UpdateWorkout(ctx context.Context, userID string, id string, req types.WorkoutTemplateRequest) (*WorkoutTemplate, error) {
+	workout, err := s.getWorkout(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyWorkoutRequest(workout, req); err != nil {
+		return nil, err
+	}
+	workout.UpdatedAt = time.Now()
+	if err := s.workoutRepo.UpdateWorkout(ctx, workout); err != nil {
+		return nil, fmt.Errorf("failed to update workout: %v", err)
+	}
+	return newWorkoutTemplate(workout)
+}
+
+// DeleteWorkout removes a workout template
+func (s *workoutService) DeleteWorkout(ctx context.Context, userID string, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%w: workout %s", ErrNotFound, id)
+	}
+	err := s.workoutRepo.DeleteWorkout(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: workout %s", ErrNotFound, id)
+	}
+	return err
+}
+
+// DuplicateWorkout copies a workout template under a new ID, marking the name as a copy
+func (s *workoutService) DuplicateWorkout(ctx context.Context, userID string, id string) (*WorkoutTemplate, error) {
+	workout, err := s.getWorkout(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	workout.ID = uuid.New()
+	workout.Name = truncateRunes(workout.Name+" (copy)", 100)
+	workout.CreatedAt = now
+	workout.UpdatedAt = now
+	if err := s.workoutRepo.CreateWorkout(ctx, workout); err != nil {
+		return nil, fmt.Errorf("failed to duplicate workout: %v", err)
+	}
+	return newWorkoutTemplate(workout)
+}
+
+// GenerateFromWorkout expands a workout template and generates its playlist with the
+// cadence settings given now, or the user's current preferences for those left out,
+// so saved paces follow the user's current cadence model
+func (s *workoutService) GenerateFromWorkout(ctx context.Context, userID string, id string, req types.GenerateFromWorkoutRequest) (*WorkoutPlaylistResponse, error) {
+	template, err := s.GetWorkout(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.spotifyService.GenerateWorkoutPlaylist(ctx, userID, types.WorkoutPlaylistRequest{
+		Name:              template.Name,
+		PaceUnit:          template.PaceUnit,
+		Segments:          ExpandWorkoutBlocks(template.Blocks),
+		CadenceSettings:   req.CadenceSettings,
+		TrackFilterParams: req.TrackFilterParams,
+	})
+}
+
+func (s *workoutService) getWorkout(ctx context.Context, userID string, id string) (*model.Workout, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("%w: workout %s", ErrNotFound, id)
+	}
+	workout, err := s.workoutRepo.GetWorkout(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: workout %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return workout, nil
+}
+
+// ExpandWorkoutBlocks flattens blocks into the segment list they describe
+func ExpandWorkoutBlocks(blocks []types.WorkoutBlock) []types.WorkoutSegment {
+	var segments []types.WorkoutSegment
+	for _, block := range blocks {
+		for i := 0; i < max(block.Repeat, 1); i++ {
+			segments = append(segments, block.Segments...)
+		}
+	}
+	return segments
+}
+
+// applyWorkoutRequest validates the request and copies it onto the workout
+func applyWorkoutRequest(workout *model.Workout, req types.WorkoutTemplateRequest) error {
+	paceUnit := normalizePaceUnit(req.PaceUnit)
+	segments := ExpandWorkoutBlocks(req.Blocks)
+	if len(segments) > maxWorkoutSegments {
+		return fmt.Errorf("%w: workout expands to %d segments, the maximum is %d", ErrInvalidRequest, len(segments), maxWorkoutSegments)
+	}
+	if _, err := planWorkoutSegments(segments, paceUnit, func(int) int { return 0 }); err != nil {
+		return err
+	}
+
+	blocks, err := json.Marshal(req.Blocks)
+	if err != nil {
+		return fmt.Errorf("failed to encode workout blocks: %v", err)
+	}
+
+	workout.Name = req.Name
+	workout.Description = req.Description
+	workout.PaceUnit = paceUnit
+	workout.Blocks = string(blocks)
+	return nil
+}
+
+// newWorkoutTemplate decodes a stored workout for the API
+func newWorkoutTemplate(workout *model.Workout) (*WorkoutTemplate, error) {
+	var blocks []types.WorkoutBlock
+	if err := json.Unmarshal([]byte(workout.Blocks), &blocks); err != nil {
+		return nil, fmt.Errorf("failed to decode workout %s: %v", workout.ID, err)
+	}
+
+	segments := ExpandWorkoutBlocks(blocks)
+	template := &WorkoutTemplate{
+		ID:           workout.ID.String(),
+		Name:         workout.Name,
+		Description:  workout.Description,
+		PaceUnit:     workout.PaceUnit,
+		Blocks:       blocks,
+		SegmentCount: len(segments),
+		CreatedAt:    workout.CreatedAt,
+		UpdatedAt:    workout.UpdatedAt,
+	}
+	if plan, err := planWorkoutSegments(segments, workout.PaceUnit, func(int) int { return 0 }); err == nil && len(plan) > 0 {
+		template.PlannedSeconds = plan[len(plan)-1].EndMs / 1000
+	}
+	return template, nil
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // RegisterRequest represents the registration request payload
 type RegisterRequest struct {
 	SpotifyUserID string  `json:"spotify_user_id" binding:"required"`
@@ -11,9 +13,327 @@ type LoginRequest struct {
 	SpotifyUserID string `json:"spotify_user_id" binding:"required"`
 }
 
-// GeneratePlaylistRequest represents the playlist generation request payload
+// GeneratePlaylistRequest represents the playlist generation request payload. Omitted
+// profile and default fields are filled in from the user's stored preferences.
 type GeneratePlaylistRequest struct {
-	PaceInSeconds int     `json:"paceInSeconds" binding:"required"`
-	Gender        string  `json:"gender" binding:"required"`
-	Height        float64 `json:"height" binding:"required"`
-} 
\ No newline at end of file
+	PaceInSeconds  int      `json:"paceInSeconds" binding:"omitempty,min=1"` // Required unless cycling or Zone is set
+	Gender         string   `json:"gender"`
+	Height         float64  `json:"height" binding:"omitempty,gt=0"`
+	HeightUnit     string   `json:"heightUnit" binding:"omitempty,oneof=cm in"`            // "cm" (default) or "in"
+	StrideLengthCm float64  `json:"strideLengthCm" binding:"omitempty,min=30,max=250"`     // Overrides the height-based stride
+	PaceUnit       string   `json:"paceUnit"`                                              // "km" (default) or "mile"
+	WorkoutName    string   `json:"workoutName"`                                           // Optional, available to playlist name templates
+	TempoTolerance *int     `json:"tempoTolerance" binding:"omitempty,min=0,max=20"`       // BPM distance accepted from the target, default 10
+	Genres         []string `json:"genres" binding:"omitempty,max=5,dive,required,max=50"` // Searched alongside the user's top tracks
+	Public         *bool    `json:"public"`                                                // Publish on the user's profile, default private
+
+	// Zone runs at one of the user's stored training zone paces instead of PaceInSeconds
+	Zone string `json:"zone" binding:"omitempty,oneof=easy marathon threshold interval repetition"`
+
+	// IgnoreCadenceProgram uses the pace-derived cadence even when a cadence program is active
+	IgnoreCadenceProgram bool `json:"ignoreCadenceProgram"`
+
+	// Activity selects the cadence model: running (default), walking, hiking or cycling
+	Activity       string  `json:"activity" binding:"omitempty,oneof=running walking hiking cycling"`
+	GradePercent   float64 `json:"gradePercent" binding:"min=-50,max=50"`                     // Average grade, for hiking
+	CadenceRPM     int     `json:"cadenceRpm" binding:"required_if=Activity cycling,max=200"` // Pedal RPM, for cycling
+	CyclingMapping string  `json:"cyclingMapping" binding:"omitempty,oneof=1:1 1:2"`          // Beats per pedal revolution, default 1:1
+
+	// Familiarity is the target fraction (0-1) of tracks already in the user's
+	// library or listening history; omit it to leave the mix unconstrained
+	Familiarity *float64 `json:"familiarity" binding:"omitempty,min=0,max=1"`
+
+	EnvironmentParams
+	TrackFilterParams
+}
+
+// EnvironmentParams are the optional conditions a run happens in. Heat needs the
+// temperature and either the dew point or the humidity.
+type EnvironmentParams struct {
+	Temperature     *float64 `json:"temperature" binding:"omitempty,min=-50,max=140"`
+	DewPoint        *float64 `json:"dewPoint" binding:"omitempty,min=-80,max=140"`
+	TemperatureUnit string   `json:"temperatureUnit" binding:"omitempty,oneof=c f"` // Applies to temperature and dew point, "c" (default) or "f"
+	Humidity        *float64 `json:"humidity" binding:"omitempty,min=0,max=100"`    // Relative humidity in percent
+	Altitude        *float64 `json:"altitude" binding:"omitempty,min=0,max=30000"`
+	AltitudeUnit    string   `json:"altitudeUnit" binding:"omitempty,oneof=m ft"` // "m" (default) or "ft"
+}
+
+// TrackFilterParams holds the optional popularity (0-100), release-era and explicit
+// content filters shared by generation requests; "last 2 years" is releasedWithinYears=2
+type TrackFilterParams struct {
+	MinPopularity       *int   `json:"minPopularity" binding:"omitempty,min=0,max=100"`
+	MaxPopularity       *int   `json:"maxPopularity" binding:"omitempty,min=0,max=100"`
+	ReleaseYearFrom     *int   `json:"releaseYearFrom" binding:"omitempty,min=1900"`
+	ReleaseYearTo       *int   `json:"releaseYearTo" binding:"omitempty,min=1900"`
+	ReleasedWithinYears *int   `json:"releasedWithinYears" binding:"omitempty,min=1"`
+	ExplicitContent     string `json:"explicitContent" binding:"omitempty,oneof=allow exclude"` // "allow" (default) or "exclude"
+}
+
+// PaceLadderRequest represents a batch request for one playlist per pace step,
+// e.g. every 10 seconds from 4:30 to 6:30 per km
+type PaceLadderRequest struct {
+	FromPaceSeconds   int    `json:"fromPaceSeconds" binding:"required,min=1"`
+	ToPaceSeconds     int    `json:"toPaceSeconds" binding:"required,min=1"`
+	StepSeconds       int    `json:"stepSeconds" binding:"required,min=1"`
+	PaceUnit          string `json:"paceUnit"`                                           // "km" (default) or "mile"
+	TracksPerPlaylist int    `json:"tracksPerPlaylist" binding:"omitempty,min=1,max=25"` // Defaults to 10
+	WorkoutName       string `json:"workoutName"`
+	TrackFilterParams
+}
+
+// PlaylistTemplateRequest represents the playlist naming templates payload
+type PlaylistTemplateRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// PlaylistVariantsRequest asks for several playlists at one target tempo, built with
+// different strategies or random seeds, for side-by-side comparison
+type PlaylistVariantsRequest struct {
+	PaceInSeconds int           `json:"paceInSeconds" binding:"required,min=1"`
+	PaceUnit      string        `json:"paceUnit"` // "km" (default) or "mile"
+	WorkoutName   string        `json:"workoutName"`
+	Variants      []VariantSpec `json:"variants" binding:"required,min=1,max=5,dive"`
+	TrackFilterParams
+
+	// IgnoreCadenceProgram uses the pace-derived cadence even when a cadence program is active
+	IgnoreCadenceProgram bool `json:"ignoreCadenceProgram"`
+}
+
+// VariantSpec describes how one variant is built
+type VariantSpec struct {
+	Strategy string `json:"strategy" binding:"required,oneof=familiar discovery high-energy random"`
+	Seed     *int64 `json:"seed"` // Optional; a seed is chosen and returned when omitted
+}
+
+// PublishVariantsRequest lists the variant IDs to publish to Spotify
+type PublishVariantsRequest struct {
+	Variants []string `json:"variants" binding:"required,min=1"`
+	Public   *bool    `json:"public"` // Publish on the user's profile; defaults to the stored preference, else private
+}
+
+// WorkoutPlaylistRequest asks for one ordered playlist covering a structured workout
+type WorkoutPlaylistRequest struct {
+	Name     string           `json:"name"`     // Workout name, available to playlist name templates
+	PaceUnit string           `json:"paceUnit"` // "km" (default) or "mile"; applies to every segment pace
+	Segments []WorkoutSegment `json:"segments" binding:"required,min=1,max=100,dive"`
+	CadenceSettings
+	TrackFilterParams
+}
+
+// WorkoutSegment is one block of a workout at a single target pace. Exactly one of
+// durationSeconds and distanceMeters must be set.
+type WorkoutSegment struct {
+	Type            string  `json:"type" binding:"required,oneof=warmup work recovery cooldown"`
+	DurationSeconds int     `json:"durationSeconds,omitempty" binding:"omitempty,min=1"`
+	DistanceMeters  float64 `json:"distanceMeters,omitempty" binding:"omitempty,gt=0"`
+	PaceSeconds     int     `json:"paceSeconds" binding:"required,min=1"`
+}
+
+// ProgressionRunRequest asks for a playlist whose tempo follows a pace that changes
+// from startPaceSeconds to endPaceSeconds over the run
+type ProgressionRunRequest struct {
+	StartPaceSeconds int    `json:"startPaceSeconds" binding:"required,min=1"`
+	EndPaceSeconds   int    `json:"endPaceSeconds" binding:"required,min=1"`
+	DurationSeconds  int    `json:"durationSeconds" binding:"required,min=60,max=21600"`
+	Shape            string `json:"shape" binding:"omitempty,oneof=linear stepwise"` // "linear" (default) or "stepwise"
+	Steps            int    `json:"steps" binding:"omitempty,min=2,max=20"`          // Number of stepwise paces, default 4
+	PaceUnit         string `json:"paceUnit"`                                        // "km" (default) or "mile"
+	WorkoutName      string `json:"workoutName"`
+	TrackFilterParams
+}
+
+// RacePlanRequest asks for a pace plan and race playlist from a goal distance and time
+type RacePlanRequest struct {
+	Distance               string  `json:"distance" binding:"required,oneof=5k 10k half marathon custom"`
+	DistanceMeters         float64 `json:"distanceMeters" binding:"omitempty,gt=0,max=500000"` // Required for "custom"
+	GoalTimeSeconds        int     `json:"goalTimeSeconds" binding:"required,min=60"`
+	Strategy               string  `json:"strategy" binding:"omitempty,oneof=even negative-split custom"` // "even" (default)
+	SplitDifferenceSeconds *int    `json:"splitDifferenceSeconds" binding:"omitempty,min=0,max=120"`      // Negative split: first-half minus second-half pace per km
+	Splits                 []int   `json:"splits" binding:"omitempty,dive,min=1"`                         // Custom: pace per km in seconds, scaled to the goal time
+	BufferSeconds          *int    `json:"bufferSeconds" binding:"omitempty,min=0,max=3600"`              // Extra music after the goal time, default 300
+	WorkoutName            string  `json:"workoutName"`
+	TrackFilterParams
+}
+
+// ImportWorkoutForm holds the form fields sent alongside an uploaded workout file
+type ImportWorkoutForm struct {
+	PaceUnit           string `form:"paceUnit"`                                           // "km" (default) or "mile"
+	DefaultPaceSeconds int    `form:"defaultPaceSeconds" binding:"omitempty,min=1"`       // Pace for steps without a pace target
+	OpenStepSeconds    int    `form:"openStepSeconds" binding:"omitempty,min=1,max=3600"` // Length assumed for lap-button steps, default 300
+	Preview            bool   `form:"preview"`                                            // Only convert the file, do not create a playlist
+}
+
+// ReplayRunForm holds the form fields sent alongside an uploaded GPX or TCX activity
+type ReplayRunForm struct {
+	PaceUnit      string `form:"paceUnit"` // "km" (default) or "mile"; used for reported paces
+	WorkoutName   string `form:"workoutName"`
+	WindowSeconds int    `form:"windowSeconds" binding:"omitempty,min=10,max=600"` // Rolling window for pace and cadence, default 60
+	Preview       bool   `form:"preview"`                                          // Only analyse the run, do not create a playlist
+}
+
+// TreadmillRequest asks for a playlist following a treadmill program of speed and
+// incline steps
+type TreadmillRequest struct {
+	SpeedUnit   string          `json:"speedUnit" binding:"omitempty,oneof=kmh mph"` // "kmh" (default) or "mph"
+	PaceUnit    string          `json:"paceUnit"`                                    // "km" (default) or "mile"; used for reported paces
+	Steps       []TreadmillStep `json:"steps" binding:"required,min=1,max=100,dive"`
+	Repeat      int             `json:"repeat" binding:"omitempty,min=1,max=20"` // Times the program is run, default 1
+	WorkoutName string          `json:"workoutName"`
+	TrackFilterParams
+}
+
+// TreadmillStep is one step of a treadmill program
+type TreadmillStep struct {
+	Type            string  `json:"type" binding:"omitempty,oneof=warmup work recovery cooldown"` // Default "work"
+	DurationSeconds int     `json:"durationSeconds" binding:"required,min=1"`
+	Speed           float64 `json:"speed" binding:"required,gt=0,max=30"`
+	InclinePercent  float64 `json:"inclinePercent" binding:"min=0,max=40"`
+}
+
+// CadenceSettings select the cadence model used to turn segment paces into tempo.
+// All fields are optional; the default is the running model. Omitted height, gender
+// and stride length are filled from the user's stored preferences at generation time.
+type CadenceSettings struct {
+	Activity       string  `json:"activity" binding:"omitempty,oneof=running walking hiking"`
+	Gender         string  `json:"gender"`
+	Height         float64 `json:"height" binding:"omitempty,gt=0"`
+	HeightUnit     string  `json:"heightUnit" binding:"omitempty,oneof=cm in"`        // "cm" (default) or "in"
+	StrideLengthCm float64 `json:"strideLengthCm" binding:"omitempty,min=30,max=250"` // Overrides the height-based stride
+	GradePercent   float64 `json:"gradePercent" binding:"min=-50,max=50"`             // Average grade, for hiking
+}
+
+// WorkoutTemplateRequest creates or replaces a saved workout template
+type WorkoutTemplateRequest struct {
+	Name        string         `json:"name" binding:"required,max=100"`
+	Description string         `json:"description" binding:"max=500"`
+	PaceUnit    string         `json:"paceUnit"` // "km" (default) or "mile"
+	Blocks      []WorkoutBlock `json:"blocks" binding:"required,min=1,max=50,dive"`
+}
+
+// WorkoutBlock is a group of segments run repeat times in a row, e.g. 6 x (400m, 90s)
+type WorkoutBlock struct {
+	Repeat   int              `json:"repeat" binding:"omitempty,min=1,max=50"` // Default 1
+	Segments []WorkoutSegment `json:"segments" binding:"required,min=1,max=20,dive"`
+}
+
+// GenerateFromWorkoutRequest renders a saved workout template into a playlist
+type GenerateFromWorkoutRequest struct {
+	CadenceSettings
+	TrackFilterParams
+}
+
+// TrainingPlanRequest attaches a schedule of dated sessions to the user. Cadence and
+// filter settings apply to every session's playlist.
+type TrainingPlanRequest struct {
+	Name      string                   `json:"name" binding:"required,max=100"`
+	LeadHours *int                     `json:"leadHours" binding:"omitempty,min=0,max=168"` // Hours before a session its playlist is generated, defaults to 12
+	Sessions  []TrainingSessionRequest `json:"sessions" binding:"required,min=1,max=366,dive"`
+	CadenceSettings
+	TrackFilterParams
+}
+
+// TrainingSessionRequest is one dated session of a training plan
+type TrainingSessionRequest struct {
+	WorkoutID   string    `json:"workoutId" binding:"required,uuid"`
+	ScheduledAt time.Time `json:"scheduledAt" binding:"required"` // RFC 3339 start time
+	Title       string    `json:"title" binding:"max=100"`        // Defaults to the workout name
+	Notes       string    `json:"notes" binding:"max=500"`
+}
+
+// TrainingZonesRequest derives and stores training zones from a recent race result
+type TrainingZonesRequest struct {
+	Distance       string  `json:"distance" binding:"required,oneof=5k 10k half marathon custom"`
+	DistanceMeters float64 `json:"distanceMeters" binding:"omitempty,gte=1500,max=100000"` // Required for "custom"
+	TimeSeconds    int     `json:"timeSeconds" binding:"required,min=180"`
+	Method         string  `json:"method" binding:"omitempty,oneof=vdot riegel"` // "vdot" (default) or "riegel"
+}
+
+// PaceCalcRequest converts between pace, speed and finish time. Exactly one of
+// PaceSeconds, Speed and TimeSeconds is given; a distance is needed for TimeSeconds
+// and to get a finish time back.
+type PaceCalcRequest struct {
+	Distance       string  `form:"distance" binding:"omitempty,oneof=5k 10k half marathon custom"`
+	DistanceMeters float64 `form:"distanceMeters" binding:"omitempty,gt=0,max=500000"` // Required for "custom"
+	PaceSeconds    int     `form:"paceSeconds" binding:"omitempty,min=1"`
+	PaceUnit       string  `form:"paceUnit"` // "km" (default) or "mile"
+	Speed          float64 `form:"speed" binding:"omitempty,gt=0,max=100"`
+	SpeedUnit      string  `form:"speedUnit" binding:"omitempty,oneof=kmh mph"` // "kmh" (default) or "mph"
+	TimeSeconds    int     `form:"timeSeconds" binding:"omitempty,min=1"`
+}
+
+// RacePredictionRequest predicts race times from a known result with Riegel's formula
+type RacePredictionRequest struct {
+	Distance             string   `form:"distance" binding:"required,oneof=5k 10k half marathon custom"`
+	DistanceMeters       float64  `form:"distanceMeters" binding:"omitempty,gt=0,max=500000"` // Required for "custom"
+	TimeSeconds          int      `form:"timeSeconds" binding:"required,min=1"`
+	TargetDistance       string   `form:"targetDistance" binding:"omitempty,oneof=5k 10k half marathon custom"` // Omit to predict every standard distance
+	TargetDistanceMeters float64  `form:"targetDistanceMeters" binding:"omitempty,gt=0,max=500000"`
+	Exponent             *float64 `form:"exponent" binding:"omitempty,min=1,max=1.3"` // Fatigue exponent, default 1.06
+}
+
+// CadenceCalcRequest converts a pace to cadence and music BPM
+type CadenceCalcRequest struct {
+	PaceSeconds    int     `form:"paceSeconds" binding:"required,min=1"`
+	PaceUnit       string  `form:"paceUnit"`                                          // "km" (default) or "mile"
+	Height         float64 `form:"height" binding:"omitempty,gt=0"`                   // Sets the step length for walking and hiking
+	HeightUnit     string  `form:"heightUnit" binding:"omitempty,oneof=cm in"`        // "cm" (default) or "in"
+	StrideLengthCm float64 `form:"strideLengthCm" binding:"omitempty,min=30,max=250"` // Overrides the height-based stride
+	Gender         string  `form:"gender"`
+	Activity       string  `form:"activity" binding:"omitempty,oneof=running walking hiking"`
+	GradePercent   float64 `form:"gradePercent" binding:"min=-50,max=50"` // Average grade, for hiking
+}
+
+// CadenceProgramRequest starts or replaces a cadence retraining program. Give the
+// weekly step either in steps per minute or as a percentage of the starting cadence.
+type CadenceProgramRequest struct {
+	StartCadence  int     `json:"startCadence" binding:"required,min=120,max=220"`
+	TargetCadence int     `json:"targetCadence" binding:"required,min=120,max=220,nefield=StartCadence"`
+	StepSPM       int     `json:"stepSpm" binding:"required_without=StepPercent,omitempty,min=1,max=20"`
+	StepPercent   float64 `json:"stepPercent" binding:"required_without=StepSPM,omitempty,gt=0,max=10"`
+}
+
+// CadenceProgramActionRequest advances or holds the current stage
+type CadenceProgramActionRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// PlaylistListQuery pages and filters the playlist history. From and To accept a date
+// (2006-01-02) or an RFC 3339 timestamp; a date To includes that whole day.
+type PlaylistListQuery struct {
+	Cursor string `form:"cursor"`                                  // nextCursor from the previous page
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"` // Default 20
+	From   string `form:"from"`
+	To     string `form:"to"`
+	MinBPM *int   `form:"minBpm" binding:"omitempty,min=1"`
+	MaxBPM *int   `form:"maxBpm" binding:"omitempty,min=1"`
+}
+
+// TrackFeedbackRequest rates one track of a generated playlist
+type TrackFeedbackRequest struct {
+	TrackID  string `json:"trackId" binding:"required"` // Spotify track ID
+	Feedback string `json:"feedback" binding:"required,oneof=up down wrong-tempo skipped too-slow too-fast"`
+}
+
+// FeedbackImpactQuery selects how many recent playlists the feedback impact covers
+type FeedbackImpactQuery struct {
+	Playlists int `form:"playlists" binding:"omitempty,min=1,max=50"` // Default 10
+}
+
+// PreferencesRequest replaces the user's stored profile and generation defaults
+type PreferencesRequest struct {
+	Height          *float64 `json:"height" binding:"omitempty,gt=0,max=300"`
+	HeightUnit      string   `json:"heightUnit" binding:"omitempty,oneof=cm in"` // "cm" (default) or "in"
+	Gender          string   `json:"gender" binding:"omitempty,oneof=male female"`
+	StrideLengthCm  *float64 `json:"strideLengthCm" binding:"omitempty,min=30,max=250"` // Overrides the height-based stride
+	PaceUnit        string   `json:"paceUnit" binding:"omitempty,oneof=km mile"`        // "km" (default) or "mile"
+	TempoTolerance  *int     `json:"tempoTolerance" binding:"omitempty,min=0,max=20"`   // Default 10, 0 keeps exact tempo matches only
+	ExplicitContent string   `json:"explicitContent" binding:"omitempty,oneof=allow exclude"`
+	Genres          []string `json:"genres" binding:"omitempty,max=5,dive,required,max=50"`
+	PlaylistPublic  bool     `json:"playlistPublic"`
+}
+
+// DeleteAccountQuery holds the options of an account deletion
+type DeleteAccountQuery struct {
+	UnfollowPlaylists bool `form:"unfollowPlaylists"` // Also unfollow generated playlists on Spotify
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+// Activity types
+const (
+	ActivityRunning = "running"
+	ActivityWalking = "walking"
+	ActivityHiking  = "hiking"
+	ActivityCycling = "cycling"
+)
+
+// Cycling cadence mappings: one beat per pedal revolution, or one per pedal stroke
+const (
+	CyclingMappingOneToOne = "1:1"
+	CyclingMappingOneToTwo = "1:2"
+)
+
+// defaultStepLength is the walking step length in meters used when height is unknown
+const defaultStepLength = 0.72
+
+// ActivityInput is everything a cadence model may need. Fields an activity does not
+// use are ignored.
+type ActivityInput struct {
+	PaceInSeconds int     // Time for 1 km or 1 mile
+	PaceUnit      string  // "km" or "mile"
+	StepLength    float64 // Meters per step; zero when unknown
+	GradePercent  float64 // Average grade of the route, for hiking
+	CadenceRPM    float64 // Pedal revolutions per minute, for cycling
+}
+
+// Activity converts what the user is doing into a movement cadence and a music tempo
+type Activity interface {
+	// Name returns the activity type
+	Name() string
+	// Cadence returns steps per minute, or pedal revolutions per minute for cycling
+	Cadence(input ActivityInput) float64
+	// BPM maps a cadence to the music tempo that matches it
+	BPM(cadence float64) float64
+	// TempoBounds returns the range target BPM is kept within
+	TempoBounds() (int, int)
+}
+
+// NewActivity returns the cadence model for an activity type. An empty name means
+// running; cyclingMapping is only used for cycling and defaults to 1:1.
+func NewActivity(name string, cyclingMapping string) (Activity, error) {
+	switch name {
+	case "", ActivityRunning:
+		return Running{}, nil
+	case ActivityWalking:
+		return Walking{}, nil
+	case ActivityHiking:
+		return Hiking{}, nil
+	case ActivityCycling:
+		switch cyclingMapping {
+		case "", CyclingMappingOneToOne:
+			return Cycling{BeatsPerRevolution: 1}, nil
+		case CyclingMappingOneToTwo:
+			return Cycling{BeatsPerRevolution: 2}, nil
+		}
+		return nil, fmt.Errorf("unknown cycling mapping %q", cyclingMapping)
+	}
+	return nil, fmt.Errorf("unknown activity %q", name)
+}
+
+// TargetBPM returns the activity's music tempo for the input, kept within its bounds
+func TargetBPM(activity Activity, input ActivityInput) int {
+	low, high := activity.TempoBounds()
+	bpm := int(activity.BPM(activity.Cadence(input)))
+	return ClampBPM(bpm, low, high)
+}
+
+// ClampBPM keeps bpm within [low, high]
+func ClampBPM(bpm int, low int, high int) int {
+	if bpm < low {
+		return low
+	}
+	if bpm > high {
+		return high
+	}
+	return bpm
+}
+
+// Running is the original BeatPace model: cadence rises linearly as pace quickens,
+// from about 150 steps per minute at 6:00/km to 180 at 5:00/km, one beat per step
+type Running struct{}
+
+func (Running) Name() string { return ActivityRunning }
+
+func (Running) Cadence(input ActivityInput) float64 {
+	return 270 - paceSecondsPerKm(input)/3.0
+}
+
+func (Running) BPM(cadence float64) float64 { return cadence }
+
+func (Running) TempoBounds() (int, int) { return 140, 180 }
+
+// Walking derives step cadence from walking speed and step length, one beat per step
+type Walking struct{}
+
+func (Walking) Name() string { return ActivityWalking }
+
+func (Walking) Cadence(input ActivityInput) float64 {
+	return walkingCadence(speedMetersPerSecond(input), input.StepLength)
+}
+
+func (Walking) BPM(cadence float64) float64 { return cadence }
+
+func (Walking) TempoBounds() (int, int) { return 90, 130 }
+
+// Hiking is walking on a grade. Tobler's hiking function gives how much a grade slows
+// a walker at the same effort; the pace is scaled back to its flat equivalent before
+// cadence is worked out, so a slow steep climb still gets an energetic tempo.
+type Hiking struct{}
+
+func (Hiking) Name() string { return ActivityHiking }
+
+func (Hiking) Cadence(input ActivityInput) float64 {
+	return walkingCadence(FlatWalkingSpeed(speedMetersPerSecond(input), input.GradePercent), input.StepLength)
+}
+
+func (Hiking) BPM(cadence float64) float64 { return cadence }
+
+func (Hiking) TempoBounds() (int, int) { return 80, 130 }
+
+// Cycling maps pedal revolutions per minute to BPM, either one beat per revolution
+// or one per pedal stroke
+type Cycling struct {
+	BeatsPerRevolution int
+}
+
+func (Cycling) Name() string { return ActivityCycling }
+
+func (Cycling) Cadence(input ActivityInput) float64 { return input.CadenceRPM }
+
+func (c Cycling) BPM(cadence float64) float64 {
+	return cadence * float64(max(c.BeatsPerRevolution, 1))
+}
+
+func (c Cycling) TempoBounds() (int, int) {
+	if c.BeatsPerRevolution == 2 {
+		return 120, 200
+	}
+	return 60, 130
+}
+
+// paceSecondsPerKm converts the input pace to seconds per km
+func paceSecondsPerKm(input ActivityInput) float64 {
+	if input.PaceUnit == "mile" {
+		return float64(input.PaceInSeconds) / 1.60934
+	}
+	return float64(input.PaceInSeconds)
+}
+
+// speedMetersPerSecond converts the input pace to a speed
+func speedMetersPerSecond(input ActivityInput) float64 {
+	pace := paceSecondsPerKm(input)
+	if pace <= 0 {
+		return 0
+	}
+	return 1000 / pace
+}
+
+// walkingCadence returns steps per minute at speed for a step length
+func walkingCadence(speed float64, stepLength float64) float64 {
+	if stepLength <= 0 {
+		stepLength = defaultStepLength
+	}
+	return speed / stepLength * 60
+}
+
+// FlatWalkingSpeed returns the flat-ground speed that takes the same effort as walking
+// at speed (m/s) on a grade, from Tobler's hiking function
+func FlatWalkingSpeed(speed float64, gradePercent float64) float64 {
+	return speed * toblerSpeed(0) / toblerSpeed(gradePercent/100)
+}
+
+// toblerSpeed is Tobler's hiking function in km/h for a grade given as a fraction
+func toblerSpeed(grade float64) float64 {
+	return 6 * math.Exp(-3.5*math.Abs(grade+0.05))
+}
@@ -0,0 +1,121 @@
+package utils
+
+import "math"
+
+// Conditions are the weather and altitude a run happens in. Nil fields are unknown.
+type Conditions struct {
+	TemperatureC    *float64
+	DewPointC       *float64
+	HumidityPercent *float64 // Used to derive the dew point when it is not given
+	AltitudeMeters  float64
+}
+
+// PaceAdjustment is how much conditions slow the pace run at the same effort, as
+// percentages of the nominal pace
+type PaceAdjustment struct {
+	DewPointC       *float64 // Dew point used, given or derived from humidity
+	HeatPercent     float64
+	AltitudePercent float64
+	TotalPercent    float64
+	Extreme         bool // Heat index beyond the model's range; hard running is not advised
+}
+
+// Factor returns the multiplier to apply to a pace in seconds
+func (a PaceAdjustment) Factor() float64 {
+	return 1 + a.TotalPercent/100
+}
+
+// heatAdjustmentPoints map temperature plus dew point, both in °F, to the percentage
+// pace slows. This is the widely published temperature + dew point table: no change up
+// to 100, rising to 8-10% at 180. Values between rows are interpolated.
+var heatAdjustmentPoints = []struct{ sum, percent float64 }{
+	{100, 0},
+	{110, 0.5},
+	{120, 1},
+	{130, 2},
+	{140, 3},
+	{150, 4.5},
+	{160, 6},
+	{170, 8},
+	{180, 10},
+}
+
+const (
+	// altitudeThresholdMeters is the altitude, about 3000 ft, below which pace is not
+	// affected
+	altitudeThresholdMeters = 914.4
+	// altitudePercentPer1000m is the slowdown per 1000 m above the threshold, the usual
+	// rule of thumb of 1.25% per 1000 ft that approximates Daniels' altitude tables
+	altitudePercentPer1000m = 1.25 / 0.3048
+)
+
+// AdjustPace works out how much heat, humidity and altitude slow the pace. Heat and
+// altitude combine multiplicatively.
+func AdjustPace(conditions Conditions) PaceAdjustment {
+	var adjustment PaceAdjustment
+
+	dewPoint := conditions.DewPointC
+	if dewPoint == nil && conditions.TemperatureC != nil && conditions.HumidityPercent != nil {
+		derived := DewPoint(*conditions.TemperatureC, *conditions.HumidityPercent)
+		dewPoint = &derived
+	}
+	if conditions.TemperatureC != nil && dewPoint != nil {
+		// Dew point cannot exceed the air temperature
+		dew := math.Min(*dewPoint, *conditions.TemperatureC)
+		adjustment.DewPointC = &dew
+		sum := celsiusToFahrenheit(*conditions.TemperatureC) + celsiusToFahrenheit(dew)
+		adjustment.HeatPercent = HeatAdjustmentPercent(sum)
+		adjustment.Extreme = sum > heatAdjustmentPoints[len(heatAdjustmentPoints)-1].sum
+	}
+
+	adjustment.AltitudePercent = AltitudeAdjustmentPercent(conditions.AltitudeMeters)
+	adjustment.TotalPercent = ((1+adjustment.HeatPercent/100)*(1+adjustment.AltitudePercent/100) - 1) * 100
+	return adjustment
+}
+
+// HeatAdjustmentPercent returns the pace slowdown for a temperature plus dew point sum
+// in °F, capped at the top of the table
+func HeatAdjustmentPercent(sumF float64) float64 {
+	points := heatAdjustmentPoints
+	if sumF <= points[0].sum {
+		return 0
+	}
+	for i := 1; i < len(points); i++ {
+		if sumF <= points[i].sum {
+			lo, hi := points[i-1], points[i]
+			return lo.percent + (sumF-lo.sum)/(hi.sum-lo.sum)*(hi.percent-lo.percent)
+		}
+	}
+	return points[len(points)-1].percent
+}
+
+// AltitudeAdjustmentPercent returns the pace slowdown at an altitude in meters
+func AltitudeAdjustmentPercent(meters float64) float64 {
+	if meters <= altitudeThresholdMeters {
+		return 0
+	}
+	return (meters - altitudeThresholdMeters) / 1000 * altitudePercentPer1000m
+}
+
+// DewPoint derives the dew point in °C from temperature and relative humidity with the
+// Magnus formula
+func DewPoint(temperatureC float64, humidityPercent float64) float64 {
+	const a, b = 17.625, 243.04
+	humidity := math.Max(humidityPercent, 1) / 100
+	gamma := math.Log(humidity) + a*temperatureC/(b+temperatureC)
+	return b * gamma / (a - gamma)
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// FahrenheitToCelsius converts °F to °C
+func FahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// FeetToMeters converts feet to meters
+func FeetToMeters(feet float64) float64 {
+	return feet * 0.3048
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+// Training zone names, from slowest to fastest
+const (
+	ZoneEasy       = "easy"
+	ZoneMarathon   = "marathon"
+	ZoneThreshold  = "threshold"
+	ZoneInterval   = "interval"
+	ZoneRepetition = "repetition"
+)
+
+// ZoneNames lists the training zones from slowest to fastest
+var ZoneNames = []string{ZoneEasy, ZoneMarathon, ZoneThreshold, ZoneInterval, ZoneRepetition}
+
+// Zone calculation methods
+const (
+	ZoneMethodVDOT   = "vdot"
+	ZoneMethodRiegel = "riegel"
+)
+
+const (
+	// DefaultRiegelExponent is Riegel's fatigue exponent for running
+	DefaultRiegelExponent = 1.06
+
+	marathonMeters = 42195.0
+	mileMeters     = 1609.34
+	// riegelIntervalMeters is the race distance whose pace stands in for interval
+	// pace, a 3000 m race lasting roughly as long as VO2max can be held
+	riegelIntervalMeters = 3000.0
+	// riegelEasyFactor slows marathon pace to easy pace, since Riegel's formula says
+	// nothing about aerobic effort below race pace
+	riegelEasyFactor = 1.2
+)
+
+// Fractions of VDOT each aerobic zone is run at, after Daniels' Running Formula
+const (
+	easyFraction      = 0.70
+	thresholdFraction = 0.88
+	intervalFraction  = 0.98
+)
+
+// TrainingZones are training paces derived from one race result, in seconds per km
+type TrainingZones struct {
+	Method     string
+	VDOT       float64 // Zero for Riegel
+	Easy       float64
+	Marathon   float64
+	Threshold  float64
+	Interval   float64
+	Repetition float64
+}
+
+// Pace returns the pace in seconds per km for a zone name
+func (z TrainingZones) Pace(zone string) (float64, bool) {
+	switch zone {
+	case ZoneEasy:
+		return z.Easy, true
+	case ZoneMarathon:
+		return z.Marathon, true
+	case ZoneThreshold:
+		return z.Threshold, true
+	case ZoneInterval:
+		return z.Interval, true
+	case ZoneRepetition:
+		return z.Repetition, true
+	}
+	return 0, false
+}
+
+// CalculateTrainingZones derives training paces from a race of distanceMeters run in
+// seconds, by the VDOT or Riegel method
+func CalculateTrainingZones(method string, distanceMeters float64, seconds float64) (TrainingZones, error) {
+	if distanceMeters <= 0 || seconds <= 0 {
+		return TrainingZones{}, fmt.Errorf("race distance and time must be positive")
+	}
+	switch method {
+	case "", ZoneMethodVDOT:
+		return VDOTZones(distanceMeters, seconds), nil
+	case ZoneMethodRiegel:
+		return RiegelZones(distanceMeters, seconds, DefaultRiegelExponent), nil
+	}
+	return TrainingZones{}, fmt.Errorf("unknown zone method %q", method)
+}
+
+// VDOTZones derives training paces from a race result using Daniels and Gilbert's
+// oxygen cost and drop-dead formulas. Easy, threshold and interval paces are run at a
+// fixed fraction of VDOT; marathon and repetition paces are the predicted marathon and
+// mile race paces.
+func VDOTZones(distanceMeters float64, seconds float64) TrainingZones {
+	vdot := VDOT(distanceMeters, seconds)
+	return TrainingZones{
+		Method:     ZoneMethodVDOT,
+		VDOT:       vdot,
+		Easy:       paceAtVO2(easyFraction * vdot),
+		Marathon:   PredictVDOTTime(vdot, marathonMeters) / marathonMeters * 1000,
+		Threshold:  paceAtVO2(thresholdFraction * vdot),
+		Interval:   paceAtVO2(intervalFraction * vdot),
+		Repetition: PredictVDOTTime(vdot, mileMeters) / mileMeters * 1000,
+	}
+}
+
+// RiegelZones derives training paces from Riegel race predictions: marathon,
+// one-hour, 3000 m and mile race paces stand in for marathon, threshold, interval and
+// repetition pace, and easy pace is marathon pace slowed by a fixed factor
+func RiegelZones(distanceMeters float64, seconds float64, exponent float64) TrainingZones {
+	paceFor := func(meters float64) float64 {
+		return RiegelTime(seconds, distanceMeters, meters, exponent) / meters * 1000
+	}
+	hourMeters := RiegelDistance(seconds, distanceMeters, 3600, exponent)
+	marathon := paceFor(marathonMeters)
+	return TrainingZones{
+		Method:     ZoneMethodRiegel,
+		Easy:       marathon * riegelEasyFactor,
+		Marathon:   marathon,
+		Threshold:  3600 / hourMeters * 1000,
+		Interval:   paceFor(riegelIntervalMeters),
+		Repetition: paceFor(mileMeters),
+	}
+}
+
+// RiegelTime predicts the time for toMeters from a race of fromMeters run in seconds:
+// t2 = t1 * (d2 / d1)^exponent
+func RiegelTime(seconds float64, fromMeters float64, toMeters float64, exponent float64) float64 {
+	return seconds * math.Pow(toMeters/fromMeters, exponent)
+}
+
+// RiegelDistance inverts RiegelTime, predicting how far the runner goes in toSeconds
+func RiegelDistance(seconds float64, fromMeters float64, toSeconds float64, exponent float64) float64 {
+	return fromMeters * math.Pow(toSeconds/seconds, 1/exponent)
+}
+
+// VDOT is Daniels' effective VO2max for a race of distanceMeters run in seconds: the
+// oxygen cost of the race speed divided by the fraction of VO2max sustainable for
+// that long
+func VDOT(distanceMeters float64, seconds float64) float64 {
+	minutes := seconds / 60
+	return vo2AtVelocity(distanceMeters/minutes) / sustainableFraction(minutes)
+}
+
+// PredictVDOTTime returns the race time in seconds for distanceMeters at a VDOT
+func PredictVDOTTime(vdot float64, distanceMeters float64) float64 {
+	// VDOT falls as the same distance takes longer, so bisect on time
+	low, high := 60.0, 48*3600.0
+	for i := 0; i < 100 && high-low > 0.01; i++ {
+		mid := (low + high) / 2
+		if VDOT(distanceMeters, mid) > vdot {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// vo2AtVelocity is the oxygen cost in ml/kg/min of running at metersPerMinute
+func vo2AtVelocity(metersPerMinute float64) float64 {
+	return -4.60 + 0.182258*metersPerMinute + 0.000104*metersPerMinute*metersPerMinute
+}
+
+// sustainableFraction is the fraction of VO2max a runner can hold for minutes
+func sustainableFraction(minutes float64) float64 {
+	return 0.8 + 0.1894393*math.Exp(-0.012778*minutes) + 0.2989558*math.Exp(-0.1932605*minutes)
+}
+
+// paceAtVO2 inverts vo2AtVelocity and returns the pace in seconds per km
+func paceAtVO2(vo2 float64) float64 {
+	a, b, c := 0.000104, 0.182258, -4.60-vo2
+	metersPerMinute := (-b + math.Sqrt(b*b-4*a*c)) / (2 * a)
+	return 60000 / metersPerMinute
+}
@@ -0,0 +1,277 @@
+package workoutfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// earthRadiusMeters is the mean Earth radius used for haversine distances
+const earthRadiusMeters = 6371000
+
+// Activity is a recorded activity: timestamped points with cumulative distance
+type Activity struct {
+	Format string
+	Name   string
+	Sport  string
+	Points []TrackPoint
+}
+
+// TrackPoint is one recorded sample
+type TrackPoint struct {
+	Time           time.Time
+	DistanceMeters float64 // Cumulative from the start of the activity
+	Cadence        float64 // Steps per minute; zero when not recorded
+}
+
+// HasCadence reports whether any point carries cadence
+func (a *Activity) HasCadence() bool {
+	for _, p := range a.Points {
+		if p.Cadence > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rawPoint is a parsed point before distances are resolved
+type rawPoint struct {
+	time        time.Time
+	lat, lon    float64
+	hasPosition bool
+	distance    float64
+	hasDistance bool
+	cadence     float64
+}
+
+// extensionNode captures arbitrary extension XML so cadence can be found in any namespace
+type extensionNode struct {
+	XMLName  xml.Name
+	Value    string          `xml:",chardata"`
+	Children []extensionNode `xml:",any"`
+}
+
+// find returns the value of the first descendant with one of the given local names
+func (n extensionNode) find(names ...string) string {
+	for _, child := range n.Children {
+		for _, name := range names {
+			if strings.EqualFold(child.XMLName.Local, name) {
+				return strings.TrimSpace(child.Value)
+			}
+		}
+		if value := child.find(names...); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+type gpxFile struct {
+	XMLName  xml.Name `xml:"gpx"`
+	Metadata struct {
+		Name string `xml:"name"`
+	} `xml:"metadata"`
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Type     string `xml:"type"`
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat        float64       `xml:"lat,attr"`
+	Lon        float64       `xml:"lon,attr"`
+	Time       string        `xml:"time"`
+	Extensions extensionNode `xml:"extensions"`
+}
+
+type tcxActivityDatabase struct {
+	Activities []struct {
+		Sport string `xml:"Sport,attr"`
+		Laps  []struct {
+			Points []tcxTrackpoint `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+type tcxTrackpoint struct {
+	Time     string `xml:"Time"`
+	Position *struct {
+		Lat float64 `xml:"LatitudeDegrees"`
+		Lon float64 `xml:"LongitudeDegrees"`
+	} `xml:"Position"`
+	DistanceMeters *float64      `xml:"DistanceMeters"`
+	Cadence        *float64      `xml:"Cadence"`
+	Extensions     extensionNode `xml:"Extensions"`
+}
+
+// ParseActivity detects whether data is GPX or TCX and reads the recorded activity
+func ParseActivity(data []byte) (*Activity, error) {
+	head := data[:min(len(data), 2048)]
+	switch {
+	case bytes.Contains(head, []byte("<gpx")):
+		return ParseGPX(data)
+	case bytes.Contains(head, []byte("TrainingCenterDatabase")):
+		return ParseTCXActivity(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ParseGPX reads all track points of a GPX file. Cadence is taken from Garmin's
+// TrackPointExtension or any extension element named cad or cadence.
+func ParseGPX(data []byte) (*Activity, error) {
+	var file gpxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing GPX: %v", err)
+	}
+
+	activity := &Activity{Format: FormatGPX, Name: strings.TrimSpace(file.Metadata.Name)}
+	var points []rawPoint
+	for _, track := range file.Tracks {
+		if activity.Name == "" {
+			activity.Name = strings.TrimSpace(track.Name)
+		}
+		if activity.Sport == "" {
+			activity.Sport = strings.ToLower(strings.TrimSpace(track.Type))
+		}
+		for _, segment := range track.Segments {
+			for _, p := range segment.Points {
+				t, err := time.Parse(time.RFC3339, strings.TrimSpace(p.Time))
+				if err != nil {
+					continue
+				}
+				points = append(points, rawPoint{
+					time:        t,
+					lat:         p.Lat,
+					lon:         p.Lon,
+					hasPosition: true,
+					cadence:     parseCadence(p.Extensions.find("cad", "cadence")),
+				})
+			}
+		}
+	}
+
+	resolved, err := resolvePoints(points)
+	if err != nil {
+		return nil, err
+	}
+	activity.Points = resolved
+	return activity, nil
+}
+
+// ParseTCXActivity reads the first activity of a TCX file
+func ParseTCXActivity(data []byte) (*Activity, error) {
+	var db tcxActivityDatabase
+	if err := xml.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("error parsing TCX: %v", err)
+	}
+	if len(db.Activities) == 0 {
+		return nil, fmt.Errorf("TCX file contains no activities")
+	}
+
+	source := db.Activities[0]
+	activity := &Activity{Format: FormatTCX, Sport: strings.ToLower(source.Sport)}
+	var points []rawPoint
+	for _, lap := range source.Laps {
+		for _, tp := range lap.Points {
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(tp.Time))
+			if err != nil {
+				continue
+			}
+			point := rawPoint{time: t}
+			if tp.Position != nil {
+				point.lat, point.lon, point.hasPosition = tp.Position.Lat, tp.Position.Lon, true
+			}
+			if tp.DistanceMeters != nil {
+				point.distance, point.hasDistance = *tp.DistanceMeters, true
+			}
+			if tp.Cadence != nil {
+				point.cadence = normaliseCadence(*tp.Cadence)
+			} else {
+				point.cadence = parseCadence(tp.Extensions.find("RunCadence"))
+			}
+			points = append(points, point)
+		}
+	}
+
+	resolved, err := resolvePoints(points)
+	if err != nil {
+		return nil, err
+	}
+	activity.Points = resolved
+	return activity, nil
+}
+
+// resolvePoints orders the points by time and fills in cumulative distance, using
+// recorded distance where present and the haversine distance between positions otherwise
+func resolvePoints(points []rawPoint) ([]TrackPoint, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("activity needs at least two timestamped points")
+	}
+	sort.SliceStable(points, func(a, b int) bool {
+		return points[a].time.Before(points[b].time)
+	})
+
+	resolved := make([]TrackPoint, len(points))
+	distance := 0.0
+	var last *rawPoint
+	for i := range points {
+		p := &points[i]
+		switch {
+		case p.hasDistance:
+			distance = math.Max(distance, p.distance)
+		case p.hasPosition && last != nil && last.hasPosition:
+			distance += haversine(last.lat, last.lon, p.lat, p.lon)
+		}
+		if p.hasPosition {
+			last = p
+		}
+		resolved[i] = TrackPoint{Time: p.time, DistanceMeters: distance, Cadence: p.cadence}
+	}
+
+	if resolved[len(resolved)-1].DistanceMeters == 0 {
+		return nil, fmt.Errorf("activity has no distance or position data")
+	}
+	return resolved, nil
+}
+
+// parseCadence parses a cadence extension value
+func parseCadence(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	cadence, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return normaliseCadence(cadence)
+}
+
+// normaliseCadence converts cadence to steps per minute. Garmin devices record running
+// cadence per leg (strides per minute), so values below 120 are doubled.
+func normaliseCadence(cadence float64) float64 {
+	if cadence <= 0 {
+		return 0
+	}
+	if cadence < 120 {
+		return cadence * 2
+	}
+	return cadence
+}
+
+// haversine returns the great-circle distance between two positions in meters
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
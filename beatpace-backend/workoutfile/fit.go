@@ -0,0 +1,340 @@
+package workoutfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// FIT global message numbers and field numbers used for workouts
+const (
+	fitMesgWorkout     = 26
+	fitMesgWorkoutStep = 27
+
+	fitFieldWorkoutSport = 4
+	fitFieldWorkoutName  = 8
+
+	fitFieldStepName       = 0
+	fitFieldDurationType   = 1
+	fitFieldDurationValue  = 2
+	fitFieldTargetType     = 3
+	fitFieldTargetValue    = 4
+	fitFieldCustomLow      = 5
+	fitFieldCustomHigh     = 6
+	fitFieldIntensity      = 7
+	fitFieldMessageIndex   = 254
+	fitBaseTypeString      = 0x07
+	fitMinHeaderSize       = 12
+	fitDurationTime        = 0 // Value in ms
+	fitDurationDistance    = 1 // Value in cm
+	fitDurationRepeatSteps = 6 // Repeat from step duration_value, target_value times
+	fitTargetSpeed         = 0 // Custom range in mm/s when target_value is 0
+)
+
+// fitSports maps the FIT sport enum to a name, for the sports BeatPace cares about
+var fitSports = map[uint64]string{
+	1:  "running",
+	2:  "cycling",
+	11: "walking",
+	17: "hiking",
+}
+
+// fitIntensities maps the FIT intensity enum to our intensities
+var fitIntensities = map[uint64]string{
+	0: IntensityActive,
+	1: IntensityRest,
+	2: IntensityWarmup,
+	3: IntensityCooldown,
+	4: IntensityRecovery,
+	5: IntensityActive, // interval
+}
+
+// fitCRCTable is the nibble table from the FIT SDK's CRC-16
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+type fitFieldDef struct {
+	num      byte
+	size     int
+	baseType byte
+}
+
+type fitDefinition struct {
+	global    uint16
+	order     binary.ByteOrder
+	fields    []fitFieldDef
+	devFields int // Total size of developer fields, skipped
+}
+
+// fitValue is one decoded field; strings and integers are kept side by side since
+// only a handful of fields are read
+type fitValue struct {
+	uint  uint64
+	str   string
+	valid bool
+}
+
+// fitStep is a workout_step message in file order
+type fitStep struct {
+	index  int
+	fields map[byte]fitValue
+}
+
+// IsFIT reports whether data starts with a FIT file header
+func IsFIT(data []byte) bool {
+	return len(data) >= fitMinHeaderSize && string(data[8:12]) == ".FIT"
+}
+
+// ParseFIT reads a FIT workout file
+func ParseFIT(data []byte) (*Workout, error) {
+	if !IsFIT(data) {
+		return nil, fmt.Errorf("not a FIT file")
+	}
+	headerSize := int(data[0])
+	if headerSize < fitMinHeaderSize || headerSize > len(data) {
+		return nil, fmt.Errorf("invalid FIT header size %d", headerSize)
+	}
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	end := headerSize + dataSize
+	if end+2 > len(data) {
+		return nil, fmt.Errorf("FIT file is truncated")
+	}
+	if crc := binary.LittleEndian.Uint16(data[end : end+2]); crc != 0 && crc != fitCRC(data[:end]) {
+		return nil, fmt.Errorf("FIT file checksum mismatch")
+	}
+
+	workout := &Workout{Format: FormatFIT}
+	var steps []fitStep
+	definitions := make(map[byte]*fitDefinition)
+
+	pos := headerSize
+	for pos < end {
+		header := data[pos]
+		pos++
+
+		if header&0x80 != 0 {
+			// Compressed timestamp header: a data message for local types 0-3
+			local := (header >> 5) & 0x03
+			_, next, err := readFITMessage(data, pos, end, definitions[local])
+			if err != nil {
+				return nil, err
+			}
+			pos = next
+			continue
+		}
+
+		local := header & 0x0F
+		if header&0x40 != 0 {
+			def, next, err := readFITDefinition(data, pos, end, header&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			definitions[local] = def
+			pos = next
+			continue
+		}
+
+		def := definitions[local]
+		fields, next, err := readFITMessage(data, pos, end, def)
+		if err != nil {
+			return nil, err
+		}
+		pos = next
+
+		switch def.global {
+		case fitMesgWorkout:
+			if v := fields[fitFieldWorkoutName]; v.valid {
+				workout.Name = v.str
+			}
+			if v := fields[fitFieldWorkoutSport]; v.valid {
+				workout.Sport = fitSports[v.uint]
+			}
+		case fitMesgWorkoutStep:
+			index := len(steps)
+			if v := fields[fitFieldMessageIndex]; v.valid {
+				index = int(v.uint)
+			}
+			steps = append(steps, fitStep{index: index, fields: fields})
+		}
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("FIT file contains no workout steps")
+	}
+	converted, err := convertFITSteps(steps)
+	if err != nil {
+		return nil, err
+	}
+	workout.Steps = converted
+	return workout, nil
+}
+
+func readFITDefinition(data []byte, pos int, end int, hasDevFields bool) (*fitDefinition, int, error) {
+	if pos+5 > end {
+		return nil, 0, fmt.Errorf("FIT definition is truncated")
+	}
+	def := &fitDefinition{order: binary.LittleEndian}
+	if data[pos+1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(data[pos+2 : pos+4])
+	count := int(data[pos+4])
+	pos += 5
+
+	if pos+count*3 > end {
+		return nil, 0, fmt.Errorf("FIT definition is truncated")
+	}
+	for i := 0; i < count; i++ {
+		def.fields = append(def.fields, fitFieldDef{num: data[pos], size: int(data[pos+1]), baseType: data[pos+2]})
+		pos += 3
+	}
+
+	if hasDevFields {
+		if pos >= end {
+			return nil, 0, fmt.Errorf("FIT definition is truncated")
+		}
+		devCount := int(data[pos])
+		pos++
+		if pos+devCount*3 > end {
+			return nil, 0, fmt.Errorf("FIT definition is truncated")
+		}
+		for i := 0; i < devCount; i++ {
+			def.devFields += int(data[pos+1])
+			pos += 3
+		}
+	}
+	return def, pos, nil
+}
+
+func readFITMessage(data []byte, pos int, end int, def *fitDefinition) (map[byte]fitValue, int, error) {
+	if def == nil {
+		return nil, 0, fmt.Errorf("FIT data message without definition")
+	}
+
+	fields := make(map[byte]fitValue, len(def.fields))
+	for _, field := range def.fields {
+		if pos+field.size > end {
+			return nil, 0, fmt.Errorf("FIT message is truncated")
+		}
+		raw := data[pos : pos+field.size]
+		pos += field.size
+
+		if field.baseType == fitBaseTypeString {
+			str := strings.TrimSpace(strings.SplitN(string(raw), "\x00", 2)[0])
+			fields[field.num] = fitValue{str: str, valid: str != ""}
+			continue
+		}
+		fields[field.num] = decodeFITUint(raw, def.order)
+	}
+
+	if pos+def.devFields > end {
+		return nil, 0, fmt.Errorf("FIT message is truncated")
+	}
+	return fields, pos + def.devFields, nil
+}
+
+// decodeFITUint reads an unsigned integer of 1, 2, 4 or 8 bytes. All bits set is
+// FIT's invalid value.
+func decodeFITUint(raw []byte, order binary.ByteOrder) fitValue {
+	var value, invalid uint64
+	switch len(raw) {
+	case 1:
+		value, invalid = uint64(raw[0]), 0xFF
+	case 2:
+		value, invalid = uint64(order.Uint16(raw)), 0xFFFF
+	case 4:
+		value, invalid = uint64(order.Uint32(raw)), 0xFFFFFFFF
+	case 8:
+		value, invalid = order.Uint64(raw), 0xFFFFFFFFFFFFFFFF
+	default:
+		return fitValue{}
+	}
+	return fitValue{uint: value, valid: value != invalid}
+}
+
+// convertFITSteps turns the flat FIT step list into steps and repeat blocks. A repeat
+// step closes a block that starts at the step index in its duration value.
+func convertFITSteps(fitSteps []fitStep) ([]Step, error) {
+	var steps []Step
+	var firstIndex []int // Lowest FIT step index covered by each entry of steps
+
+	for _, fs := range fitSteps {
+		durationType := fs.fields[fitFieldDurationType]
+		durationValue := fs.fields[fitFieldDurationValue]
+		name := fs.fields[fitFieldStepName].str
+
+		if durationType.valid && durationType.uint == fitDurationRepeatSteps {
+			from := int(durationValue.uint)
+			count := fs.fields[fitFieldTargetValue].uint
+			if !durationValue.valid || count < 1 {
+				return nil, fmt.Errorf("repeat step %d is missing its range or count", fs.index)
+			}
+			if count > MaxRepetitions {
+				return nil, fmt.Errorf("repeat step %d has %d repetitions, the maximum is %d", fs.index, count, MaxRepetitions)
+			}
+
+			split := len(steps)
+			for split > 0 && firstIndex[split-1] >= from {
+				split--
+			}
+			if split == len(steps) {
+				return nil, fmt.Errorf("repeat step %d has nothing to repeat", fs.index)
+			}
+
+			block := Step{Name: name, Repetitions: int(count), Steps: append([]Step(nil), steps[split:]...)}
+			start := firstIndex[split]
+			steps = append(steps[:split], block)
+			firstIndex = append(firstIndex[:split], start)
+			continue
+		}
+
+		step := Step{Name: name, Intensity: IntensityActive, DurationType: DurationOpen}
+		if v := fs.fields[fitFieldIntensity]; v.valid {
+			if intensity, ok := fitIntensities[v.uint]; ok {
+				step.Intensity = intensity
+			}
+		}
+		if durationType.valid && durationValue.valid {
+			switch durationType.uint {
+			case fitDurationTime:
+				step.DurationType = DurationTime
+				step.DurationSeconds = float64(durationValue.uint) / 1000
+			case fitDurationDistance:
+				step.DurationType = DurationDistance
+				step.DistanceMeters = float64(durationValue.uint) / 100
+			}
+		}
+
+		// Only custom speed ranges carry values; speed zones live on the device
+		targetType := fs.fields[fitFieldTargetType]
+		zone := fs.fields[fitFieldTargetValue]
+		if targetType.valid && targetType.uint == fitTargetSpeed && (!zone.valid || zone.uint == 0) {
+			if v := fs.fields[fitFieldCustomLow]; v.valid {
+				step.SpeedLow = float64(v.uint) / 1000
+			}
+			if v := fs.fields[fitFieldCustomHigh]; v.valid {
+				step.SpeedHigh = float64(v.uint) / 1000
+			}
+		}
+
+		steps = append(steps, step)
+		firstIndex = append(firstIndex, fs.index)
+	}
+	return steps, nil
+}
+
+// fitCRC computes the FIT CRC-16 of data
+func fitCRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		tmp := fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[b&0xF]
+		tmp = fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[(b>>4)&0xF]
+	}
+	return crc
+}
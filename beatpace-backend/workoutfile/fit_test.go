@@ -0,0 +1,201 @@
+package workoutfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// fitBuilder writes FIT files field by field, so tests can describe workouts
+// without binary fixtures
+type fitBuilder struct {
+	records bytes.Buffer
+}
+
+// fitField is a field definition and its value in one data message
+type fitField struct {
+	num      byte
+	size     int
+	baseType byte
+	value    uint64
+	str      string
+}
+
+func fitUint8(num byte, value uint64) fitField {
+	return fitField{num: num, size: 1, baseType: 0x02, value: value}
+}
+
+func fitUint16(num byte, value uint64) fitField {
+	return fitField{num: num, size: 2, baseType: 0x84, value: value}
+}
+
+func fitUint32(num byte, value uint64) fitField {
+	return fitField{num: num, size: 4, baseType: 0x86, value: value}
+}
+
+func fitString(num byte, size int, str string) fitField {
+	return fitField{num: num, size: size, baseType: fitBaseTypeString, str: str}
+}
+
+// message writes a definition for local type 0 followed by one data message
+func (b *fitBuilder) message(global uint16, fields ...fitField) {
+	b.records.Write([]byte{0x40, 0, 0})
+	binary.Write(&b.records, binary.LittleEndian, global)
+	b.records.WriteByte(byte(len(fields)))
+	for _, field := range fields {
+		b.records.Write([]byte{field.num, byte(field.size), field.baseType})
+	}
+
+	b.records.WriteByte(0x00)
+	for _, field := range fields {
+		raw := make([]byte, field.size)
+		switch {
+		case field.baseType == fitBaseTypeString:
+			copy(raw, field.str)
+		case field.size == 1:
+			raw[0] = byte(field.value)
+		case field.size == 2:
+			binary.LittleEndian.PutUint16(raw, uint16(field.value))
+		case field.size == 4:
+			binary.LittleEndian.PutUint32(raw, uint32(field.value))
+		}
+		b.records.Write(raw)
+	}
+}
+
+// step writes a workout_step message
+func (b *fitBuilder) step(index uint64, fields ...fitField) {
+	b.message(fitMesgWorkoutStep, append([]fitField{fitUint16(fitFieldMessageIndex, index)}, fields...)...)
+}
+
+// bytes returns the complete file with its header and CRC
+func (b *fitBuilder) bytes() []byte {
+	var file bytes.Buffer
+	file.Write([]byte{14, 0x20})
+	binary.Write(&file, binary.LittleEndian, uint16(2100))
+	binary.Write(&file, binary.LittleEndian, uint32(b.records.Len()))
+	file.WriteString(".FIT")
+	binary.Write(&file, binary.LittleEndian, uint16(0))
+	file.Write(b.records.Bytes())
+	binary.Write(&file, binary.LittleEndian, fitCRC(file.Bytes()))
+	return file.Bytes()
+}
+
+// intervalsFIT is a warmup, four repeats of 400m hard and 90s easy, and a cooldown
+func intervalsFIT(repeats uint64) *fitBuilder {
+	b := &fitBuilder{}
+	b.message(fitMesgWorkout, fitUint8(fitFieldWorkoutSport, 1), fitString(fitFieldWorkoutName, 16, "Track 4x400"))
+	b.step(0, fitString(fitFieldStepName, 8, "Warm up"), fitUint8(fitFieldDurationType, fitDurationTime),
+		fitUint32(fitFieldDurationValue, 600000), fitUint8(fitFieldIntensity, 2))
+	b.step(1, fitUint8(fitFieldDurationType, fitDurationDistance), fitUint32(fitFieldDurationValue, 40000),
+		fitUint8(fitFieldTargetType, fitTargetSpeed), fitUint32(fitFieldTargetValue, 0),
+		fitUint32(fitFieldCustomLow, 4000), fitUint32(fitFieldCustomHigh, 4500), fitUint8(fitFieldIntensity, 0))
+	b.step(2, fitUint8(fitFieldDurationType, fitDurationTime), fitUint32(fitFieldDurationValue, 90000),
+		fitUint8(fitFieldIntensity, 4))
+	b.step(3, fitUint8(fitFieldDurationType, fitDurationRepeatSteps), fitUint32(fitFieldDurationValue, 1),
+		fitUint32(fitFieldTargetValue, repeats))
+	b.step(4, fitUint8(fitFieldDurationType, fitDurationTime), fitUint32(fitFieldDurationValue, 300000),
+		fitUint8(fitFieldIntensity, 3))
+	return b
+}
+
+func TestParseFITWorkout(t *testing.T) {
+	data := intervalsFIT(4).bytes()
+	if !IsFIT(data) {
+		t.Fatal("IsFIT = false for a FIT file")
+	}
+
+	workout, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if workout.Format != FormatFIT || workout.Name != "Track 4x400" || workout.Sport != "running" {
+		t.Fatalf("workout = %q %q %q", workout.Format, workout.Name, workout.Sport)
+	}
+	if len(workout.Steps) != 3 {
+		t.Fatalf("got %d top-level steps, want 3", len(workout.Steps))
+	}
+
+	warmup := workout.Steps[0]
+	if warmup.Name != "Warm up" || warmup.Intensity != IntensityWarmup || warmup.DurationType != DurationTime || warmup.DurationSeconds != 600 {
+		t.Errorf("warmup = %+v", warmup)
+	}
+
+	repeat := workout.Steps[1]
+	if repeat.Repetitions != 4 || len(repeat.Steps) != 2 {
+		t.Fatalf("repeat = %+v", repeat)
+	}
+	work := repeat.Steps[0]
+	if work.DurationType != DurationDistance || work.DistanceMeters != 400 || work.SpeedLow != 4 || work.SpeedHigh != 4.5 {
+		t.Errorf("work step = %+v", work)
+	}
+	if work.TargetSpeed() != 4.25 {
+		t.Errorf("TargetSpeed = %v, want 4.25", work.TargetSpeed())
+	}
+	recovery := repeat.Steps[1]
+	if recovery.Intensity != IntensityRecovery || recovery.DurationSeconds != 90 || recovery.HasSpeedTarget() {
+		t.Errorf("recovery step = %+v", recovery)
+	}
+
+	if cooldown := workout.Steps[2]; cooldown.Intensity != IntensityCooldown || cooldown.DurationSeconds != 300 {
+		t.Errorf("cooldown = %+v", cooldown)
+	}
+}
+
+func TestParseFITRejectsLargeRepeat(t *testing.T) {
+	for _, repeats := range []uint64{MaxRepetitions + 1, 2000000000} {
+		_, err := ParseFIT(intervalsFIT(repeats).bytes())
+		if err == nil || !strings.Contains(err.Error(), "repetitions") {
+			t.Errorf("%d repeats: err = %v, want a repetitions error", repeats, err)
+		}
+	}
+	if _, err := ParseFIT(intervalsFIT(MaxRepetitions).bytes()); err != nil {
+		t.Errorf("%d repeats: %v", MaxRepetitions, err)
+	}
+}
+
+func TestParseFITRejectsRepeatWithoutSteps(t *testing.T) {
+	b := &fitBuilder{}
+	b.step(0, fitUint8(fitFieldDurationType, fitDurationRepeatSteps), fitUint32(fitFieldDurationValue, 0),
+		fitUint32(fitFieldTargetValue, 2))
+	if _, err := ParseFIT(b.bytes()); err == nil {
+		t.Fatal("expected an error for a repeat with nothing to repeat")
+	}
+}
+
+func TestParseFITCorruptFiles(t *testing.T) {
+	valid := intervalsFIT(4).bytes()
+
+	corrupt := append([]byte(nil), valid...)
+	corrupt[20] ^= 0xFF
+	truncated := valid[:len(valid)-10]
+	badHeader := append([]byte(nil), valid...)
+	badHeader[0] = 4
+	noSteps := &fitBuilder{}
+	noSteps.message(fitMesgWorkout, fitString(fitFieldWorkoutName, 8, "Empty"))
+	undefined := append([]byte(nil), valid[:14]...)
+	undefined = append(undefined, 0x01)
+	binary.LittleEndian.PutUint32(undefined[4:8], 1)
+	undefined = append(undefined, 0, 0)
+
+	tests := map[string][]byte{
+		"checksum":           corrupt,
+		"truncated":          truncated,
+		"header size":        badHeader,
+		"no steps":           noSteps.bytes(),
+		"missing definition": undefined,
+	}
+	for name, data := range tests {
+		if _, err := ParseFIT(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFITCRC(t *testing.T) {
+	// Check value of CRC-16/ARC, the polynomial the FIT SDK table implements
+	if got := fitCRC([]byte("123456789")); got != 0xBB3D {
+		t.Errorf("fitCRC = %#04x, want 0xbb3d", got)
+	}
+}
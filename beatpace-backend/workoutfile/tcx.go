@@ -0,0 +1,141 @@
+package workoutfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// tcxDatabase is the subset of a Garmin TrainingCenterDatabase holding workouts
+type tcxDatabase struct {
+	Workouts []tcxWorkout `xml:"Workouts>Workout"`
+}
+
+type tcxWorkout struct {
+	Sport string    `xml:"Sport,attr"`
+	Name  string    `xml:"Name"`
+	Steps []tcxStep `xml:"Step"`
+}
+
+// tcxStep is either a Step_t or a Repeat_t, told apart by its xsi:type
+type tcxStep struct {
+	Attrs       []xml.Attr  `xml:",any,attr"`
+	Name        string      `xml:"Name"`
+	Duration    tcxDuration `xml:"Duration"`
+	Intensity   string      `xml:"Intensity"`
+	Target      tcxTarget   `xml:"Target"`
+	Repetitions int         `xml:"Repetitions"`
+	Children    []tcxStep   `xml:"Child"`
+}
+
+type tcxDuration struct {
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Seconds float64    `xml:"Seconds"`
+	Meters  float64    `xml:"Meters"`
+}
+
+type tcxTarget struct {
+	Attrs     []xml.Attr   `xml:",any,attr"`
+	SpeedZone tcxSpeedZone `xml:"SpeedZone"`
+}
+
+type tcxSpeedZone struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Low   float64    `xml:"LowInMetersPerSecond"`
+	High  float64    `xml:"HighInMetersPerSecond"`
+}
+
+// xsiType returns the xsi:type of an element, ignoring how the prefix was declared
+func xsiType(attrs []xml.Attr) string {
+	for _, attr := range attrs {
+		if attr.Name.Local == "type" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// ParseTCX reads the first workout in a TCX file
+func ParseTCX(data []byte) (*Workout, error) {
+	var db tcxDatabase
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&db); err != nil {
+		return nil, fmt.Errorf("error parsing TCX: %v", err)
+	}
+	if len(db.Workouts) == 0 {
+		return nil, fmt.Errorf("TCX file contains no workouts")
+	}
+
+	workout := db.Workouts[0]
+	steps, err := convertTCXSteps(workout.Steps)
+	if err != nil {
+		return nil, err
+	}
+	return &Workout{
+		Format: FormatTCX,
+		Name:   strings.TrimSpace(workout.Name),
+		Sport:  strings.ToLower(workout.Sport),
+		Steps:  steps,
+	}, nil
+}
+
+func convertTCXSteps(tcxSteps []tcxStep) ([]Step, error) {
+	steps := make([]Step, 0, len(tcxSteps))
+	for _, ts := range tcxSteps {
+		name := strings.TrimSpace(ts.Name)
+
+		if xsiType(ts.Attrs) == "Repeat_t" {
+			children, err := convertTCXSteps(ts.Children)
+			if err != nil {
+				return nil, err
+			}
+			if len(children) == 0 {
+				return nil, fmt.Errorf("repeat step %q has nothing to repeat", name)
+			}
+			if ts.Repetitions < 1 {
+				return nil, fmt.Errorf("repeat step %q has no repetitions", name)
+			}
+			if ts.Repetitions > MaxRepetitions {
+				return nil, fmt.Errorf("repeat step %q has %d repetitions, the maximum is %d", name, ts.Repetitions, MaxRepetitions)
+			}
+			steps = append(steps, Step{Name: name, Repetitions: ts.Repetitions, Steps: children})
+			continue
+		}
+
+		step := Step{Name: name, Intensity: tcxIntensity(ts.Intensity, name)}
+		switch xsiType(ts.Duration.Attrs) {
+		case "Time_t":
+			step.DurationType = DurationTime
+			step.DurationSeconds = ts.Duration.Seconds
+		case "Distance_t":
+			step.DurationType = DurationDistance
+			step.DistanceMeters = ts.Duration.Meters
+		default:
+			step.DurationType = DurationOpen
+		}
+
+		// Only custom speed zones carry values; predefined zones live on the device
+		if xsiType(ts.Target.Attrs) == "Speed_t" && xsiType(ts.Target.SpeedZone.Attrs) == "CustomSpeedZone_t" {
+			step.SpeedLow = ts.Target.SpeedZone.Low
+			step.SpeedHigh = ts.Target.SpeedZone.High
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// tcxIntensity maps TCX's Active/Resting to our intensities. TCX has no warmup or
+// cooldown intensity, so those are recognised by step name.
+func tcxIntensity(intensity string, name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "warm"):
+		return IntensityWarmup
+	case strings.Contains(lower, "cool"):
+		return IntensityCooldown
+	case intensity == "Resting":
+		return IntensityRest
+	}
+	return IntensityActive
+}
@@ -0,0 +1,126 @@
+package workoutfile
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const tcxIntervals = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+    xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <Workouts>
+    <Workout Sport="Running">
+      <Name> Tempo Tuesday </Name>
+      <Step xsi:type="Step_t">
+        <Name>Warmup</Name>
+        <Duration xsi:type="Time_t"><Seconds>600</Seconds></Duration>
+        <Intensity>Active</Intensity>
+        <Target xsi:type="None_t"/>
+      </Step>
+      <Step xsi:type="Repeat_t">
+        <Name>Main set</Name>
+        <Repetitions>%d</Repetitions>
+        <Child xsi:type="Step_t">
+          <Name>Hard</Name>
+          <Duration xsi:type="Distance_t"><Meters>1000</Meters></Duration>
+          <Intensity>Active</Intensity>
+          <Target xsi:type="Speed_t">
+            <SpeedZone xsi:type="CustomSpeedZone_t">
+              <LowInMetersPerSecond>3.8</LowInMetersPerSecond>
+              <HighInMetersPerSecond>4.2</HighInMetersPerSecond>
+            </SpeedZone>
+          </Target>
+        </Child>
+        <Child xsi:type="Step_t">
+          <Name>Jog</Name>
+          <Duration xsi:type="UserInitiated_t"/>
+          <Intensity>Resting</Intensity>
+          <Target xsi:type="Speed_t">
+            <SpeedZone xsi:type="PredefinedSpeedZone_t"><Number>1</Number></SpeedZone>
+          </Target>
+        </Child>
+      </Step>
+      <Step xsi:type="Step_t">
+        <Name>Cool down</Name>
+        <Duration xsi:type="Time_t"><Seconds>300</Seconds></Duration>
+        <Intensity>Active</Intensity>
+      </Step>
+    </Workout>
+  </Workouts>
+</TrainingCenterDatabase>`
+
+func TestParseTCXWorkout(t *testing.T) {
+	workout, err := Parse([]byte(fmt.Sprintf(tcxIntervals, 3)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if workout.Format != FormatTCX || workout.Name != "Tempo Tuesday" || workout.Sport != "running" {
+		t.Fatalf("workout = %q %q %q", workout.Format, workout.Name, workout.Sport)
+	}
+	if len(workout.Steps) != 3 {
+		t.Fatalf("got %d top-level steps, want 3", len(workout.Steps))
+	}
+
+	if warmup := workout.Steps[0]; warmup.Intensity != IntensityWarmup || warmup.DurationType != DurationTime || warmup.DurationSeconds != 600 {
+		t.Errorf("warmup = %+v", warmup)
+	}
+
+	repeat := workout.Steps[1]
+	if repeat.Name != "Main set" || repeat.Repetitions != 3 || len(repeat.Steps) != 2 {
+		t.Fatalf("repeat = %+v", repeat)
+	}
+	hard := repeat.Steps[0]
+	if hard.DurationType != DurationDistance || hard.DistanceMeters != 1000 || hard.SpeedLow != 3.8 || hard.SpeedHigh != 4.2 {
+		t.Errorf("hard step = %+v", hard)
+	}
+	jog := repeat.Steps[1]
+	if jog.Intensity != IntensityRest || jog.DurationType != DurationOpen || jog.HasSpeedTarget() {
+		t.Errorf("jog step = %+v", jog)
+	}
+
+	if cooldown := workout.Steps[2]; cooldown.Intensity != IntensityCooldown || cooldown.DurationSeconds != 300 {
+		t.Errorf("cooldown = %+v", cooldown)
+	}
+}
+
+func TestParseTCXRepetitions(t *testing.T) {
+	for _, repetitions := range []int{0, -1, MaxRepetitions + 1, 2000000000} {
+		_, err := ParseTCX([]byte(fmt.Sprintf(tcxIntervals, repetitions)))
+		if err == nil || !strings.Contains(err.Error(), "repetitions") {
+			t.Errorf("%d repetitions: err = %v, want a repetitions error", repetitions, err)
+		}
+	}
+	if _, err := ParseTCX([]byte(fmt.Sprintf(tcxIntervals, MaxRepetitions))); err != nil {
+		t.Errorf("%d repetitions: %v", MaxRepetitions, err)
+	}
+}
+
+func TestParseTCXErrors(t *testing.T) {
+	tests := map[string]string{
+		"no workouts": `<TrainingCenterDatabase><Workouts></Workouts></TrainingCenterDatabase>`,
+		"malformed":   `<TrainingCenterDatabase><Workouts><Workout>`,
+	}
+	for name, data := range tests {
+		if _, err := ParseTCX([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Parse([]byte("not a workout file")); err != ErrUnknownFormat {
+		t.Errorf("Parse(text) err = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestParseTCXRejectsEmptyRepeat(t *testing.T) {
+	// Nested empty repeats expand to nothing but cost repetitions to the
+	// power of the depth to walk, so they must fail at any depth
+	repeat := `<Step xsi:type="Repeat_t"><Name>Inner</Name><Repetitions>100</Repetitions></Step>`
+	for depth := 0; depth < 4; depth++ {
+		data := []byte(`<TrainingCenterDatabase xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><Workouts><Workout Sport="Running">` +
+			repeat + `</Workout></Workouts></TrainingCenterDatabase>`)
+		if _, err := ParseTCX(data); err == nil || !strings.Contains(err.Error(), "nothing to repeat") {
+			t.Errorf("depth %d: err = %v, want a nothing to repeat error", depth, err)
+		}
+		repeat = `<Step xsi:type="Repeat_t"><Repetitions>100</Repetitions>` + repeat + `</Step>`
+	}
+}
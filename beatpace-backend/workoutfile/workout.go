@@ -0,0 +1,88 @@
+// Package workoutfile reads files exported by watches and training platforms:
+// structured workouts from TCX and FIT, and recorded activities from GPX and TCX.
+package workoutfile
+
+import (
+	"bytes"
+	"errors"
+)
+
+// File formats
+const (
+	FormatTCX = "tcx"
+	FormatFIT = "fit"
+	FormatGPX = "gpx"
+)
+
+// Step intensities, normalised across formats
+const (
+	IntensityWarmup   = "warmup"
+	IntensityActive   = "active"
+	IntensityRest     = "rest"
+	IntensityRecovery = "recovery"
+	IntensityCooldown = "cooldown"
+)
+
+// Step duration types. DurationOpen covers every step that ends on something other
+// than time or distance, such as the lap button or a heart rate threshold.
+const (
+	DurationTime     = "time"
+	DurationDistance = "distance"
+	DurationOpen     = "open"
+)
+
+// MaxRepetitions is the most times a repeat block may run. Repeats are expanded into
+// one segment per step, and a workout playlist has at most 100 segments, so larger
+// counts can never be used.
+const MaxRepetitions = 100
+
+// ErrUnknownFormat is returned when the data is not in a supported format
+var ErrUnknownFormat = errors.New("unrecognised file format")
+
+// Workout is a structured workout read from a file
+type Workout struct {
+	Format string
+	Name   string
+	Sport  string
+	Steps  []Step
+}
+
+// Step is a single workout step, or a repeat block when Repetitions is set
+type Step struct {
+	Name            string
+	Intensity       string
+	DurationType    string
+	DurationSeconds float64
+	DistanceMeters  float64
+	SpeedLow        float64 // Target speed range in m/s; both zero without a speed target
+	SpeedHigh       float64
+	Repetitions     int    // Number of times Steps is run; zero for a plain step
+	Steps           []Step // Children of a repeat block
+}
+
+// HasSpeedTarget reports whether the step carries a speed or pace target
+func (s Step) HasSpeedTarget() bool {
+	return s.SpeedLow > 0 || s.SpeedHigh > 0
+}
+
+// TargetSpeed returns the middle of the step's speed range in m/s
+func (s Step) TargetSpeed() float64 {
+	switch {
+	case s.SpeedLow > 0 && s.SpeedHigh > 0:
+		return (s.SpeedLow + s.SpeedHigh) / 2
+	case s.SpeedLow > 0:
+		return s.SpeedLow
+	}
+	return s.SpeedHigh
+}
+
+// Parse detects the format of data and reads the workout it contains
+func Parse(data []byte) (*Workout, error) {
+	if IsFIT(data) {
+		return ParseFIT(data)
+	}
+	if bytes.Contains(data[:min(len(data), 1024)], []byte("<")) {
+		return ParseTCX(data)
+	}
+	return nil, ErrUnknownFormat
+}